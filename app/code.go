@@ -13,4 +13,5 @@ const (
 	CodeServiceReadNotFound
 	CodeServiceWriteError
 	CodeAdapterError
+	CodeTooManyRequests
 )
@@ -23,6 +23,14 @@ type (
 		Port    int    `yaml:"port" json:"port"`
 		Version string `yaml:"version" json:"version"`
 
+		// Grpc port.
+		//
+		// Publish service over grpc listen on this port with the
+		// same host. Grpc server not started if not configured.
+		//
+		// Example: 8102
+		GrpcPort int `yaml:"grpc-port" json:"grpc-port"`
+
 		// Consul
 		// enabled status.
 		//
@@ -66,6 +74,10 @@ type (
 		// Example: 172.16.0.100:8080
 		Addr string `yaml:"-" json:"-"`
 
+		// Grpc address.
+		// Example: 172.16.0.100:8102
+		GrpcAddr string `yaml:"-" json:"-"`
+
 		// Running pid.
 		// Example: 3721
 		Pid int `yaml:"-" json:"-"`
@@ -103,6 +115,7 @@ func (o *Configuration) LoadYaml(name string) error {
 // execution fields based on assigned.
 func (o *Configuration) Update() {
 	o.Addr = fmt.Sprintf("%s:%d", o.Host, o.Port)
+	o.GrpcAddr = fmt.Sprintf("%s:%d", o.Host, o.GrpcPort)
 	o.Software = fmt.Sprintf("%s/%s", o.Name, o.Version)
 }
 
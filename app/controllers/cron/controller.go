@@ -0,0 +1,61 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+// Package cron
+// MVC Controller with route prefix /cron.
+package cron
+
+import (
+	"github.com/fuyibing/gmd/app/logics"
+	"github.com/fuyibing/gmd/app/logics/cron"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	// Controller
+	// Cron.
+	//
+	// @RoutePrefix(/cron)
+	Controller struct{}
+)
+
+// PostAdd
+// Add scheduled publication.
+//
+// Publish message to topic and tag on cron expression in specified
+// timezone. Each fire time is published at most once in cluster.
+//
+// @Request(app/logics/cron.AddRequest)
+// @Response(app/logics/cron.AddResponse)
+func (o *Controller) PostAdd(i iris.Context) interface{} {
+	return logics.New(i, cron.NewAdd().Run)
+}
+
+// PostHistory
+// Latest run histories of scheduled publication.
+//
+// @Request(app/logics/cron.HistoryRequest)
+// @Response(app/logics/cron.HistoryResponse)
+func (o *Controller) PostHistory(i iris.Context) interface{} {
+	return logics.New(i, cron.NewHistory().Run)
+}
+
+// PostPause
+// Pause scheduled publication.
+//
+// @Request(app/logics/cron.EditStatus)
+// @Response(app/logics/cron.EditResponse)
+func (o *Controller) PostPause(i iris.Context) interface{} {
+	return logics.New(i, cron.NewEditPause().Run)
+}
+
+// PostResume
+// Resume scheduled publication.
+//
+// Ticks missed when paused are not fired.
+//
+// @Request(app/logics/cron.EditStatus)
+// @Response(app/logics/cron.EditResponse)
+func (o *Controller) PostResume(i iris.Context) interface{} {
+	return logics.New(i, cron.NewEditResume().Run)
+}
@@ -4,6 +4,8 @@
 package controllers
 
 import (
+	"github.com/fuyibing/gmd/app/controllers/cron"
+	"github.com/fuyibing/gmd/app/controllers/message"
 	"github.com/fuyibing/gmd/app/controllers/task"
 	"github.com/fuyibing/gmd/app/controllers/topic"
 	"sync"
@@ -14,9 +16,11 @@ var ControllerRegistration map[string]interface{}
 func init() {
 	new(sync.Once).Do(func() {
 		ControllerRegistration = map[string]interface{}{
-			"/":      &Controller{},
-			"task":   &task.Controller{},
-			"/topic": &topic.Controller{},
+			"/":        &Controller{},
+			"/cron":    &cron.Controller{},
+			"/message": &message.Controller{},
+			"task":     &task.Controller{},
+			"/topic":   &topic.Controller{},
 		}
 	})
 }
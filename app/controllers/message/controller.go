@@ -0,0 +1,44 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+// Package message
+// MVC Controller with route prefix /message.
+package message
+
+import (
+	"github.com/fuyibing/gmd/app/logics"
+	"github.com/fuyibing/gmd/app/logics/message"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	// Controller
+	// Message.
+	//
+	// @RoutePrefix(/message)
+	Controller struct{}
+)
+
+// PostAck
+// Acknowledge accepted message.
+//
+// Called by handler which responded 202 on delivering, the message
+// is completed as succeed.
+//
+// @Request(app/logics/message.AckRequest)
+// @Response(app/logics/message.AckResponse)
+func (o *Controller) PostAck(i iris.Context) interface{} {
+	return logics.New(i, message.NewAck().Run)
+}
+
+// PostNack
+// Negative acknowledge accepted message.
+//
+// Called by handler which responded 202 on delivering, the message
+// is retried if max retry not reached, otherwise failed.
+//
+// @Request(app/logics/message.AckRequest)
+// @Response(app/logics/message.AckResponse)
+func (o *Controller) PostNack(i iris.Context) interface{} {
+	return logics.New(i, message.NewNack().Run)
+}
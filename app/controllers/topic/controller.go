@@ -31,6 +31,18 @@ func (o *Controller) PostBatch(i iris.Context) interface{} {
 	return logics.New(i, topic.NewBatch().Run)
 }
 
+// PostPriority
+// Set priority.
+//
+// Set default publish priority of registry, it is applied when
+// registries reloaded into memory.
+//
+// @Request(app/logics/topic.PriorityRequest)
+// @Response(app/logics/topic.PriorityResponse)
+func (o *Controller) PostPriority(i iris.Context) interface{} {
+	return logics.New(i, topic.NewPriority().Run)
+}
+
 // PostPublish
 // Publish one.
 //
@@ -42,3 +54,28 @@ func (o *Controller) PostBatch(i iris.Context) interface{} {
 func (o *Controller) PostPublish(i iris.Context) interface{} {
 	return logics.New(i, topic.NewPublish().Run)
 }
+
+// PostSchema
+// Get schema.
+//
+// Return json schema of registry, latest version returned if version
+// not specified. Subscribers read message contract from it.
+//
+// @Request(app/logics/topic.SchemaRequest)
+// @Response(app/logics/topic.SchemaResponse)
+func (o *Controller) PostSchema(i iris.Context) interface{} {
+	return logics.New(i, topic.NewSchema().Run)
+}
+
+// PostSchemaAdd
+// Add schema.
+//
+// Add new version of json schema for registry, rejected if not backward
+// compatible with latest version unless force specified. Published
+// messages are validated when registries reloaded into memory.
+//
+// @Request(app/logics/topic.SchemaAddRequest)
+// @Response(app/logics/topic.SchemaResponse)
+func (o *Controller) PostSchemaAdd(i iris.Context) interface{} {
+	return logics.New(i, topic.NewSchemaAdd().Run)
+}
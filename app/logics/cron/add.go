@@ -0,0 +1,149 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+	"strings"
+	"time"
+)
+
+type (
+	Add struct {
+		cron     *base.Cron
+		request  *AddRequest
+		response *AddResponse
+	}
+
+	AddRequest struct {
+		Title      string      `json:"title" validate:"required,lte=80" mock:"Daily report" label:"Cron name"`
+		TopicName  string      `json:"topic_name" validate:"required,min=2,max=30" mock:"reports" label:"Topic name"`
+		TopicTag   string      `json:"topic_tag" validate:"required,min=2,max=60" mock:"daily" label:"Topic tag"`
+		Expression string      `json:"expression" validate:"required,max=64" mock:"0 9 * * 1-5" label:"Cron expression" desc:"Standard 5 fields: minute, hour, day of month, month and day of week.<br />Accept: *, number, range (1-5), step (*/10) and list (1,15,30).<br />Descriptor: @yearly, @monthly, @weekly, @daily, @hourly"`
+		Timezone   string      `json:"timezone" validate:"omitempty,max=64" mock:"Asia/Shanghai" label:"Timezone" desc:"IANA timezone name of expression.<br />Default: UTC"`
+		Message    interface{} `json:"message" label:"Message content" desc:"Accept json string or json object"`
+
+		MessageBody string `json:"-" label:"Message content" validate:"required,min=2,max=65536"`
+	}
+
+	AddResponse struct {
+		Id         int    `json:"id" mock:"1" label:"Cron id"`
+		Title      string `json:"title" mock:"Daily report" label:"Cron name"`
+		NextAt     int64  `json:"next_at" mock:"1677200400" label:"Next fire time" desc:"Unix timestamp in seconds"`
+		RegistryId int    `json:"registry_id" mock:"1" label:"Registry id"`
+	}
+)
+
+func NewAdd() *Add {
+	return &Add{
+		request:  &AddRequest{},
+		response: &AddResponse{},
+	}
+}
+
+func (o *Add) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = o.request.Validate(); err == nil {
+		err = request.Validate.Struct(o.request)
+	}
+	if err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Return error
+	// if expression or timezone invalid.
+	if o.cron, err = base.NewCron(o.request.Expression, o.request.Timezone); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: topic-name=%s, topic-tag=%s, expression=%s", o.request.TopicName, o.request.TopicTag, o.request.Expression)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *Add) Send(ctx context.Context) (code int, err error) {
+	var (
+		bean     *models.Cron
+		next     time.Time
+		registry *base.Registry
+		sess     = db.Connector.GetMasterWithContext(ctx)
+	)
+
+	// Return error
+	// if registry not found in memory.
+	if registry = base.Memory.GetRegistryByName(o.request.TopicName, o.request.TopicTag); registry == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Return error
+	// if expression never fired.
+	if next = o.cron.Next(time.Now()); next.IsZero() {
+		code = app.CodeInvalidPayloadFields
+		err = fmt.Errorf("cron expression never fired")
+		return
+	}
+
+	// Create cron.
+	if bean, err = services.NewCronService(sess).Add(&models.Cron{
+		Title:       o.request.Title,
+		Expression:  o.cron.Expression,
+		Timezone:    o.cron.Location.String(),
+		NextAt:      next.Unix(),
+		RegistryId:  registry.Id,
+		MessageBody: o.request.MessageBody,
+	}); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Id = bean.Id
+	o.response.Title = bean.Title
+	o.response.NextAt = bean.NextAt
+	o.response.RegistryId = bean.RegistryId
+	return
+}
+
+func (o *AddRequest) Validate() error {
+	if s, ok := o.Message.(string); ok {
+		o.MessageBody = strings.TrimSpace(s)
+		return nil
+	}
+
+	buf, _ := json.Marshal(o.Message)
+	o.MessageBody = string(buf)
+	return nil
+}
@@ -0,0 +1,108 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package cron
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	EditPause struct {
+		request  *EditStatus
+		response *EditResponse
+	}
+
+	EditResponse struct {
+		Affects int64  `json:"affects" mock:"1" label:"Updated count"`
+		Id      int    `json:"id" mock:"1" label:"Cron id"`
+		Title   string `json:"title" mock:"Daily report" label:"Cron name"`
+		NextAt  int64  `json:"next_at" mock:"1677200400" label:"Next fire time" desc:"Unix timestamp in seconds"`
+	}
+
+	EditStatus struct {
+		Id int `json:"id" validate:"required,gte=1" mock:"1" label:"Cron id"`
+	}
+)
+
+func NewEditPause() *EditPause {
+	return &EditPause{
+		request:  &EditStatus{},
+		response: &EditResponse{},
+	}
+}
+
+func (o *EditPause) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: cron-id=%d", o.request.Id)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *EditPause) Send(ctx context.Context) (code int, err error) {
+	var (
+		affects int64
+		bean    *models.Cron
+		sess    = db.Connector.GetMasterWithContext(ctx)
+		service = services.NewCronService(sess)
+	)
+
+	// Read cron
+	// bean from database.
+	if bean, err = service.GetById(o.request.Id); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if cron not found.
+	if bean == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("cron not found")
+		return
+	}
+
+	// Send update service.
+	if affects, err = service.SetStatusAsDisabled(bean.Id); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Affects = affects
+	o.response.Id = bean.Id
+	o.response.Title = bean.Title
+	return
+}
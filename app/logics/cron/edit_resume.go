@@ -0,0 +1,115 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package cron
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+	"time"
+)
+
+type (
+	EditResume struct {
+		request  *EditStatus
+		response *EditResponse
+	}
+)
+
+func NewEditResume() *EditResume {
+	return &EditResume{
+		request:  &EditStatus{},
+		response: &EditResponse{},
+	}
+}
+
+func (o *EditResume) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: cron-id=%d", o.request.Id)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *EditResume) Send(ctx context.Context) (code int, err error) {
+	var (
+		affects int64
+		bean    *models.Cron
+		expr    *base.Cron
+		next    time.Time
+		sess    = db.Connector.GetMasterWithContext(ctx)
+		service = services.NewCronService(sess)
+	)
+
+	// Read cron
+	// bean from database.
+	if bean, err = service.GetById(o.request.Id); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if cron not found.
+	if bean == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("cron not found")
+		return
+	}
+
+	// Return error
+	// if expression never fired.
+	if expr, err = base.NewCron(bean.Expression, bean.Timezone); err != nil {
+		code = app.CodeInvalidPayloadFields
+		return
+	}
+	if next = expr.Next(time.Now()); next.IsZero() {
+		code = app.CodeInvalidPayloadFields
+		err = fmt.Errorf("cron expression never fired")
+		return
+	}
+
+	// Send update service,
+	// ticks missed when paused are not fired.
+	if affects, err = service.SetStatusAsEnabled(bean.Id, next.Unix()); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Affects = affects
+	o.response.Id = bean.Id
+	o.response.Title = bean.Title
+	o.response.NextAt = next.Unix()
+	return
+}
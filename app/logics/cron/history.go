@@ -0,0 +1,139 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package cron
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	History struct {
+		request  *HistoryRequest
+		response *HistoryResponse
+	}
+
+	HistoryRequest struct {
+		Id    int `json:"id" validate:"required,gte=1" mock:"1" label:"Cron id"`
+		Limit int `json:"limit" validate:"omitempty,gte=1,lte=100" mock:"20" label:"Limit" desc:"Latest run histories count.<br />Default: 20"`
+	}
+
+	HistoryResponse struct {
+		Id         int            `json:"id" mock:"1" label:"Cron id"`
+		Title      string         `json:"title" mock:"Daily report" label:"Cron name"`
+		Status     int            `json:"status" mock:"1" label:"Status" desc:"0: Paused<br />1: Running"`
+		Expression string         `json:"expression" mock:"0 9 * * 1-5" label:"Cron expression"`
+		Timezone   string         `json:"timezone" mock:"Asia/Shanghai" label:"Timezone"`
+		NextAt     int64          `json:"next_at" mock:"1677200400" label:"Next fire time" desc:"Unix timestamp in seconds"`
+		Histories  []*HistoryItem `json:"histories" label:"Run histories"`
+	}
+
+	HistoryItem struct {
+		FireAt     int64  `json:"fire_at" mock:"1677200400" label:"Fire time" desc:"Unix timestamp in seconds"`
+		Status     int    `json:"status" mock:"1" label:"Status" desc:"1: Succeed<br />2: Failed<br />4: Publishing"`
+		Node       string `json:"node" mock:"host-1A2B3C4D" label:"Node"`
+		Hash       string `json:"hash" mock:"C0837A1B5E264F19826F31457D51546D" label:"Message hash"`
+		MessageId  string `json:"message_id" mock:"" label:"Message id"`
+		Error      string `json:"error" mock:"" label:"Failed reason"`
+		GmtCreated string `json:"gmt_created" mock:"2023-02-24 09:00:00" label:"Run time"`
+	}
+)
+
+func NewHistory() *History {
+	return &History{
+		request:  &HistoryRequest{},
+		response: &HistoryResponse{Histories: make([]*HistoryItem, 0)},
+	}
+}
+
+func (o *History) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: cron-id=%d", o.request.Id)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *History) Send(ctx context.Context) (code int, err error) {
+	var (
+		bean *models.Cron
+		list []*models.CronHistory
+		sess = db.Connector.GetSlaveWithContext(ctx)
+	)
+
+	// Read cron
+	// bean from database.
+	if bean, err = services.NewCronService(sess).GetById(o.request.Id); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if cron not found.
+	if bean == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("cron not found")
+		return
+	}
+
+	// Read latest
+	// run histories.
+	if o.request.Limit == 0 {
+		o.request.Limit = 20
+	}
+	if list, err = services.NewCronHistoryService(sess).ListByCron(bean.Id, o.request.Limit); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Set response result.
+	o.response.Id = bean.Id
+	o.response.Title = bean.Title
+	o.response.Status = bean.Status
+	o.response.Expression = bean.Expression
+	o.response.Timezone = bean.Timezone
+	o.response.NextAt = bean.NextAt
+	for _, x := range list {
+		o.response.Histories = append(o.response.Histories, &HistoryItem{
+			FireAt:     x.FireAt,
+			Status:     x.Status,
+			Node:       x.Node,
+			Hash:       x.Hash,
+			MessageId:  x.MessageId,
+			Error:      x.Error,
+			GmtCreated: x.GmtCreated.String(),
+		})
+	}
+	return
+}
@@ -6,6 +6,7 @@ package index
 import (
 	"context"
 	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md"
 	"github.com/fuyibing/gmd/app/models"
 	"github.com/fuyibing/util/v8/web/response"
 	"github.com/kataras/iris/v12"
@@ -19,11 +20,13 @@ type (
 	}
 
 	PingResponse struct {
-		Cpu       int     `json:"cpu" label:"CPU core count" mock:"8"`
-		Goroutine int     `json:"goroutines" label:"Coroutine counts" mock:"32"`
-		Memory    float64 `json:"memory" label:"Used system memory" desc:"Unit: MB" mock:"16.57"`
-		Pid       int     `json:"pid" label:"Process ID" mock:"3721"`
-		StartTime string  `json:"start_time" label:"Started time" mock:"2022-01-19 14:21:25"`
+		Bucket    map[string]int `json:"bucket" label:"Producer bucket" desc:"Payloads count of each priority lane"`
+		Cpu       int            `json:"cpu" label:"CPU core count" mock:"8"`
+		Expired   int64          `json:"expired" label:"Expired messages" desc:"Messages not delivered for expired since started" mock:"0"`
+		Goroutine int            `json:"goroutines" label:"Coroutine counts" mock:"32"`
+		Memory    float64        `json:"memory" label:"Used system memory" desc:"Unit: MB" mock:"16.57"`
+		Pid       int            `json:"pid" label:"Process ID" mock:"3721"`
+		StartTime string         `json:"start_time" label:"Started time" mock:"2022-01-19 14:21:25"`
 	}
 )
 
@@ -43,5 +46,7 @@ func (o *Ping) Run(_ context.Context, _ iris.Context) interface{} {
 	runtime.ReadMemStats(&m)
 
 	o.response.Memory = float64(int((float64(m.Sys)/1024/1024)*100)) / 100
+	o.response.Bucket = md.Boot.Producer().Bucket().Lengths()
+	o.response.Expired = md.Boot.Consumer().Container().Worker().Expired()
 	return response.With.Data(o.response)
 }
@@ -0,0 +1,109 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package message
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	Ack struct {
+		acked    bool
+		request  *AckRequest
+		response *AckResponse
+	}
+
+	AckRequest struct {
+		Token  string `json:"token" validate:"required,max=32" mock:"C0837A1B5E264F19826F31457D51546D" label:"Ack token" desc:"Token of X-Gmd-Ack-Token header when message delivered."`
+		Result string `json:"result" validate:"max=65536" label:"Result" desc:"Stored as response body of message."`
+	}
+
+	AckResponse struct {
+		Affects int64 `json:"affects" mock:"1" label:"Updated count"`
+		Id      int64 `json:"id" mock:"1" label:"Message id"`
+	}
+)
+
+func NewAck() *Ack {
+	return &Ack{
+		acked:    true,
+		request:  &AckRequest{},
+		response: &AckResponse{},
+	}
+}
+
+func (o *Ack) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: token=%s, acked=%v", o.request.Token, o.acked)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *Ack) Send(ctx context.Context) (code int, err error) {
+	var (
+		affects int64
+		bean    *models.Message
+		sess    = db.Connector.GetMasterWithContext(ctx)
+		service = services.NewMessageService(sess)
+	)
+
+	// Read message
+	// bean from database.
+	if bean, err = service.GetByAckToken(o.request.Token); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if message not found or not waiting acknowledgement.
+	if bean == nil || bean.Status != models.StatusProcessing || bean.AckDeadline == 0 {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("message not found or acknowledged already")
+		return
+	}
+
+	// Send complete.
+	if affects, err = md.Boot.Ack().Done(ctx, bean, o.acked, o.request.Result); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Affects = affects
+	o.response.Id = bean.Id
+	return
+}
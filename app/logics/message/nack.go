@@ -0,0 +1,12 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package message
+
+func NewNack() *Ack {
+	return &Ack{
+		acked:    false,
+		request:  &AckRequest{},
+		response: &AckResponse{},
+	}
+}
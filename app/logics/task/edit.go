@@ -5,6 +5,7 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/fuyibing/db/v8"
 	"github.com/fuyibing/gmd/app"
@@ -30,8 +31,19 @@ type (
 		Concurrency  *int32  `json:"concurrency" validate:"required,gte=0" mock:"10" label:"Max concurrency" desc:"Max consuming message per consumer.<br />Default: 10.<br />Total: Nodes x Parallels * Concurrency.<br />Attention: If this value is set too large, the subscription service will be killed when there are too many messages in the queue (similar to DDOS)"`
 		MaxRetry     *int    `json:"max_retry" validate:"required,gte=0" mock:"3" label:"Max consume times" desc:"Max consume times if failed returned.<br />Default: 3."`
 		Broadcasting *int    `json:"broadcasting"  mock:"0" label:"Broadcast enabled" desc:"When enabled, all consumers of each deployment node will consume.<br />0: Disabled<br />1: Enabled"`
+		Ordered      *int    `json:"ordered" validate:"omitempty,oneof=0 1" mock:"0" label:"Ordered delivery" desc:"When enabled, messages with the same key are delivered one at a time in publish order, messages with different keys are delivered in parallel. Aliyunmns keeps order of messages received by the same consumer node.<br />0: Disabled<br />1: Enabled"`
+		Dedupe       *int    `json:"dedupe" validate:"omitempty,oneof=0 1" mock:"0" label:"Dedupe" desc:"When enabled, message delivered already or delivering on other node is skipped before delivery, used to protect non-idempotent handler.<br />0: Disabled<br />1: Enabled"`
+		MaxAge       *int    `json:"max_age_seconds" validate:"omitempty,gte=0" mock:"0" label:"Max age" desc:"Message older than specified seconds since published is not delivered and stored as expired, should be greater than delay seconds.<br />Default: 0 (never expired)"`
+		AckTimeout   *int    `json:"ack_timeout" validate:"omitempty,gte=0,lte=86400" mock:"0" label:"Ack timeout" desc:"When greater than 0, handler can respond 202 and acknowledge by /message/ack or /message/nack with token of X-Gmd-Ack-Token header, the message is retried if not acknowledged in specified seconds.<br />Default: 0 (synchronous)"`
+		RateLimit    *int    `json:"rate_limit" validate:"omitempty,gte=0" mock:"0" label:"Rate limit" desc:"Max delivered messages per second on each node, messages are kept in queue if limited.<br />Default: 0 (not limited)"`
+		RateBurst    *int    `json:"rate_burst" validate:"omitempty,gte=0" mock:"0" label:"Rate burst" desc:"Max messages delivered at once if rate limited.<br />Default: 0 (equal to rate limit)"`
 		Title        *string `json:"title" mock:"Example task" label:"Task name"`
 		Remark       *string `json:"remark" mock:"Description about task" label:"Task remark"`
+
+		ConcurrencyAdaptive *int   `json:"concurrency_adaptive" validate:"omitempty,oneof=0 1" mock:"0" label:"Adaptive concurrency" desc:"When enabled, effective concurrency is tuned between min concurrency and max concurrency by latency and error rate of delivering.<br />0: Disabled<br />1: Enabled"`
+		ConcurrencyMin      *int32 `json:"concurrency_min" validate:"omitempty,gte=0" mock:"1" label:"Min concurrency" desc:"Min consuming message per consumer if adaptive concurrency enabled.<br />Default: 1"`
+		GlobalConcurrency   *int   `json:"global_concurrency" validate:"omitempty,gte=0" mock:"0" label:"Cluster concurrency" desc:"Max consuming message in cluster, shared equally by alive nodes.<br />Default: 0 (not limited)"`
+		GlobalRateLimit     *int   `json:"global_rate_limit" validate:"omitempty,gte=0" mock:"0" label:"Cluster rate limit" desc:"Max delivered messages per second in cluster, shared equally by alive nodes.<br />Default: 0 (not limited)"`
 	}
 
 	EditResponse struct {
@@ -52,6 +64,14 @@ type (
 		Method       *string `json:"method" label:"Deliver method" desc:"Request method when delivering message. <br />Default: POST"`
 		ResponseType *int    `json:"response_type" label:"Response type" desc:"How to identify the return results of business parties.<br />0: https status code is 200.<br />1: Return json string and errno field value is zero string or integer."`
 		Timeout      *int    `json:"timeout" mock:"10" label:"Timeout" desc:"If response not returned within specified seconds."`
+
+		Balance   *int            `json:"balance" validate:"omitempty,gte=0,lte=2" mock:"0" label:"Balance strategy" desc:"How to select endpoint for each delivery, handler only.<br />0: Round robin.<br />1: Weighted.<br />2: Least in-flight."`
+		Endpoints []*EditEndpoint `json:"endpoints" validate:"omitempty,max=20,dive" label:"Extra endpoints" desc:"Extra endpoints of handler, handler only. Failed endpoint is ejected for a while and the delivery retried on other endpoint.<br />Empty list: remove all extra endpoints."`
+	}
+
+	EditEndpoint struct {
+		Addr   string `json:"addr" validate:"required,url" mock:"http://10.0.0.2/path/route" label:"Endpoint address"`
+		Weight int    `json:"weight" validate:"gte=0,lte=100" mock:"1" label:"Endpoint weight" desc:"Used by weighted balance.<br />Default: 1"`
 	}
 )
 
@@ -127,8 +147,19 @@ func (o *Edit) Send(ctx context.Context) (code int, err error) {
 		MaxRetry:     *o.request.MaxRetry,
 		DelaySeconds: *o.request.DelaySeconds,
 		Broadcasting: *o.request.Broadcasting,
+		Ordered:      *o.request.Ordered,
+		Dedupe:       *o.request.Dedupe,
+		AckTimeout:   *o.request.AckTimeout,
+		RateLimit:    *o.request.RateLimit,
+		RateBurst:    *o.request.RateBurst,
 		Title:        *o.request.Title,
 		Remark:       *o.request.Remark,
+
+		ConcurrencyAdaptive: *o.request.ConcurrencyAdaptive,
+		ConcurrencyMin:      *o.request.ConcurrencyMin,
+		GlobalConcurrency:   *o.request.GlobalConcurrency,
+		GlobalRateLimit:     *o.request.GlobalRateLimit,
+		MaxAgeSeconds:       *o.request.MaxAge,
 	}
 
 	// Send update service.
@@ -158,6 +189,36 @@ func (o *EditRequest) Override(x *models.Task) {
 	if o.Broadcasting != nil {
 		o.Broadcasting = &x.Broadcasting
 	}
+	if o.Ordered == nil {
+		o.Ordered = &x.Ordered
+	}
+	if o.Dedupe == nil {
+		o.Dedupe = &x.Dedupe
+	}
+	if o.MaxAge == nil {
+		o.MaxAge = &x.MaxAgeSeconds
+	}
+	if o.AckTimeout == nil {
+		o.AckTimeout = &x.AckTimeout
+	}
+	if o.RateLimit == nil {
+		o.RateLimit = &x.RateLimit
+	}
+	if o.RateBurst == nil {
+		o.RateBurst = &x.RateBurst
+	}
+	if o.ConcurrencyAdaptive == nil {
+		o.ConcurrencyAdaptive = &x.ConcurrencyAdaptive
+	}
+	if o.ConcurrencyMin == nil {
+		o.ConcurrencyMin = &x.ConcurrencyMin
+	}
+	if o.GlobalConcurrency == nil {
+		o.GlobalConcurrency = &x.GlobalConcurrency
+	}
+	if o.GlobalRateLimit == nil {
+		o.GlobalRateLimit = &x.GlobalRateLimit
+	}
 	if o.Title != nil {
 		o.Title = &x.Title
 	}
@@ -212,6 +273,22 @@ func (o *EditSubscriber) OverrideHandler(x *models.Task) {
 	if o.IgnoreCodes == nil {
 		o.IgnoreCodes = &x.HandlerIgnoreCodes
 	}
+	if o.Balance == nil {
+		o.Balance = &x.HandlerBalance
+	}
+}
+
+// HandlerEndpoints
+// return json string of extra endpoints.
+func (o *EditSubscriber) HandlerEndpoints(x *models.Task) string {
+	if o.Endpoints == nil {
+		return x.HandlerEndpoints
+	}
+	if len(o.Endpoints) == 0 {
+		return ""
+	}
+	buf, _ := json.Marshal(o.Endpoints)
+	return string(buf)
 }
 
 func (o *EditSubscriber) OverrideSucceed(x *models.Task) {
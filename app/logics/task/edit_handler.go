@@ -9,6 +9,7 @@ import (
 	"github.com/fuyibing/db/v8"
 	"github.com/fuyibing/gmd/app"
 	"github.com/fuyibing/gmd/app/md"
+	"github.com/fuyibing/gmd/app/md/base"
 	"github.com/fuyibing/gmd/app/models"
 	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
@@ -97,6 +98,16 @@ func (o *EditHandler) Send(ctx context.Context) (code int, err error) {
 		HandlerCondition:    *o.request.Condition,
 		HandlerResponseType: *o.request.ResponseType,
 		HandlerIgnoreCodes:  *o.request.IgnoreCodes,
+		HandlerEndpoints:    o.request.HandlerEndpoints(bean),
+		HandlerBalance:      *o.request.Balance,
+	}
+
+	// Return error
+	// if extra endpoints invalid, stored value is checked too if not
+	// specified in request.
+	if _, err = base.ParseEndpoints(req.HandlerEndpoints); err != nil {
+		code = app.CodeInvalidPayloadFields
+		return
 	}
 
 	// Send update service.
@@ -6,50 +6,107 @@ package topic
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fuyibing/gmd/app"
 	"github.com/fuyibing/gmd/app/md"
 	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/web/request"
 	"github.com/fuyibing/util/v8/web/response"
 	"github.com/google/uuid"
 	"github.com/kataras/iris/v12"
 	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOrderedAborted
+	// error of message with key not published as previous message of
+	// the same key failed.
+	ErrOrderedAborted = errors.New("not published as previous message of the same key failed")
 )
 
 type (
 	Batch struct {
+		offsets  []int
 		registry *base.Registry
 		request  *BatchRequest
 		response *BatchResponse
 	}
 
 	BatchRequest struct {
-		TopicName     string        `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
-		TopicTag      string        `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
-		Messages      []interface{} `json:"messages" label:"Message list" desc:"Accept json string or json object in list"`
-		MessageBodies []string      `json:"-" validate:"required,min=1,max=100" label:"Message list"`
+		TopicName       string        `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
+		TopicTag        string        `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
+		Messages        []interface{} `json:"messages" label:"Message list" desc:"Accept json string or json object in list, empty message is not accepted"`
+		IdempotencyKeys []string      `json:"idempotency_keys" label:"Idempotency keys" desc:"Idempotency key of message with the same index in message list, message is published once in idempotency window, original hash and message id returned in duplicates if duplicated"`
+		DeliverAt       int64         `json:"deliver_at" label:"Deliver time" validate:"omitempty,gte=0" desc:"Unix timestamp in seconds, messages in list are delivered to subscribers at specified time, stored and published by scheduler if delayed longer than adapter supported.<br />Max delay: Rocketmq 2 hours, Aliyunmns 12 hours"`
+		DelaySeconds    int           `json:"delay_seconds" label:"Delay seconds" validate:"omitempty,gte=0" desc:"Messages in list are delivered to subscribers after specified seconds, ignored if deliver time specified"`
+		DeliverAtList   []int64       `json:"deliver_at_list" label:"Deliver time list" validate:"omitempty,dive,gte=0" desc:"Deliver time of message with the same index in message list, batch deliver time used if not specified or zero"`
+		DelayList       []int         `json:"delay_seconds_list" label:"Delay seconds list" validate:"omitempty,dive,gte=0" desc:"Delay seconds of message with the same index in message list, ignored if deliver time of the same index specified"`
+		ExpiresAt       int64         `json:"expires_at" label:"Expire time" validate:"omitempty,gte=0" desc:"Unix timestamp in seconds, messages in list are not delivered and stored as expired after specified time.<br />Accept: Rocketmq, Aliyunmns"`
+		Key             string        `json:"key" label:"Message key" validate:"omitempty,max=128" desc:"Key of all messages in list, they are delivered one at a time in list order if ordered delivery of task enabled"`
+		Priority        string        `json:"priority" label:"Priority" validate:"omitempty,oneof=high normal low" desc:"Publish priority of all messages in list, default priority of registry used if not specified.<br />Accept: high, normal, low"`
+		Sync            bool          `json:"sync" label:"Sync mode" desc:"Return after broker accepted messages with message id of each message in results, failed messages are not retried, message delayed longer than adapter supported is rejected"`
+		MessageBodies   []string      `json:"-" validate:"required,min=1,max=100" label:"Message list"`
+
+		// Idempotency key
+		// of message with the same index in message bodies.
+		IdempotencyKeysNormalized []string `json:"-"`
+
+		// Deliver time
+		// of message with the same index in message bodies.
+		DeliverAtsNormalized []int64 `json:"-"`
 	}
 
 	BatchResponse struct {
-		Count      int    `json:"count" label:"Message count" mock:"3"`
-		Hash       string `json:"hash" label:"Message hash" mock:"C0837A1B5E264F19826F31457D51546D"`
-		RegistryId int    `json:"registry_id" label:"Registry id" mock:"1"`
+		Count      int               `json:"count" label:"Message count" desc:"Published message count, duplicated messages excluded" mock:"3"`
+		Duplicates []*BatchDuplicate `json:"duplicates" label:"Duplicated messages"`
+		Hash       string            `json:"hash" label:"Message hash" mock:"C0837A1B5E264F19826F31457D51546D"`
+		RegistryId int               `json:"registry_id" label:"Registry id" mock:"1"`
+		Results    []*BatchResult    `json:"results" label:"Publish results" desc:"Returned in sync mode, or if message with key failed and messages after it are not published"`
+	}
+
+	BatchDuplicate struct {
+		Index     int    `json:"index" label:"Index in message list" mock:"0"`
+		Hash      string `json:"hash" label:"Original message hash" mock:"C0837A1B5E264F19826F31457D51546D"`
+		MessageId string `json:"message_id" label:"Original message id" mock:""`
+	}
+
+	BatchResult struct {
+		Index     int    `json:"index" label:"Index in message list" mock:"0"`
+		MessageId string `json:"message_id" label:"Message id" desc:"Returned if broker accepted" mock:"7F00000100207A4E8E9A1A4C4D3A0001"`
+		Error     string `json:"error" label:"Adapter error" desc:"Returned if publish failed" mock:""`
 	}
 )
 
 func NewBatch() *Batch {
 	return &Batch{
+		offsets:  make([]int, 0),
 		request:  &BatchRequest{},
-		response: &BatchResponse{},
+		response: &BatchResponse{Duplicates: make([]*BatchDuplicate, 0), Results: make([]*BatchResult, 0)},
 	}
 }
 
+// Response
+// return response of batch.
+func (o *Batch) Response() *BatchResponse { return o.response }
+
+// With
+// assign request, used by grpc service.
+func (o *Batch) With(req *BatchRequest) *Batch {
+	o.request = req
+	return o
+}
+
 func (o *Batch) Run(ctx context.Context, i iris.Context) (res interface{}) {
 	var (
-		c   context.Context
-		err error
+		code int
+		err  error
 	)
 
 	// Read payload json string
@@ -59,83 +116,321 @@ func (o *Batch) Run(ctx context.Context, i iris.Context) (res interface{}) {
 		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
 	}
 
+	// Call do to main process.
+	if code, err = o.Do(ctx); err != nil {
+		// Return too many requests
+		// if bucket overflowed.
+		if code == app.CodeTooManyRequests {
+			return tooManyRequests(i, err)
+		}
+		return response.With.ErrorCode(err, code)
+	}
+	return response.With.Data(o.response)
+}
+
+// Do
+// validate request and publish messages, shared by http api and grpc
+// service.
+//
+// Return error code and error if failed.
+func (o *Batch) Do(ctx context.Context) (code int, err error) {
 	// Validate
 	// requested payload params.
 	if err = o.request.Validate(); err == nil {
 		err = request.Validate.Struct(o.request)
 	}
 	if err != nil {
-		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+		code = app.CodeInvalidPayloadFields
+		return
 	}
 
 	// Return error
 	// if registry not found in memory.
 	if o.registry = base.Memory.GetRegistryByName(o.request.TopicName, o.request.TopicTag); o.registry == nil {
-		return response.With.ErrorCode(
-			fmt.Errorf("registry not found"),
-			app.CodeServiceReadNotFound,
-		)
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Return error
+	// if any message not matched json schema of registry.
+	for i, s := range o.request.MessageBodies {
+		if err = o.registry.Validate(ctx, s); err != nil {
+			code = app.CodeInvalidPayloadFields
+			err = fmt.Errorf("index=%d: %v", i, err)
+			return
+		}
 	}
 
 	// Init key fields
 	// for response.
 	o.response.Hash = strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
 	o.response.RegistryId = o.registry.Id
-	o.response.Count = len(o.request.MessageBodies)
+
+	// Claim idempotency keys, duplicated messages
+	// are not published again.
+	if err = o.Claim(ctx); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Return
+	// if all messages duplicated.
+	if o.response.Count = len(o.offsets); o.response.Count == 0 {
+		return
+	}
 
 	// Message send progress.
 	log.Infofc(ctx, "logic call producer manager: topic=%s, tag=%s, filter=%s, hash=%s, total=%d", o.registry.TopicName, o.registry.TopicTag, o.registry.FilterTag, o.response.Hash, o.response.Count)
-	c = log.NewChild(ctx)
-	if err = o.Send(c); err != nil {
-		return response.With.ErrorCode(
-			fmt.Errorf("message publish failed"),
-			app.CodeAdapterError,
-		)
+	if err = o.Send(log.NewChild(ctx)); err != nil {
+		// Return results
+		// if messages with key before failed one published.
+		var oe *md.OrderedError
+		if errors.As(err, &oe) && oe.Index > 0 {
+			o.SendOrdered(oe)
+			err = nil
+			return
+		}
+
+		o.Release()
+		code, err = sendError(err, o.request.Sync)
+	}
+	return
+}
+
+// Claim
+// idempotency keys of messages.
+func (o *Batch) Claim(ctx context.Context) error {
+	var (
+		bean    *models.Idempotency
+		claimed bool
+		err     error
+		service = services.NewIdempotencyService()
+	)
+
+	for i, k := range o.request.IdempotencyKeysNormalized {
+		// Publish
+		// if idempotency key not specified.
+		if k == "" {
+			o.offsets = append(o.offsets, i)
+			continue
+		}
+
+		// Return
+		// if error occurred.
+		if bean, claimed, err = service.Claim(o.registry.Id, k, o.response.Hash, i, conf.Config.Producer.IdempotencySeconds); err != nil {
+			o.Release()
+			return err
+		}
+
+		// Publish
+		// if claimed.
+		if claimed {
+			o.offsets = append(o.offsets, i)
+			continue
+		}
+
+		// Duplicated.
+		log.Infofc(ctx, "logic ignore duplicated publish: index=%d, idempotency-key=%s, hash=%s", i, k, bean.Hash)
+		o.response.Duplicates = append(o.response.Duplicates, &BatchDuplicate{
+			Index:     i,
+			Hash:      bean.Hash,
+			MessageId: bean.MessageId,
+		})
+	}
+	return nil
+}
+
+// Release
+// claimed idempotency keys if publish failed.
+func (o *Batch) Release() {
+	service := services.NewIdempotencyService()
+
+	for _, i := range o.offsets {
+		if k := o.request.IdempotencyKeysNormalized[i]; k != "" {
+			_, _ = service.Release(o.registry.Id, k, o.response.Hash, i)
+		}
 	}
-	return response.With.Data(o.response)
 }
 
 func (o *Batch) Send(ctx context.Context) error {
 	payloads := make([]*base.Payload, 0)
+	priority := o.registry.Priority
+
+	// Override
+	// default priority of registry.
+	if o.request.Priority != "" {
+		priority = base.ParsePriority(o.request.Priority)
+	}
 
 	// Iterate message list into buffer.
-	for i0, s0 := range o.request.MessageBodies {
-		log.Infofc(ctx, "batch item: offset=%d, item=%d-%d", i0, o.response.Count, i0+1)
+	for i0, o0 := range o.offsets {
+		log.Infofc(ctx, "batch item: offset=%d, item=%d-%d", o0, o.response.Count, i0+1)
 		c0 := log.NewChild(ctx)
 
 		// Append to buffers.
 		payloads = append(payloads, func(c1 context.Context, o1 int, s1 string) *base.Payload {
 			p := base.Pool.AcquirePayload().SetContext(c1)
 			p.Hash = o.response.Hash
+			p.IdempotencyKey = o.request.IdempotencyKeysNormalized[o1]
 			p.Offset = o1
 			p.RegistryId = o.registry.Id
 			p.TopicName = o.registry.TopicName
 			p.TopicTag = o.registry.TopicTag
 			p.FilterTag = o.registry.FilterTag
+			p.Keyword = o.request.Key
+			p.DeliverAt = o.request.DeliverAtsNormalized[o1]
+			p.ExpiresAt = o.request.ExpiresAt
 			p.MessageBody = s1
+			p.Priority = priority
 			return p
-		}(c0, i0, s0))
+		}(c0, o0, o.request.MessageBodies[o0]))
+	}
+
+	// Publish directly
+	// in sync mode.
+	if o.request.Sync {
+		return o.SendSync(payloads)
 	}
 
 	// Send message progress.
 	return md.Boot.Producer().Publish(payloads...)
 }
 
+// SendOrdered
+// assign results of messages with key if any of them failed, messages
+// after failed one are not published. Idempotency keys of failed and
+// not published messages are released.
+func (o *Batch) SendOrdered(oe *md.OrderedError) {
+	offsets := o.offsets
+
+	for i, offset := range offsets {
+		r := &BatchResult{Index: offset}
+		switch {
+		case i == oe.Index:
+			r.Error = oe.Err.Error()
+		case i > oe.Index:
+			r.Error = ErrOrderedAborted.Error()
+		}
+		o.response.Results = append(o.response.Results, r)
+	}
+
+	o.offsets = offsets[oe.Index:]
+	o.response.Count = oe.Index
+	o.Release()
+}
+
+// SendSync
+// publish payloads directly and wait results. Payloads are published
+// one by one if message key specified, otherwise in parallel.
+func (o *Batch) SendSync(payloads []*base.Payload) error {
+	var (
+		errs    = make([]error, len(payloads))
+		offsets = o.offsets
+		wg      = &sync.WaitGroup{}
+	)
+
+	// Send
+	// and collect results, messages with key after failed one are not
+	// published to keep order.
+	for i, p := range payloads {
+		o.response.Results = append(o.response.Results, &BatchResult{Index: offsets[i]})
+		p.SetSync(true)
+
+		if o.request.Key != "" {
+			if i > 0 && errs[i-1] != nil {
+				errs[i] = ErrOrderedAborted
+				p.SetTransient(true)
+				continue
+			}
+			errs[i] = md.Boot.Producer().PublishDirect(p)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, p *base.Payload) {
+			defer wg.Done()
+			errs[i] = md.Boot.Producer().PublishDirect(p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	// Assign results
+	// then release payloads.
+	o.offsets = make([]int, 0)
+	o.response.Count = 0
+	for i, p := range payloads {
+		if errs[i] != nil {
+			o.offsets = append(o.offsets, offsets[i])
+			o.response.Results[i].Error = errs[i].Error()
+		} else {
+			o.response.Count++
+			o.response.Results[i].MessageId = p.GetMessageId()
+		}
+		p.Release()
+	}
+
+	// Return first error
+	// if all messages failed.
+	if o.response.Count == 0 {
+		return errs[0]
+	}
+
+	// Release idempotency keys
+	// of failed messages.
+	o.Release()
+	return nil
+}
+
 func (o *BatchRequest) Validate() error {
 	o.MessageBodies = make([]string, 0)
+	o.IdempotencyKeysNormalized = make([]string, 0)
+	o.DeliverAtsNormalized = make([]int64, 0)
 
-	for _, v := range o.Messages {
-		if s, ok := v.(string); ok {
-			if s = strings.TrimSpace(s); s != "" {
-				o.MessageBodies = append(o.MessageBodies, s)
+	now := time.Now().Unix()
+	if o.DeliverAt == 0 && o.DelaySeconds > 0 {
+		o.DeliverAt = now + int64(o.DelaySeconds)
+	}
+
+	for i, v := range o.Messages {
+		var (
+			d    = o.DeliverAt
+			k, s string
+		)
+
+		// Deliver time
+		// of the same index, override batch deliver time.
+		if i < len(o.DeliverAtList) && o.DeliverAtList[i] > 0 {
+			d = o.DeliverAtList[i]
+		} else if i < len(o.DelayList) && o.DelayList[i] > 0 {
+			d = now + int64(o.DelayList[i])
+		}
+
+		// Idempotency key
+		// of the same index.
+		if i < len(o.IdempotencyKeys) {
+			if k = strings.TrimSpace(o.IdempotencyKeys[i]); len(k) > 128 {
+				return fmt.Errorf("idempotency key too long: index=%d", i)
 			}
-			continue
 		}
 
-		b, _ := json.Marshal(v)
-		if s := strings.TrimSpace(string(b)); s != "" {
-			o.MessageBodies = append(o.MessageBodies, s)
+		if x, ok := v.(string); ok {
+			s = strings.TrimSpace(x)
+		} else {
+			b, _ := json.Marshal(v)
+			s = strings.TrimSpace(string(b))
 		}
+
+		// Return error
+		// if message is empty, so index of duplicates and results is
+		// the same as message list.
+		if s == "" || s == "null" {
+			return fmt.Errorf("empty message: index=%d", i)
+		}
+
+		o.MessageBodies = append(o.MessageBodies, s)
+		o.IdempotencyKeysNormalized = append(o.IdempotencyKeysNormalized, k)
+		o.DeliverAtsNormalized = append(o.DeliverAtsNormalized, d)
 	}
 
 	return nil
@@ -0,0 +1,98 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package topic
+
+import (
+	"errors"
+	"github.com/fuyibing/gmd/app/md"
+	"testing"
+	"time"
+)
+
+func TestBatchRequestValidate(t *testing.T) {
+	req := &BatchRequest{
+		Messages:        []interface{}{"first", map[string]interface{}{"id": 2}, " third "},
+		IdempotencyKeys: []string{"key-1", "", " key-3 "},
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+
+	bodies := []string{"first", `{"id":2}`, "third"}
+	keys := []string{"key-1", "", "key-3"}
+	for i := range bodies {
+		if req.MessageBodies[i] != bodies[i] || req.IdempotencyKeysNormalized[i] != keys[i] {
+			t.Fatalf("index=%d: body=%s, key=%s", i, req.MessageBodies[i], req.IdempotencyKeysNormalized[i])
+		}
+	}
+}
+
+func TestBatchRequestValidateEmpty(t *testing.T) {
+	for _, v := range []interface{}{"", "  ", nil} {
+		req := &BatchRequest{Messages: []interface{}{"first", v, "third"}}
+		if err := req.Validate(); err == nil {
+			t.Fatalf("empty message should be rejected: %#v", v)
+		}
+	}
+}
+
+func TestBatchRequestValidateDeliverAt(t *testing.T) {
+	req := &BatchRequest{
+		Messages:      []interface{}{"first", "second", "third", "fourth"},
+		DeliverAt:     1700000000,
+		DeliverAtList: []int64{0, 1800000000},
+		DelayList:     []int{0, 60, 60},
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+
+	if d := req.DeliverAtsNormalized[0]; d != 1700000000 {
+		t.Fatalf("batch deliver time expected: %d", d)
+	}
+	if d := req.DeliverAtsNormalized[1]; d != 1800000000 {
+		t.Fatalf("deliver time of index expected: %d", d)
+	}
+	if d := req.DeliverAtsNormalized[2]; d < time.Now().Unix()+59 || d > time.Now().Unix()+61 {
+		t.Fatalf("delay seconds of index expected: %d", d)
+	}
+	if d := req.DeliverAtsNormalized[3]; d != 1700000000 {
+		t.Fatalf("batch deliver time expected: %d", d)
+	}
+}
+
+func TestBatchSendOrdered(t *testing.T) {
+	x := NewBatch()
+	x.request = &BatchRequest{Key: "user-1", IdempotencyKeysNormalized: []string{"", "", "", ""}}
+	x.offsets = []int{0, 1, 2, 3}
+
+	// Messages before failed one
+	// are published, failed and not published messages returned with
+	// error.
+	x.SendOrdered(&md.OrderedError{Err: errors.New("adapter error"), Index: 2})
+
+	res := x.response
+	if res.Count != 2 || len(res.Results) != 4 {
+		t.Fatalf("published count and results expected: count=%d, results=%d", res.Count, len(res.Results))
+	}
+	for i, r := range res.Results {
+		if r.Index != i {
+			t.Fatalf("index=%d: result index %d", i, r.Index)
+		}
+		if (i < 2 && r.Error != "") || (i >= 2 && r.Error == "") {
+			t.Fatalf("index=%d: unexpected error: %s", i, r.Error)
+		}
+	}
+	if res.Results[3].Error != ErrOrderedAborted.Error() {
+		t.Fatalf("not published error expected: %s", res.Results[3].Error)
+	}
+
+	// Idempotency keys
+	// of failed and not published messages released only.
+	if len(x.offsets) != 2 || x.offsets[0] != 2 || x.offsets[1] != 3 {
+		t.Fatalf("offsets of released keys: %v", x.offsets)
+	}
+}
@@ -0,0 +1,111 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-26
+
+package topic
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	Priority struct {
+		request  *PriorityRequest
+		response *PriorityResponse
+	}
+
+	PriorityRequest struct {
+		TopicName string `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
+		TopicTag  string `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
+		Priority  string `json:"priority" label:"Priority" validate:"required,oneof=high normal low" desc:"Default publish priority of registry.<br />Accept: high, normal, low"`
+	}
+
+	PriorityResponse struct {
+		Affects    int64  `json:"affects" label:"Updated count" mock:"1"`
+		Priority   string `json:"priority" label:"Priority" mock:"high"`
+		RegistryId int    `json:"registry_id" label:"Registry id" mock:"1"`
+	}
+)
+
+func NewPriority() *Priority {
+	return &Priority{
+		request:  &PriorityRequest{},
+		response: &PriorityResponse{},
+	}
+}
+
+func (o *Priority) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: topic=%s, tag=%s, priority=%s", o.request.TopicName, o.request.TopicTag, o.request.Priority)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *Priority) Send(ctx context.Context) (code int, err error) {
+	var (
+		affects  int64
+		bean     *models.Registry
+		priority = base.ParsePriority(o.request.Priority)
+		sess     = db.Connector.GetMasterWithContext(ctx)
+		service  = services.NewRegistryService(sess)
+	)
+
+	// Read registry
+	// bean from database.
+	if bean, err = service.GetByNames(o.request.TopicName, o.request.TopicTag); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if registry not found.
+	if bean == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Send update service.
+	if affects, err = service.SetPriority(bean.Id, int(priority)); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Affects = affects
+	o.response.Priority = priority.String()
+	o.response.RegistryId = bean.Id
+	return
+}
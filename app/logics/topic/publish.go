@@ -6,16 +6,23 @@ package topic
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fuyibing/gmd/app"
 	"github.com/fuyibing/gmd/app/md"
 	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/web/request"
 	"github.com/fuyibing/util/v8/web/response"
 	"github.com/google/uuid"
 	"github.com/kataras/iris/v12"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type (
@@ -26,15 +33,24 @@ type (
 	}
 
 	PublishRequest struct {
-		TopicName string      `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
-		TopicTag  string      `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
-		Message   interface{} `json:"message" label:"Message content" desc:"Accept json string or json object"`
+		TopicName      string      `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
+		TopicTag       string      `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
+		Message        interface{} `json:"message" label:"Message content" desc:"Accept json string or json object"`
+		IdempotencyKey string      `json:"idempotency_key" label:"Idempotency key" validate:"omitempty,max=128" desc:"Message with the same idempotency key is published once in idempotency window, original hash and message id returned if duplicated"`
+		DeliverAt      int64       `json:"deliver_at" label:"Deliver time" validate:"omitempty,gte=0" desc:"Unix timestamp in seconds, message is delivered to subscribers at specified time, stored and published by scheduler if delayed longer than adapter supported.<br />Max delay: Rocketmq 2 hours, Aliyunmns 12 hours"`
+		DelaySeconds   int         `json:"delay_seconds" label:"Delay seconds" validate:"omitempty,gte=0" desc:"Message is delivered to subscribers after specified seconds, ignored if deliver time specified"`
+		ExpiresAt      int64       `json:"expires_at" label:"Expire time" validate:"omitempty,gte=0" desc:"Unix timestamp in seconds, message is not delivered and stored as expired after specified time.<br />Accept: Rocketmq, Aliyunmns"`
+		Key            string      `json:"key" label:"Message key" validate:"omitempty,max=128" desc:"Messages with the same key are delivered one at a time in publish order if ordered delivery of task enabled, for example: order id"`
+		Priority       string      `json:"priority" label:"Priority" validate:"omitempty,oneof=high normal low" desc:"Publish priority, message of high priority is popped from bucket earlier, default priority of registry used if not specified.<br />Accept: high, normal, low"`
+		Sync           bool        `json:"sync" label:"Sync mode" desc:"Return after broker accepted message with message id, adapter error returned and message is not retried if publish failed, rejected if delayed longer than adapter supported"`
 
 		MessageBody string `json:"-" label:"Message content" validate:"required,min=2,max=65536"`
 	}
 
 	PublishResponse struct {
+		Duplicated bool   `json:"duplicated" label:"Duplicated" desc:"Published already with the same idempotency key" mock:"false"`
 		Hash       string `json:"hash" label:"Message hash" mock:"C0837A1B5E264F19826F31457D51546D"`
+		MessageId  string `json:"message_id" label:"Message id" desc:"Returned if published in sync mode, or duplicated and original message published" mock:""`
 		RegistryId int    `json:"registry_id" label:"Registry id" mock:"1"`
 	}
 )
@@ -46,8 +62,22 @@ func NewPublish() *Publish {
 	}
 }
 
+// Response
+// return response of publish.
+func (o *Publish) Response() *PublishResponse { return o.response }
+
+// With
+// assign request, used by grpc service.
+func (o *Publish) With(req *PublishRequest) *Publish {
+	o.request = req
+	return o
+}
+
 func (o *Publish) Run(ctx context.Context, i iris.Context) (res interface{}) {
-	var err error
+	var (
+		code int
+		err  error
+	)
 
 	// Read payload json string
 	// then assign to request fields.
@@ -56,22 +86,47 @@ func (o *Publish) Run(ctx context.Context, i iris.Context) (res interface{}) {
 		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
 	}
 
+	// Call do to main process.
+	if code, err = o.Do(ctx); err != nil {
+		// Return too many requests
+		// if bucket overflowed.
+		if code == app.CodeTooManyRequests {
+			return tooManyRequests(i, err)
+		}
+		return response.With.ErrorCode(err, code)
+	}
+	return response.With.Data(o.response)
+}
+
+// Do
+// validate request and publish message, shared by http api and grpc
+// service.
+//
+// Return error code and error if failed.
+func (o *Publish) Do(ctx context.Context) (code int, err error) {
 	// Validate
 	// requested payload params.
 	if err = o.request.Validate(); err == nil {
 		err = request.Validate.Struct(o.request)
 	}
 	if err != nil {
-		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+		code = app.CodeInvalidPayloadFields
+		return
 	}
 
 	// Return error
 	// if registry not found in memory.
 	if o.registry = base.Memory.GetRegistryByName(o.request.TopicName, o.request.TopicTag); o.registry == nil {
-		return response.With.ErrorCode(
-			fmt.Errorf("registry not found"),
-			app.CodeServiceReadNotFound,
-		)
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Return error
+	// if message not matched json schema of registry.
+	if err = o.registry.Validate(ctx, o.request.MessageBody); err != nil {
+		code = app.CodeInvalidPayloadFields
+		return
 	}
 
 	// Init key fields
@@ -79,14 +134,36 @@ func (o *Publish) Run(ctx context.Context, i iris.Context) (res interface{}) {
 	o.response.Hash = strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
 	o.response.RegistryId = o.registry.Id
 
+	// Return original hash and message id
+	// if idempotency key published already.
+	if o.request.IdempotencyKey != "" {
+		var (
+			bean    *models.Idempotency
+			claimed bool
+		)
+		if bean, claimed, err = services.NewIdempotencyService().Claim(o.registry.Id, o.request.IdempotencyKey, o.response.Hash, 0, conf.Config.Producer.IdempotencySeconds); err != nil {
+			code = app.CodeServiceWriteError
+			return
+		}
+		if !claimed {
+			o.response.Duplicated = true
+			o.response.Hash = bean.Hash
+			o.response.MessageId = bean.MessageId
+			log.Infofc(ctx, "logic ignore duplicated publish: idempotency-key=%s, hash=%s", o.request.IdempotencyKey, bean.Hash)
+			return
+		}
+	}
+
 	// Message send progress.
 	if err = o.Send(ctx); err != nil {
-		return response.With.ErrorCode(
-			fmt.Errorf("message publish failed"),
-			app.CodeAdapterError,
-		)
+		// Release idempotency key
+		// for retry of publisher.
+		if o.request.IdempotencyKey != "" {
+			_, _ = services.NewIdempotencyService().Release(o.registry.Id, o.request.IdempotencyKey, o.response.Hash, 0)
+		}
+		code, err = sendError(err, o.request.Sync)
 	}
-	return response.With.Data(o.response)
+	return
 }
 
 func (o *Publish) Send(ctx context.Context) error {
@@ -98,17 +175,45 @@ func (o *Publish) Send(ctx context.Context) error {
 	)
 
 	p.Hash = o.response.Hash
+	p.IdempotencyKey = o.request.IdempotencyKey
 	p.Offset = 0
 	p.RegistryId = o.registry.Id
 	p.TopicName = o.registry.TopicName
 	p.TopicTag = o.registry.TopicTag
 	p.FilterTag = o.registry.FilterTag
+	p.Keyword = o.request.Key
+	p.DeliverAt = o.request.DeliverAt
+	p.ExpiresAt = o.request.ExpiresAt
 	p.MessageBody = o.request.MessageBody
+	p.Priority = o.registry.Priority
+
+	// Override
+	// default priority of registry.
+	if o.request.Priority != "" {
+		p.Priority = base.ParsePriority(o.request.Priority)
+	}
+
+	// Publish directly
+	// in sync mode and return message id.
+	if o.request.Sync {
+		p.SetSync(true)
+		defer p.Release()
+
+		if err := md.Boot.Producer().PublishDirect(p); err != nil {
+			return err
+		}
+		o.response.MessageId = p.GetMessageId()
+		return nil
+	}
 
 	return md.Boot.Producer().Publish(p)
 }
 
 func (o *PublishRequest) Validate() error {
+	if o.DeliverAt == 0 && o.DelaySeconds > 0 {
+		o.DeliverAt = time.Now().Unix() + int64(o.DelaySeconds)
+	}
+
 	if s, ok := o.Message.(string); ok {
 		o.MessageBody = strings.TrimSpace(s)
 		return nil
@@ -118,3 +223,34 @@ func (o *PublishRequest) Validate() error {
 	o.MessageBody = string(buf)
 	return nil
 }
+
+// Return error code
+// of send error, adapter error is returned in sync mode only.
+func sendError(err error, sync bool) (int, error) {
+	// Return too many requests
+	// if bucket overflowed.
+	if md.IsOverflow(err) {
+		return app.CodeTooManyRequests, err
+	}
+
+	// Return invalid fields
+	// if delay is longer than adapter supported in sync mode.
+	if errors.Is(err, md.ErrSyncDelay) {
+		return app.CodeInvalidPayloadFields, err
+	}
+
+	// Return adapter error
+	// in sync mode.
+	if sync {
+		return app.CodeAdapterError, err
+	}
+	return app.CodeAdapterError, fmt.Errorf("message publish failed")
+}
+
+// Set status code 429 with Retry-After header, publisher should retry
+// after configured seconds.
+func tooManyRequests(i iris.Context, err error) interface{} {
+	i.Header("Retry-After", strconv.Itoa(conf.Config.Producer.BucketRetryAfter))
+	i.StatusCode(http.StatusTooManyRequests)
+	return response.With.ErrorCode(err, app.CodeTooManyRequests)
+}
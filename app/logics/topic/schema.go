@@ -0,0 +1,125 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package topic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+)
+
+type (
+	Schema struct {
+		request  *SchemaRequest
+		response *SchemaResponse
+	}
+
+	SchemaRequest struct {
+		TopicName string `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
+		TopicTag  string `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
+		Version   int    `json:"version" label:"Schema version" validate:"omitempty,gte=1" desc:"Latest version returned if not specified"`
+	}
+
+	SchemaResponse struct {
+		Mode       string          `json:"mode" label:"Validate mode" desc:"strict: reject message not matched, soft: warning only" mock:"strict"`
+		RegistryId int             `json:"registry_id" label:"Registry id" mock:"1"`
+		Schema     json.RawMessage `json:"schema" label:"JSON schema"`
+		Version    int             `json:"version" label:"Schema version" mock:"1"`
+	}
+)
+
+func NewSchema() *Schema {
+	return &Schema{
+		request:  &SchemaRequest{},
+		response: &SchemaResponse{},
+	}
+}
+
+func (o *Schema) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = request.Validate.Struct(o.request); err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: topic=%s, tag=%s, version=%d", o.request.TopicName, o.request.TopicTag, o.request.Version)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *Schema) Send(_ context.Context) (code int, err error) {
+	var (
+		bean     *models.RegistrySchema
+		registry *models.Registry
+		service  = services.NewRegistrySchemaService()
+	)
+
+	// Return error
+	// if registry not found.
+	if registry, err = services.NewRegistryService().GetByNames(o.request.TopicName, o.request.TopicTag); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+	if registry == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Read schema
+	// of specified or latest version.
+	if o.request.Version > 0 {
+		bean, err = service.GetByVersion(registry.Id, o.request.Version)
+	} else {
+		bean, err = service.GetLatest(registry.Id)
+	}
+	if err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+	if bean == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("schema not found")
+		return
+	}
+
+	// Set response result.
+	o.response.Mode = schemaModeName(bean.Mode)
+	o.response.RegistryId = registry.Id
+	o.response.Schema = json.RawMessage(bean.Content)
+	o.response.Version = bean.Version
+	return
+}
+
+func schemaModeName(mode int) string {
+	if mode == models.SchemaModeSoft {
+		return "soft"
+	}
+	return "strict"
+}
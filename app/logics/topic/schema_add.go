@@ -0,0 +1,162 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package topic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/web/request"
+	"github.com/fuyibing/util/v8/web/response"
+	"github.com/kataras/iris/v12"
+	"strings"
+)
+
+type (
+	SchemaAdd struct {
+		request  *SchemaAddRequest
+		response *SchemaResponse
+	}
+
+	SchemaAddRequest struct {
+		TopicName string      `json:"topic_name" label:"Topic name" validate:"required,min=2,max=30"`
+		TopicTag  string      `json:"topic_tag" label:"Topic tag" validate:"required,min=2,max=60"`
+		Schema    interface{} `json:"schema" label:"JSON schema" desc:"Accept json string or json object.<br />Keywords: type, properties, required, additionalProperties, items, enum, minimum, maximum, minLength, maxLength, pattern, minItems, maxItems"`
+		Mode      string      `json:"mode" label:"Validate mode" validate:"omitempty,oneof=strict soft" desc:"strict: reject message not matched, soft: warning only.<br />Default: strict"`
+		Force     bool        `json:"force" label:"Force" desc:"Add new version even if not backward compatible with latest version"`
+
+		Content string `json:"-" label:"JSON schema" validate:"required,min=2,max=65536"`
+	}
+)
+
+func NewSchemaAdd() *SchemaAdd {
+	return &SchemaAdd{
+		request:  &SchemaAddRequest{},
+		response: &SchemaResponse{},
+	}
+}
+
+func (o *SchemaAdd) Run(ctx context.Context, i iris.Context) (res interface{}) {
+	var (
+		code int
+		err  error
+	)
+
+	// Read payload json string
+	// then assign to request fields.
+	if i.ReadJSON(o.request) != nil {
+		err = fmt.Errorf("invalid json payload")
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFormat)
+	}
+
+	// Validate
+	// requested payload params.
+	if err = o.request.Validate(); err == nil {
+		err = request.Validate.Struct(o.request)
+	}
+	if err != nil {
+		return response.With.ErrorCode(err, app.CodeInvalidPayloadFields)
+	}
+
+	// Call send to do main process.
+	log.Infofc(ctx, "logic send request: topic=%s, tag=%s, mode=%s, force=%v", o.request.TopicName, o.request.TopicTag, o.request.Mode, o.request.Force)
+	c := log.NewChild(ctx)
+	if code, err = o.Send(c); err != nil {
+		return response.With.ErrorCode(err, code)
+	}
+
+	// Return succeed response.
+	return response.With.Data(o.response)
+}
+
+func (o *SchemaAdd) Send(_ context.Context) (code int, err error) {
+	var (
+		bean, latest *models.RegistrySchema
+		current      *base.Schema
+		registry     *models.Registry
+		service      = services.NewRegistrySchemaService()
+		version      = 1
+	)
+
+	// Return error
+	// if schema can not be parsed.
+	if current, err = base.NewSchema(o.request.Content); err != nil {
+		code = app.CodeInvalidPayloadFields
+		return
+	}
+
+	// Return error
+	// if registry not found.
+	if registry, err = services.NewRegistryService().GetByNames(o.request.TopicName, o.request.TopicTag); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+	if registry == nil {
+		code = app.CodeServiceReadNotFound
+		err = fmt.Errorf("registry not found")
+		return
+	}
+
+	// Read
+	// latest version.
+	if latest, err = service.GetLatest(registry.Id); err != nil {
+		code = app.CodeServiceReadError
+		return
+	}
+
+	// Return error
+	// if not backward compatible with latest version.
+	if latest != nil {
+		version = latest.Version + 1
+
+		if !o.request.Force {
+			if previous, pe := base.NewSchema(latest.Content); pe == nil {
+				if err = current.Compatible(previous); err != nil {
+					code = app.CodeInvalidPayloadFields
+					err = fmt.Errorf("schema not backward compatible with version %d, %v", latest.Version, err)
+					return
+				}
+			}
+		}
+	}
+
+	// Add
+	// new version.
+	mode := models.SchemaModeStrict
+	if o.request.Mode == "soft" {
+		mode = models.SchemaModeSoft
+	}
+	if bean, err = service.Add(&models.RegistrySchema{
+		RegistryId: registry.Id,
+		Version:    version,
+		Mode:       mode,
+		Content:    o.request.Content,
+	}); err != nil {
+		code = app.CodeServiceWriteError
+		return
+	}
+
+	// Set response result.
+	o.response.Mode = schemaModeName(bean.Mode)
+	o.response.RegistryId = registry.Id
+	o.response.Schema = json.RawMessage(bean.Content)
+	o.response.Version = bean.Version
+	return
+}
+
+func (o *SchemaAddRequest) Validate() error {
+	if s, ok := o.Schema.(string); ok {
+		o.Content = strings.TrimSpace(s)
+		return nil
+	}
+
+	buf, _ := json.Marshal(o.Schema)
+	o.Content = string(buf)
+	return nil
+}
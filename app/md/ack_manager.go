@@ -0,0 +1,227 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package md
+
+import (
+	"context"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/process"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	AckManager interface {
+		// Done
+		// complete accepted message.
+		//
+		// Message is succeed if acknowledged, otherwise it is waiting
+		// for retry manager if retry count less than max retry of
+		// task, or failed. Notification is sent if enabled.
+		//
+		//   x := md.Boot.Ack()
+		//   x.Done(ctx, bean, true, "result")
+		Done(ctx context.Context, bean *models.Message, acked bool, result string) (affects int64, err error)
+
+		// Processor
+		// return ack processor interface.
+		//
+		//   x := md.Boot.Ack().Processor()
+		//   x.Start(ctx)
+		Processor() process.Processor
+	}
+
+	ack struct {
+		processor process.Processor
+		running   int32
+	}
+)
+
+// /////////////////////////////////////////////////////////////
+// Interface methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *ack) Done(ctx context.Context, bean *models.Message, acked bool, result string) (affects int64, err error) {
+	var (
+		sess    = db.Connector.GetMasterWithContext(ctx)
+		service = services.NewMessageService(sess)
+		status  = models.StatusSucceed
+		task    = base.Memory.GetTask(bean.TaskId)
+	)
+
+	// Retry or fail
+	// if not acknowledged.
+	if !acked {
+		if task != nil && bean.Retry < task.MaxRetry {
+			status = models.StatusWaiting
+		} else {
+			status = models.StatusFailed
+		}
+	}
+
+	// Return
+	// if completed by other process or coroutine.
+	if affects, err = service.SetAckResult(bean.Id, bean.AckToken, status, result); err != nil || affects == 0 {
+		return
+	}
+
+	log.Infofc(ctx, "ack manager: message completed, bean-id=%d, status=%d", bean.Id, status)
+
+	// Send notification
+	// if enabled.
+	if task != nil {
+		m := (&base.Message{}).SetContext(ctx)
+		m.MessageId = bean.MessageId
+		m.TaskId = bean.TaskId
+
+		switch status {
+		case models.StatusSucceed:
+			if task.EnNotificationSucceed() {
+				Boot.Consumer().Container().Worker().DoNotify(m, conf.Config.Producer.NotificationTopic, conf.Config.Producer.NotificationTagSucceed)
+			}
+		case models.StatusFailed:
+			if task.EnNotificationFailed() {
+				Boot.Consumer().Container().Worker().DoNotify(m, conf.Config.Producer.NotificationTopic, conf.Config.Producer.NotificationTagFailed)
+			}
+		}
+	}
+	return
+}
+
+func (o *ack) Processor() process.Processor { return o.processor }
+
+// /////////////////////////////////////////////////////////////
+// Event methods.
+// /////////////////////////////////////////////////////////////
+
+// OnAfter
+// called when processor stopped.
+func (o *ack) OnAfter(_ context.Context) (ignored bool) {
+	log.Debugf("ack manager: processor stopped")
+	return
+}
+
+// OnBefore
+// called when processor start.
+func (o *ack) OnBefore(_ context.Context) (ignored bool) {
+	log.Debugf("ack manager: start processor")
+	return
+}
+
+// OnCallChannel
+// listen channel signal.
+func (o *ack) OnCallChannel(ctx context.Context) (ignored bool) {
+	log.Debugf("ack manager: listen channel signal")
+
+	ticker := time.NewTicker(time.Duration(conf.Config.Retry.AckSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			go o.CallExpired()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnPanic
+// called with panic at runtime.
+func (o *ack) OnPanic(ctx context.Context, v interface{}) {
+	log.Panicfc(ctx, "ack manager: %v", v)
+}
+
+// /////////////////////////////////////////////////////////////
+// Actions methods.
+// /////////////////////////////////////////////////////////////
+
+// CallExpired
+// complete messages which ack deadline exceeded as not
+// acknowledged.
+func (o *ack) CallExpired() {
+	// Return
+	// if process is running.
+	if !atomic.CompareAndSwapInt32(&o.running, 0, 1) {
+		return
+	}
+
+	// Unlock
+	// when end.
+	redo := false
+	defer func() {
+		atomic.StoreInt32(&o.running, 0)
+
+		// Recall
+		// if not empty.
+		if redo {
+			o.CallExpired()
+		}
+	}()
+
+	redo = o.SendExpired() > 0
+}
+
+func (o *ack) SendExpired() (count int) {
+	var (
+		ctx  context.Context
+		err  error
+		list []*models.Message
+		wg   *sync.WaitGroup
+	)
+
+	// Return
+	// if list expired messages failed.
+	if list, err = services.NewMessageService().ListAckExpired(conf.Config.Retry.AckCount); err != nil {
+		log.Errorf("ack manager: list expired messages failed, error=%v", err)
+		return
+	}
+
+	// Return
+	// if message not found.
+	if count = len(list); count == 0 {
+		return
+	}
+
+	ctx = log.NewContext()
+	log.Infofc(ctx, "ack manager: ack deadline exceeded messages loaded, count=%d", count)
+
+	wg = &sync.WaitGroup{}
+	for _, b0 := range list {
+		wg.Add(1)
+		c0 := log.NewChild(ctx)
+		go func(c1 context.Context, b1 *models.Message) {
+			defer wg.Done()
+			if _, e1 := o.Done(c1, b1, false, "ack deadline exceeded"); e1 != nil {
+				log.Errorfc(c1, "ack manager: complete expired message failed, bean-id=%d, error=%v", b1.Id, e1)
+			}
+		}(c0, b0)
+	}
+	wg.Wait()
+
+	return
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *ack) init() *ack {
+	// Register ack processor event callbacks.
+	o.processor = process.New("ack manager").After(
+		o.OnAfter,
+	).Before(
+		o.OnBefore,
+	).Callback(
+		o.OnCallChannel,
+	).Panic(o.OnPanic)
+
+	return o
+}
@@ -34,6 +34,8 @@ const (
 	DefaultPollingWaitSeconds     = 30
 	DefaultSlices                 = 0
 	DefaultVisibilityTimeout      = 30
+	DefaultVisibilityMargin       = 10
+	DefaultVisibilityMaxTimeout   = 43200
 )
 
 type (
@@ -60,6 +62,13 @@ type (
 		//   Agent.GenSubscriptionName(1) // return "X-S1"
 		GenSubscriptionName(id int) string
 
+		// GenVisibilityTimeout
+		// generate and return visibility timeout of queue.
+		//
+		// Derived from dispatch timeout of task, so message is not
+		// visible again while delivering.
+		GenVisibilityTimeout(task *base.Task) int32
+
 		// GenTopicName
 		// generate and return topic name.
 		//
@@ -150,6 +159,17 @@ func (o *agent) GenTopicName(name string) string {
 	return fmt.Sprintf("%s%s", conf.Config.Account.Aliyunmns.Prefix, name)
 }
 
+func (o *agent) GenVisibilityTimeout(task *base.Task) int32 {
+	n := int32(task.DispatchTimeout() + DefaultVisibilityMargin)
+	if n < DefaultVisibilityTimeout {
+		return DefaultVisibilityTimeout
+	}
+	if n > DefaultVisibilityMaxTimeout {
+		return DefaultVisibilityMaxTimeout
+	}
+	return n
+}
+
 func (o *agent) GetQueueClient(id int) mns.AliMNSQueue {
 	o.mu.Lock()
 	defer o.mu.Unlock()
@@ -200,8 +220,9 @@ func (o *agent) GetTopicManager() mns.AliTopicManager {
 
 func (o *agent) buildQueue(ctx context.Context, m mns.AliQueueManager, task *base.Task) (err error) {
 	var (
-		attr mns.QueueAttribute
-		name = o.GenQueueName(task.Id)
+		attr       mns.QueueAttribute
+		name       = o.GenQueueName(task.Id)
+		visibility = o.GenVisibilityTimeout(task)
 	)
 
 	// Read queue attribute
@@ -213,13 +234,13 @@ func (o *agent) buildQueue(ctx context.Context, m mns.AliQueueManager, task *bas
 		if attr.DelaySeconds != int32(task.DelaySeconds) ||
 			attr.MaxMessageSize != DefaultMaxMessageSize ||
 			attr.MessageRetentionPeriod != DefaultMessageRetentionPeriod ||
-			attr.VisibilityTimeout != DefaultVisibilityTimeout ||
+			attr.VisibilityTimeout != visibility ||
 			attr.PollingWaitSeconds != DefaultPollingWaitSeconds {
 			if err = m.SetQueueAttributes(name,
 				int32(task.DelaySeconds),
 				DefaultMaxMessageSize,
 				DefaultMessageRetentionPeriod,
-				DefaultVisibilityTimeout,
+				visibility,
 				DefaultPollingWaitSeconds,
 				DefaultSlices); err != nil {
 				return
@@ -228,8 +249,8 @@ func (o *agent) buildQueue(ctx context.Context, m mns.AliQueueManager, task *bas
 
 		// Succeed return
 		// if queue exists and matched on local.
-		log.Infofc(ctx, "aliyunmns-client: queue synced, name=%s, delay=%d, messages=%d, updated=%v",
-			attr.QueueName, task.DelaySeconds,
+		log.Infofc(ctx, "aliyunmns-client: queue synced, name=%s, delay=%d, visibility=%d, messages=%d, updated=%v",
+			attr.QueueName, task.DelaySeconds, visibility,
 			attr.ActiveMessages+attr.InactiveMessages+attr.DelayMessages,
 			time.Unix(attr.LastModifyTime, 0).Format("2006-01-02/15:04:05"),
 		)
@@ -248,16 +269,17 @@ func (o *agent) buildQueue(ctx context.Context, m mns.AliQueueManager, task *bas
 		int32(task.DelaySeconds),
 		DefaultMaxMessageSize,
 		DefaultMessageRetentionPeriod,
-		DefaultVisibilityTimeout,
+		visibility,
 		DefaultPollingWaitSeconds,
 		DefaultSlices); err != nil {
 		return
 	}
 
 	// Return succeed.
-	log.Infofc(ctx, "aliyunmns-client: queue created, name=%s, delay=%d",
+	log.Infofc(ctx, "aliyunmns-client: queue created, name=%s, delay=%d, visibility=%d",
 		name,
 		task.DelaySeconds,
+		visibility,
 	)
 	return
 }
@@ -11,6 +11,8 @@ import (
 	"github.com/fuyibing/gmd/app/md/conf"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/process"
+	"math"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,7 +26,7 @@ type (
 		name      string
 		processor process.Processor
 
-		dispatcher   func(task *base.Task, message *base.Message) (retry bool)
+		dispatcher   func(task *base.Task, message *base.Message) (retry bool, delay time.Duration)
 		id, parallel int
 		task         *base.Task
 
@@ -42,8 +44,10 @@ func NewConsumer(id, parallel int) *Consumer {
 	}).init()
 }
 
-func (o *Consumer) Dispatcher(v func(*base.Task, *base.Message) bool) { o.dispatcher = v }
-func (o *Consumer) Processor() process.Processor                      { return o.processor }
+func (o *Consumer) Dispatcher(v func(*base.Task, *base.Message) (bool, time.Duration)) {
+	o.dispatcher = v
+}
+func (o *Consumer) Processor() process.Processor { return o.processor }
 
 // /////////////////////////////////////////////////////////////
 // Action methods
@@ -68,24 +72,49 @@ func (o *Consumer) doReceivedMessage(res mns.MessageReceiveResponse) {
 
 	log.Infofc(ctx, "%s: message received, dequeue=%d, message-id=%v", o.name, res.DequeueCount, res.MessageId)
 
-	msg = base.Pool.AcquireMessage().SetContext(ctx)
-	msg.Dequeue = int(res.DequeueCount)
-	msg.MessageId = res.MessageId
-	msg.MessageTime = res.EnqueueTime
-
-	// Check topic message.
+	// Check topic message,
+	// properties carried in envelope.
+	body, topicMessageId := res.MessageBody, ""
 	if ok, mi, mb := o.parseTopicMessage(res.MessageBody); ok {
-		msg.MessageBody = mb
-		msg.PayloadMessageId = mi
-	} else {
-		msg.MessageBody = res.MessageBody
+		body, topicMessageId = mb, mi
+	}
+	env := decodeEnvelope(body)
+
+	// Hide message
+	// until deliver time reached, it is not dispatched.
+	if seconds := env.DeliverAt - time.Now().Unix(); seconds > 0 {
+		if seconds > DefaultVisibilityMaxTimeout {
+			seconds = DefaultVisibilityMaxTimeout
+		}
+		log.Infofc(ctx, "%s: message delayed, deliver-at=%d", o.name, env.DeliverAt)
+		o.sendRetry(ctx, res.ReceiptHandle, seconds)
+		return
 	}
 
-	// Call dispatcher.
-	if retry := o.dispatcher(o.task, msg); retry {
-		o.sendRetry(ctx, res.ReceiptHandle, res.DequeueCount)
+	msg = base.Pool.AcquireMessage().SetContext(ctx)
+	msg.Dequeue = env.dequeue(res)
+	msg.MessageId = res.MessageId
+	msg.MessageTime = res.EnqueueTime
+	msg.PayloadMessageId = topicMessageId
+	env.assign(msg)
+
+	// Extend visibility
+	// while delivering.
+	stop := o.keepVisible(ctx, res.ReceiptHandle)
+
+	// Call dispatcher, visible after deferred duration
+	// or dequeue count minutes if retry.
+	retry, delay := o.dispatcher(o.task, msg)
+	handle := stop()
+
+	if retry {
+		if delay > 0 {
+			o.sendRetry(ctx, handle, int64(math.Ceil(delay.Seconds())))
+		} else {
+			o.sendRetry(ctx, handle, res.DequeueCount*60)
+		}
 	} else {
-		o.sendDelete(ctx, res.ReceiptHandle)
+		o.sendDelete(ctx, handle)
 	}
 }
 
@@ -106,12 +135,20 @@ func (o *Consumer) doReceiver(ctx context.Context) {
 
 	// Recall
 	// if concurrency is greater than configured.
-	if processing := atomic.LoadInt32(&o.cliProcessing); processing >= o.task.Concurrency {
+	if processing := atomic.LoadInt32(&o.cliProcessing); processing >= o.task.CurrentConcurrency() {
 		time.Sleep(time.Millisecond * 50)
 		o.doReceiver(ctx)
 		return
 	}
 
+	// Recall
+	// if consumption of task paused.
+	if o.task.Paused() {
+		time.Sleep(conf.PausedSleepDuration)
+		o.doReceiver(ctx)
+		return
+	}
+
 	// Polling message
 	// from aliyunmns queue.
 	func(cli mns.AliMNSQueue, cliRes chan mns.MessageReceiveResponse, cliErr chan error) {
@@ -123,6 +160,45 @@ func (o *Consumer) doReceiver(ctx context.Context) {
 	o.doReceiver(ctx)
 }
 
+// Extend visibility
+// of message in background until stop called, the newest receipt
+// handle is returned by stop.
+func (o *Consumer) keepVisible(ctx context.Context, handle string) (stop func() string) {
+	var (
+		ch      = make(chan bool)
+		seconds = Agent.GenVisibilityTimeout(o.task)
+		wg      = &sync.WaitGroup{}
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ch:
+				return
+			case <-ticker.C:
+				if res, err := o.cli.ChangeMessageVisibility(handle, int64(seconds)); err != nil {
+					log.Warnfc(ctx, "%s: extend message visibility, seconds=%d, error=%v", o.name, seconds, err)
+				} else {
+					handle = res.ReceiptHandle
+					log.Infofc(ctx, "%s: extend message visibility, seconds=%d", o.name, seconds)
+				}
+			}
+		}
+	}()
+
+	return func() string {
+		close(ch)
+		wg.Wait()
+		return handle
+	}
+}
+
 func (o *Consumer) parseTopicMessage(str string) (yes bool, messageId, messageBody string) {
 	v := TopicMessagePool.Get().(*TopicMessage)
 	defer v.Release()
@@ -140,11 +216,11 @@ func (o *Consumer) sendDelete(ctx context.Context, key string) {
 	}
 }
 
-func (o *Consumer) sendRetry(ctx context.Context, key string, minutes int64) {
-	if _, err := o.cli.ChangeMessageVisibility(key, minutes*60); err != nil {
-		log.Warnfc(ctx, "%s: change queue message visibility time, minutes=%d, error=%v", o.name, minutes, err)
+func (o *Consumer) sendRetry(ctx context.Context, key string, seconds int64) {
+	if _, err := o.cli.ChangeMessageVisibility(key, seconds); err != nil {
+		log.Warnfc(ctx, "%s: change queue message visibility time, seconds=%d, error=%v", o.name, seconds, err)
 	} else {
-		log.Infofc(ctx, "%s: change queue message visibility time, minutes=%d", o.name, minutes)
+		log.Infofc(ctx, "%s: change queue message visibility time, seconds=%d", o.name, seconds)
 	}
 }
 
@@ -0,0 +1,73 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package aliyunmns
+
+import (
+	"encoding/json"
+	mns "github.com/aliyun/aliyun-mns-go-sdk"
+	"github.com/fuyibing/gmd/app/md/base"
+	"time"
+)
+
+type (
+	// Envelope
+	// of published message body. Aliyunmns has no custom message
+	// property, so properties of payload are carried in body with
+	// original message.
+	Envelope struct {
+		DeliverAt int64   `json:"GmdDeliverAt,omitempty"`
+		ExpiresAt int64   `json:"GmdExpiresAt,omitempty"`
+		Keyword   string  `json:"GmdKeyword,omitempty"`
+		Message   *string `json:"GmdMessage"`
+	}
+)
+
+// Return message body
+// sent to aliyunmns, original body returned if no property carried.
+func encodeEnvelope(p *base.Payload) string {
+	v := &Envelope{ExpiresAt: p.ExpiresAt, Keyword: p.Keyword, Message: &p.MessageBody}
+
+	// Deliver time
+	// carried if not reached.
+	if p.DeliverAt > time.Now().Unix() {
+		v.DeliverAt = p.DeliverAt
+	}
+
+	if v.DeliverAt == 0 && v.ExpiresAt == 0 && v.Keyword == "" {
+		return p.MessageBody
+	}
+
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
+
+// Return envelope
+// of received body, original body used if not in envelope, so
+// messages published before are accepted.
+func decodeEnvelope(body string) *Envelope {
+	v := &Envelope{}
+	if err := json.Unmarshal([]byte(body), v); err != nil || v.Message == nil {
+		return &Envelope{Message: &body}
+	}
+	return v
+}
+
+// Assign
+// properties and original body to message.
+func (o *Envelope) assign(msg *base.Message) {
+	msg.ExpiresAt = o.ExpiresAt
+	msg.Keyword = o.Keyword
+	msg.MessageBody = *o.Message
+}
+
+// Return dequeue count
+// of received message, receive of hiding message before deliver time
+// is not counted.
+func (o *Envelope) dequeue(res mns.MessageReceiveResponse) int {
+	n := int(res.DequeueCount)
+	if o.DeliverAt > 0 && res.FirstDequeueTime < o.DeliverAt*1000 && n > 1 {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,88 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package aliyunmns
+
+import (
+	mns "github.com/aliyun/aliyun-mns-go-sdk"
+	"github.com/fuyibing/gmd/app/md/base"
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	p := base.Pool.AcquirePayload()
+	p.Keyword = "user-1"
+	p.MessageBody = `{"id":1}`
+
+	body := encodeEnvelope(p)
+	if body == p.MessageBody {
+		t.Fatalf("envelope expected if key specified: %s", body)
+	}
+
+	// Key and original body
+	// assigned to message.
+	m := base.Pool.AcquireMessage()
+	if decodeEnvelope(body).assign(m); m.Keyword != "user-1" || m.MessageBody != `{"id":1}` {
+		t.Fatalf("key and original body expected: key=%s, body=%s", m.Keyword, m.MessageBody)
+	}
+}
+
+func TestEnvelopeOriginal(t *testing.T) {
+	p := base.Pool.AcquirePayload()
+	p.MessageBody = `{"id":1}`
+	p.DeliverAt = time.Now().Add(-time.Minute).Unix()
+
+	// Original body sent
+	// if no property carried, deliver time reached is not carried.
+	if body := encodeEnvelope(p); body != p.MessageBody {
+		t.Fatalf("original body expected: %s", body)
+	}
+
+	// Body assigned directly
+	// if not in envelope.
+	for _, body := range []string{`{"id":1}`, `plain text`, `{"GmdKeyword":"user-1"}`} {
+		m := base.Pool.AcquireMessage()
+		if decodeEnvelope(body).assign(m); m.MessageBody != body || m.Keyword != "" {
+			t.Fatalf("original body expected: key=%s, body=%s", m.Keyword, m.MessageBody)
+		}
+	}
+}
+
+func TestEnvelopeDeliverAt(t *testing.T) {
+	p := base.Pool.AcquirePayload()
+	p.MessageBody = `{"id":1}`
+	p.DeliverAt = time.Now().Add(time.Hour).Unix()
+
+	v := decodeEnvelope(encodeEnvelope(p))
+	if v.DeliverAt != p.DeliverAt || *v.Message != p.MessageBody {
+		t.Fatalf("deliver time and original body expected: deliver-at=%d", v.DeliverAt)
+	}
+
+	// Receive
+	// hiding message before deliver time not counted.
+	if n := v.dequeue(mns.MessageReceiveResponse{DequeueCount: 2, FirstDequeueTime: time.Now().UnixMilli()}); n != 1 {
+		t.Fatalf("hiding receive not counted expected: %d", n)
+	}
+	if n := v.dequeue(mns.MessageReceiveResponse{DequeueCount: 2, FirstDequeueTime: (p.DeliverAt + 1) * 1000}); n != 2 {
+		t.Fatalf("receive after deliver time counted expected: %d", n)
+	}
+}
+
+func TestEnvelopeExpiresAt(t *testing.T) {
+	p := base.Pool.AcquirePayload()
+	p.MessageBody = `{"id":1}`
+	p.ExpiresAt = time.Now().Add(time.Hour).Unix()
+
+	body := encodeEnvelope(p)
+	if body == p.MessageBody {
+		t.Fatalf("envelope expected if expire time specified: %s", body)
+	}
+
+	// Expire time
+	// assigned to message.
+	m := base.Pool.AcquireMessage()
+	if decodeEnvelope(body).assign(m); m.ExpiresAt != p.ExpiresAt || m.MessageBody != p.MessageBody {
+		t.Fatalf("expire time and original body expected: expires-at=%d, body=%s", m.ExpiresAt, m.MessageBody)
+	}
+}
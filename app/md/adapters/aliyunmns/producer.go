@@ -32,14 +32,24 @@ func NewProducer() *Producer {
 
 func (o *Producer) Processor() process.Processor { return o.processor }
 
+// MaxDelay
+// return max visibility timeout of queue, topic message can not be
+// published with delay seconds. Delayed message is hidden by consumer
+// until deliver time reached, delay longer than it is published by
+// scheduler manager.
+func (o *Producer) MaxDelay() time.Duration {
+	return time.Duration(DefaultVisibilityMaxTimeout) * time.Second
+}
+
 // Publish
-// send topic message to aliyunmns.
+// send topic message to aliyunmns, message key, deliver time and
+// expire time are carried in envelope of body.
 func (o *Producer) Publish(payload *base.Payload) (string, error) {
 	atomic.AddInt32(&o.processing, 1)
 	defer atomic.AddInt32(&o.processing, -1)
 
 	res, err := Agent.GetTopicClient(payload.TopicName).PublishMessage(mns.MessagePublishRequest{
-		MessageBody: payload.MessageBody, MessageTag: payload.FilterTag,
+		MessageBody: encodeEnvelope(payload), MessageTag: payload.FilterTag,
 	})
 
 	if err != nil {
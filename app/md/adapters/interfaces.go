@@ -9,6 +9,7 @@ import (
 	"context"
 	"github.com/fuyibing/gmd/app/md/base"
 	"github.com/fuyibing/util/v8/process"
+	"time"
 )
 
 type (
@@ -17,16 +18,37 @@ type (
 	ConsumerAdapter interface {
 		// Dispatcher
 		// bind consume callback for consumer adapter.
-		Dispatcher(dispatcher func(task *base.Task, message *base.Message) (retry bool))
+		//
+		// Adapter should redeliver message after delay if retry
+		// returned and delay is greater than zero.
+		Dispatcher(dispatcher func(task *base.Task, message *base.Message) (retry bool, delay time.Duration))
 
 		// Processor
 		// return processor instance of consumer adapter.
 		Processor() process.Processor
 	}
 
+	// BatchProducerAdapter
+	// optional interface of producer adapter which send payloads of
+	// the same topic in one request.
+	//
+	// Aliyunmns is not implemented, topic of mns can not publish
+	// messages in batch.
+	BatchProducerAdapter interface {
+		// PublishBatch
+		// send payloads of the same topic, message id of each payload
+		// returned in order.
+		PublishBatch(ps []*base.Payload) (messageIds []string, err error)
+	}
+
 	// ProducerAdapter
 	// interface of producer adapter.
 	ProducerAdapter interface {
+		// MaxDelay
+		// return maximum delay of scheduled message supported by
+		// adapter, message delayed longer is scheduled in database.
+		MaxDelay() time.Duration
+
 		// Processor
 		// return processor instance of producer adapter.
 		Processor() process.Processor
@@ -9,6 +9,7 @@ import (
 	"github.com/fuyibing/gmd/app/md/base"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/process"
+	"time"
 )
 
 type (
@@ -20,7 +21,7 @@ type (
 
 		id, parallel int
 		task         *base.Task
-		dispatcher   func(task *base.Task, message *base.Message) (retry bool)
+		dispatcher   func(task *base.Task, message *base.Message) (retry bool, delay time.Duration)
 	}
 )
 
@@ -28,7 +29,7 @@ func NewConsumer(id, parallel int) *Consumer {
 	return (&Consumer{id: id, parallel: parallel}).init()
 }
 
-func (o *Consumer) Dispatcher(dispatcher func(*base.Task, *base.Message) bool) {
+func (o *Consumer) Dispatcher(dispatcher func(*base.Task, *base.Message) (bool, time.Duration)) {
 	o.dispatcher = dispatcher
 }
 
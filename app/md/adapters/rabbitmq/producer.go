@@ -9,6 +9,7 @@ import (
 	"github.com/fuyibing/gmd/app/md/base"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/process"
+	"time"
 )
 
 type (
@@ -24,6 +25,10 @@ func NewProducer() *Producer {
 	return (&Producer{}).init()
 }
 
+func (o *Producer) MaxDelay() time.Duration {
+	return 0
+}
+
 func (o *Producer) Processor() process.Processor {
 	return o.processor
 }
@@ -27,7 +27,7 @@ type (
 
 		client        sdk.PushConsumer
 		clientSuspend bool
-		dispatcher    func(task *base.Task, message *base.Message) (retry bool)
+		dispatcher    func(task *base.Task, message *base.Message) (retry bool, delay time.Duration)
 		id, parallel  int
 		received      *Received
 		task          *base.Task
@@ -45,17 +45,39 @@ func NewConsumer(id, parallel int) *Consumer {
 // Interface methods
 // /////////////////////////////////////////////////////////////
 
-func (o *Consumer) Dispatcher(x func(*base.Task, *base.Message) bool) { o.dispatcher = x }
-func (o *Consumer) Processor() process.Processor                      { return o.processor }
+func (o *Consumer) Dispatcher(x func(*base.Task, *base.Message) (bool, time.Duration)) {
+	o.dispatcher = x
+}
+func (o *Consumer) Processor() process.Processor { return o.processor }
 
 // /////////////////////////////////////////////////////////////
 // Access methods
 // /////////////////////////////////////////////////////////////
 
+// Suspend client
+// if consumption of task paused, resume client if concurrency
+// is less than configured.
+func (o *Consumer) doClientCheck() {
+	if o.task.Paused() {
+		o.doClientSuspend()
+		return
+	}
+
+	if o.received != nil && !o.received.IsBusy() {
+		o.doClientResume()
+	}
+}
+
 func (o *Consumer) doClientResume() {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
+	// Keep suspended
+	// if consumption of task paused.
+	if o.task != nil && o.task.Paused() {
+		return
+	}
+
 	if o.clientSuspend {
 		o.clientSuspend = false
 
@@ -137,8 +159,13 @@ func (o *Consumer) onBefore(_ context.Context) (ignored bool) {
 func (o *Consumer) onCallChannel(ctx context.Context) (ignored bool) {
 	log.Infof("%s: listen channel signal", o.name)
 
+	ticker := time.NewTicker(conf.PausedSleepDuration)
+	defer ticker.Stop()
+
 	for {
 		select {
+		case <-ticker.C:
+			o.doClientCheck()
 		case <-ctx.Done():
 			return
 		}
@@ -164,6 +191,12 @@ func (o *Consumer) onCallClientBuild(_ context.Context) (ignored bool) {
 		opts = append(opts, consumer.WithConsumerModel(consumer.Clustering))
 	}
 
+	// Consume orderly
+	// for ordered task.
+	if o.task.Ordered {
+		opts = append(opts, consumer.WithConsumerOrder(true))
+	}
+
 	// Extension
 	opts = append(opts,
 		consumer.WithInterceptor(o.doInterceptor),
@@ -238,12 +271,13 @@ func (o *Consumer) onCallClientSubscribe(_ context.Context) (ignored bool) {
 		callbackSuspend: o.doClientSuspend,
 	}
 
-	// Delay subscription.
+	// Delay subscription, messages are republished with delay
+	// tag until delay time of task or scheduled deliver time reached.
+	o.received.delayerTag = fmt.Sprintf("%s%d-%s", DefaultDelayTagPrefix, o.task.Id, o.task.TopicTag)
+	o.received.selector.Expression = fmt.Sprintf("%s || %s", o.task.TopicTag, o.received.delayerTag)
 	if o.task.DelaySeconds > 0 {
 		o.received.delayer = true
 		o.received.delayerMilliSeconds = int64(o.task.DelaySeconds * 1000)
-		o.received.delayerTag = fmt.Sprintf("%s%d-%s", DefaultDelayTagPrefix, o.task.Id, o.task.TopicTag)
-		o.received.selector.Expression = fmt.Sprintf("%s || %s", o.task.TopicTag, o.received.delayerTag)
 	}
 
 	// Subscribe topic.
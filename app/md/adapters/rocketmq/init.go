@@ -15,11 +15,17 @@ const (
 	DefaultConsumerGroupName      = "GID"
 	DefaultProducerGroupName      = "GmdProducers"
 	DefaultConsumeSuspendDuration = time.Millisecond * 10
+	DefaultOrderlySuspendDuration = time.Second
 	DefaultReconsumeTimes         = 5
+	DefaultMaxDelay               = time.Hour * 2
 
+	DefaultCodec             = "GMD_CODEC"
 	DefaultDelayTagPrefix    = "GMD-DELAY-"
 	DefaultDelayMessageTime  = "GMD_DELAY_MESSAGE_TIME"
 	DefaultDelayPublishCount = "GMD_DELAY_PUBLISH_COUNT"
+	DefaultDeliverTime       = "GMD_DELIVER_TIME"
+	DefaultExpiresAt         = "GMD_EXPIRES_AT"
+	DefaultFailedTimes       = "GMD_FAILED_TIMES"
 	DefaultTopicMessageId    = "GMD_TOPIC_MESSAGE_ID"
 )
 
@@ -44,9 +44,16 @@ func NewProducer() *Producer {
 // Interface methods
 // /////////////////////////////////////////////////////////////
 
+func (o *Producer) MaxDelay() time.Duration                 { return DefaultMaxDelay }
 func (o *Producer) Processor() process.Processor            { return o.processor }
 func (o *Producer) Publish(p *base.Payload) (string, error) { return o.doPublish(p) }
 
+// PublishBatch
+// send payloads of the same topic in one request.
+func (o *Producer) PublishBatch(ps []*base.Payload) ([]string, error) {
+	return o.doPublishBatch(ps)
+}
+
 // /////////////////////////////////////////////////////////////
 // Access methods
 // /////////////////////////////////////////////////////////////
@@ -55,10 +62,78 @@ func (o *Producer) doPublish(p *base.Payload) (string, error) {
 	if !o.processor.Healthy() {
 		return "", fmt.Errorf("producer is starting or restarting")
 	}
-	return o.doSend(p.GetContext(), (&primitive.Message{
+
+	m := (&primitive.Message{
 		Topic: Agent.GenTopicName(p.TopicName),
-		Body:  []byte(p.MessageBody),
-	}).WithTag(p.TopicTag))
+		Body:  []byte(p.Body()),
+	}).WithTag(p.TopicTag)
+
+	// Codec
+	// of compressed body, consumer decompresses with it.
+	if c := p.Codec(); c != conf.CodecNone {
+		m.WithProperty(DefaultCodec, string(c))
+	}
+
+	// Select queue
+	// by hash of key, messages with the same key are sent to the
+	// same queue in publish order.
+	if p.Keyword != "" {
+		m.WithKeys([]string{p.Keyword})
+		m.WithShardingKey(p.Keyword)
+	}
+
+	// Deliver at scheduled time,
+	// consumer republishes with delay level until time reached.
+	if seconds := int(p.DeliverAt - time.Now().Unix()); p.DeliverAt > 0 && seconds > 0 {
+		m.WithProperty(DefaultDeliverTime, fmt.Sprintf("%d", p.DeliverAt*1000))
+		m.WithDelayTimeLevel(delaySecondsToLevel(seconds))
+	}
+
+	// Expire time
+	// checked by consumer before delivery.
+	if p.ExpiresAt > 0 {
+		m.WithProperty(DefaultExpiresAt, fmt.Sprintf("%d", p.ExpiresAt))
+	}
+
+	return o.doSend(p.GetContext(), m)
+}
+
+func (o *Producer) doPublishBatch(ps []*base.Payload) ([]string, error) {
+	if !o.processor.Healthy() {
+		return nil, fmt.Errorf("producer is starting or restarting")
+	}
+
+	ms, ids := o.doBatchMessages(ps)
+	if _, err := o.client.SendSync(ps[0].GetContext(), ms...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Build messages
+// of batch, delay level and sharding key not supported in batch.
+// Unique id is generated for each message and returned in order,
+// message id in send result is not split by message.
+func (o *Producer) doBatchMessages(ps []*base.Payload) (ms []*primitive.Message, ids []string) {
+	for _, p := range ps {
+		id := primitive.CreateUniqID()
+		m := (&primitive.Message{
+			Topic: Agent.GenTopicName(p.TopicName),
+			Body:  []byte(p.Body()),
+		}).WithTag(p.TopicTag)
+		m.WithProperty(primitive.PropertyUniqueClientMessageIdKeyIndex, id)
+
+		if c := p.Codec(); c != conf.CodecNone {
+			m.WithProperty(DefaultCodec, string(c))
+		}
+
+		if p.ExpiresAt > 0 {
+			m.WithProperty(DefaultExpiresAt, fmt.Sprintf("%d", p.ExpiresAt))
+		}
+		ids = append(ids, id)
+		ms = append(ms, m)
+	}
+	return
 }
 
 func (o *Producer) doSend(ctx context.Context, m *primitive.Message) (string, error) {
@@ -130,6 +205,7 @@ func (o *Producer) onCallClientBuild(_ context.Context) (ignored bool) {
 			producer.WithSendMsgTimeout(time.Duration(conf.Config.Producer.PublishTimeout) * time.Second),
 			producer.WithInstanceName(node),
 			producer.WithGroupName(DefaultProducerGroupName),
+			producer.WithQueueSelector(producer.NewHashQueueSelector()),
 		}
 	)
 
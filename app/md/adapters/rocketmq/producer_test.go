@@ -0,0 +1,70 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package rocketmq
+
+import (
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"strings"
+	"testing"
+)
+
+func TestProducerBatchMessages(t *testing.T) {
+	ps := make([]*base.Payload, 0)
+	for _, body := range []string{"first", "second", "third"} {
+		p := base.Pool.AcquirePayload()
+		p.TopicName = "Topic"
+		p.TopicTag = "Tag"
+		p.MessageBody = body
+		ps = append(ps, p)
+	}
+
+	ms, ids := (&Producer{}).doBatchMessages(ps)
+	if len(ms) != len(ps) || len(ids) != len(ps) {
+		t.Fatalf("message and id of each payload expected: messages=%d, ids=%d", len(ms), len(ids))
+	}
+
+	seen := make(map[string]bool)
+	for i, m := range ms {
+		id := m.GetProperty(primitive.PropertyUniqueClientMessageIdKeyIndex)
+		if id == "" || id != ids[i] {
+			t.Fatalf("index=%d: unique id of message=%s, returned=%s", i, id, ids[i])
+		}
+		if seen[id] {
+			t.Fatalf("index=%d: unique id duplicated: %s", i, id)
+		}
+		if string(m.Body) != ps[i].MessageBody {
+			t.Fatalf("index=%d: body in order expected: %s", i, m.Body)
+		}
+		seen[id] = true
+	}
+}
+
+func TestProducerCodecProperty(t *testing.T) {
+	threshold, codec := conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec
+	defer func() { conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec = threshold, codec }()
+	conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec = 16, conf.CodecZstd
+
+	ps := make([]*base.Payload, 0)
+	for _, body := range []string{"short", strings.Repeat("compressed", 32)} {
+		p := base.Pool.AcquirePayload()
+		p.TopicName = "Topic"
+		p.MessageBody = body
+		ps = append(ps, p)
+	}
+
+	// Codec recorded
+	// in property only if body compressed.
+	ms, _ := (&Producer{}).doBatchMessages(ps)
+	if s := ms[0].GetProperty(DefaultCodec); s != "" {
+		t.Fatalf("no codec of short body expected: %s", s)
+	}
+	if s := ms[1].GetProperty(DefaultCodec); s != string(conf.CodecZstd) {
+		t.Fatalf("codec of compressed body expected: %s", s)
+	}
+	if s, err := base.Decompress(conf.Codec(ms[1].GetProperty(DefaultCodec)), string(ms[1].Body)); err != nil || s != ps[1].MessageBody {
+		t.Fatalf("original body expected: error=%v", err)
+	}
+}
@@ -10,8 +10,11 @@ import (
 	"github.com/apache/rocketmq-client-go/v2/consumer"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
 	"github.com/fuyibing/log/v8"
+	"math"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -23,7 +26,7 @@ type (
 		delayer             bool
 		delayerMilliSeconds int64
 		delayerTag          string
-		dispatcher          func(task *base.Task, message *base.Message) (retry bool)
+		dispatcher          func(task *base.Task, message *base.Message) (retry bool, delay time.Duration)
 		name                string
 		selector            consumer.MessageSelector
 		task                *base.Task
@@ -54,6 +57,12 @@ func (o *Received) Consume(ctx context.Context, ms ...*primitive.MessageExt) (co
 	return o.doCheck(ctx, ms[0])
 }
 
+// IsBusy
+// return delivering messages reached concurrency or not.
+func (o *Received) IsBusy() bool {
+	return atomic.LoadInt32(&o.consuming) >= o.task.CurrentConcurrency()
+}
+
 // IsIdle
 // return all delivering messages are completed or not.
 func (o *Received) IsIdle() bool {
@@ -65,16 +74,11 @@ func (o *Received) IsIdle() bool {
 // /////////////////////////////////////////////////////////////
 
 func (o *Received) doCheck(ctx context.Context, m *primitive.MessageExt) (consumer.ConsumeResult, error) {
-	// Consume immediately
-	// if subscription task not configure delay time.
-	if !o.delayer {
-		return o.doConsume(m)
-	}
-
 	// Time diff.
 	var (
 		bornTime    = m.BornTimestamp
 		currTime    = time.Now().UnixMilli()
+		deliverTime int64
 		diffSeconds int
 	)
 
@@ -86,21 +90,35 @@ func (o *Received) doCheck(ctx context.Context, m *primitive.MessageExt) (consum
 		}
 	}
 
+	// Deliver time
+	// if subscription task configured delay time.
+	if o.delayer {
+		deliverTime = bornTime + o.delayerMilliSeconds
+	}
+
+	// Read scheduled deliver time
+	// from property, the later one is used.
+	if s := m.GetProperty(DefaultDeliverTime); s != "" {
+		if n, ne := strconv.ParseInt(s, 0, 64); ne == nil && n > deliverTime {
+			deliverTime = n
+		}
+	}
+
 	// Consume immediately
-	// if the current time minus the message time is greater than delay time.
-	if diffSeconds = int((o.delayerMilliSeconds + bornTime - currTime) / 1000); diffSeconds <= 0 {
-		return o.doConsume(m)
+	// if deliver time reached.
+	if diffSeconds = int((deliverTime - currTime) / 1000); diffSeconds <= 0 {
+		return o.doConsume(ctx, m)
 	}
 
 	// Publish delay message.
-	level := o.delaySecondsToLevel(diffSeconds)
-	return o.doPublish(ctx, m, bornTime, diffSeconds, level)
+	level := delaySecondsToLevel(diffSeconds)
+	return o.doPublish(ctx, m, bornTime, 0, diffSeconds, level)
 }
 
-func (o *Received) doConsume(m *primitive.MessageExt) (consumer.ConsumeResult, error) {
+func (o *Received) doConsume(ctx context.Context, m *primitive.MessageExt) (consumer.ConsumeResult, error) {
 	// Increment consuming count, Call suspend
 	// if concurrency is greater than configuration.
-	if concurrency := atomic.AddInt32(&o.consuming, 1); concurrency >= o.task.Concurrency {
+	if concurrency := atomic.AddInt32(&o.consuming, 1); concurrency >= o.task.CurrentConcurrency() {
 		if o.callbackSuspend != nil {
 			o.callbackSuspend()
 		}
@@ -109,7 +127,7 @@ func (o *Received) doConsume(m *primitive.MessageExt) (consumer.ConsumeResult, e
 	// Decrement consuming count, call resume
 	// if concurrency is greater than configuration.
 	defer func() {
-		if concurrency := atomic.AddInt32(&o.consuming, -1); concurrency < o.task.Concurrency {
+		if concurrency := atomic.AddInt32(&o.consuming, -1); concurrency < o.task.CurrentConcurrency() {
 			if o.callbackResume != nil {
 				o.callbackResume()
 			}
@@ -119,13 +137,12 @@ func (o *Received) doConsume(m *primitive.MessageExt) (consumer.ConsumeResult, e
 	// Prepare
 	// for message consume process.
 	var (
-		ctx            = log.NewContext()
-		msg            *base.Message
+		c              = log.NewContext()
 		topicMessageId = m.MsgId
 		bornTime       = m.BornTimestamp
 		currTime       = time.Now().UnixMilli()
 		diffTime       int64
-		consumeTimes   = int(m.ReconsumeTimes + 1)
+		consumeTimes   = failedTimes(m) + 1
 		information    = ""
 	)
 
@@ -159,33 +176,114 @@ func (o *Received) doConsume(m *primitive.MessageExt) (consumer.ConsumeResult, e
 	}
 
 	// Info: logger trace.
-	log.Infofc(ctx, "%s: %s", o.name, information)
+	log.Infofc(c, "%s: %s", o.name, information)
+
+	for {
+		// Call dispatcher, reconsume with delay level
+		// if failed.
+		retry, delay := o.dispatcher(o.task, o.doMessage(c, m, bornTime, consumeTimes, topicMessageId))
+		if !retry {
+			break
+		}
+		if delay <= 0 {
+			log.Infofc(c, "%s: consume later", o.name)
+			return o.doRetry(ctx, 0), nil
+		}
+
+		// Deferred message
+		// is not failure, republished with deliver time and original
+		// acknowledged, so reconsume times of broker not incremented.
+		if !o.task.Ordered {
+			log.Infofc(c, "%s: consume deferred, delay=%v", o.name, delay)
+			seconds := int(math.Ceil(delay.Seconds()))
+			return o.doPublish(ctx, m, bornTime, time.Now().Add(delay).UnixMilli(), seconds, delaySecondsToLevel(seconds))
+		}
 
-	// Acquire message instance.
-	msg = base.Pool.AcquireMessage().SetContext(ctx)
+		// Wait deferred duration
+		// and dispatch again for ordered task, queue is held so later
+		// messages of the queue are not delivered before this message.
+		log.Infofc(c, "%s: consume deferred, wait=%v", o.name, delay)
+		if !o.doWait(ctx, delay) {
+			return o.doRetry(ctx, delay), nil
+		}
+	}
+
+	// Return succeed response.
+	log.Infofc(c, "%s: consume succeed", o.name)
+	return consumer.ConsumeSuccess, nil
+}
+
+// Build message
+// of dispatcher, it is released by dispatcher.
+func (o *Received) doMessage(c context.Context, m *primitive.MessageExt, bornTime int64, consumeTimes int, topicMessageId string) *base.Message {
+	msg := base.Pool.AcquireMessage().SetContext(c)
 	msg.Dequeue = consumeTimes
 	msg.MessageId = m.MsgId
 	msg.MessageTime = bornTime
+	msg.Codec = conf.Codec(m.GetProperty(DefaultCodec))
 	msg.MessageBody = string(m.Body)
 	msg.PayloadMessageId = topicMessageId
 
-	// Keyword trace.
-	if k := m.GetKeys(); k != "" {
+	// Keyword trace,
+	// separator appended by broker is trimmed.
+	if k := strings.TrimSpace(m.GetKeys()); k != "" {
 		msg.Keyword = k
 	}
 
-	// Call dispatcher.
-	if retry := o.dispatcher(o.task, msg); retry {
-		log.Infofc(ctx, "%s: consume later", o.name)
-		return consumer.ConsumeRetryLater, nil
+	// Parse expire time.
+	if s := m.GetProperty(DefaultExpiresAt); s != "" {
+		if n, ne := strconv.ParseInt(s, 0, 64); ne == nil && n > 0 {
+			msg.ExpiresAt = n
+		}
 	}
+	return msg
+}
 
-	// Return succeed response.
-	log.Infofc(ctx, "%s: consume succeed", o.name)
-	return consumer.ConsumeSuccess, nil
+// Return retry result.
+//
+// Queue is suspended for ordered task, so later messages of the queue
+// are not delivered before this message.
+func (o *Received) doRetry(ctx context.Context, delay time.Duration) consumer.ConsumeResult {
+	if o.task.Ordered {
+		if delay <= 0 {
+			delay = DefaultOrderlySuspendDuration
+		}
+		if ctx != nil {
+			if cc, ok := primitive.GetOrderlyCtx(ctx); ok {
+				cc.SuspendCurrentQueueTimeMillis = int(delay.Milliseconds())
+			}
+		}
+		return consumer.SuspendCurrentQueueAMoment
+	}
+
+	if delay > 0 && ctx != nil {
+		if cc, ok := primitive.GetConcurrentlyCtx(ctx); ok {
+			cc.DelayLevelWhenNextConsume = delaySecondsToLevel(int(math.Ceil(delay.Seconds())))
+		}
+	}
+	return consumer.ConsumeRetryLater
 }
 
-func (o *Received) doPublish(ctx context.Context, m *primitive.MessageExt, bt int64, seconds, level int) (consumer.ConsumeResult, error) {
+// Wait
+// deferred duration, return false if context cancelled.
+func (o *Received) doWait(ctx context.Context, delay time.Duration) bool {
+	if ctx == nil {
+		time.Sleep(delay)
+		return true
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (o *Received) doPublish(ctx context.Context, m *primitive.MessageExt, bt, deliverTime int64, seconds, level int) (consumer.ConsumeResult, error) {
 	var (
 		bornTime       = fmt.Sprintf("%v", bt)
 		err            error
@@ -208,7 +306,7 @@ func (o *Received) doPublish(ctx context.Context, m *primitive.MessageExt, bt in
 	}
 
 	information += fmt.Sprintf("message publish delay, Message {Dequeue=%d, MessageId=%s, MessageTag=%s}",
-		m.ReconsumeTimes+1, m.MsgId, m.GetTags(),
+		failedTimes(m)+1, m.MsgId, m.GetTags(),
 	) + fmt.Sprintf(", Topic {Name=%s, MessageId=%s}",
 		m.Topic, topicMessageId,
 	) + fmt.Sprintf(", Target {Topic=%s, MessageTag=%s}",
@@ -218,30 +316,70 @@ func (o *Received) doPublish(ctx context.Context, m *primitive.MessageExt, bt in
 	)
 
 	// Generate message param.
+	x := o.delayMessage(m, bornTime, publishCount, topicMessageId, deliverTime, level)
+
+	// Delay message publish failed.
+	if messageId, err = defaultProducer.doSend(ctx, x); err != nil {
+		log.Errorf("%s: %s, error=%v", o.name, information, err)
+		return consumer.ConsumeRetryLater, nil
+	}
+
+	// Delay message published.
+	log.Infof("%s: %s, Target {MessageId=%s}", o.name, information, messageId)
+	return consumer.ConsumeSuccess, nil
+}
+
+// Build delay message
+// of received message, deliver time of property is replaced if given
+// and failed times is kept.
+func (o *Received) delayMessage(m *primitive.MessageExt, bornTime, publishCount, topicMessageId string, deliverTime int64, level int) *primitive.Message {
 	x := &primitive.Message{Topic: o.topic, Body: m.Body}
 	x.WithProperty(DefaultDelayPublishCount, publishCount)
 	x.WithProperty(DefaultDelayMessageTime, bornTime)
 	x.WithProperty(DefaultTopicMessageId, topicMessageId)
+	if n := failedTimes(m); n > 0 {
+		x.WithProperty(DefaultFailedTimes, fmt.Sprintf("%d", n))
+	}
+	if s := m.GetProperty(DefaultCodec); s != "" {
+		x.WithProperty(DefaultCodec, s)
+	}
+	if deliverTime > 0 {
+		x.WithProperty(DefaultDeliverTime, fmt.Sprintf("%d", deliverTime))
+	} else if s := m.GetProperty(DefaultDeliverTime); s != "" {
+		x.WithProperty(DefaultDeliverTime, s)
+	}
+	if s := m.GetProperty(DefaultExpiresAt); s != "" {
+		x.WithProperty(DefaultExpiresAt, s)
+	}
 	x.WithTag(o.delayerTag)
 	x.WithDelayTimeLevel(level)
 
 	// Copy key.
-	if k := m.GetKeys(); k != "" {
+	if k := strings.TrimSpace(m.GetKeys()); k != "" {
 		x.WithKeys([]string{k})
+		x.WithShardingKey(k)
 	}
+	return x
+}
 
-	// Delay message publish failed.
-	if messageId, err = defaultProducer.doSend(ctx, x); err != nil {
-		log.Errorf("%s: %s, error=%v", o.name, information, err)
-		return consumer.ConsumeRetryLater, nil
+// Return
+// failed times of message, failures of republished message are kept
+// in property and added to reconsume times of broker.
+func failedTimes(m *primitive.MessageExt) int {
+	n := int(m.ReconsumeTimes)
+	if s := m.GetProperty(DefaultFailedTimes); s != "" {
+		if v, ve := strconv.Atoi(s); ve == nil && v > 0 {
+			n += v
+		}
 	}
-
-	// Delay message published.
-	log.Infof("%s: %s, Target {MessageId=%s}", o.name, information, messageId)
-	return consumer.ConsumeSuccess, nil
+	return n
 }
 
-func (o *Received) delaySecondsToLevel(s int) (l int) {
+// /////////////////////////////////////////////////////////////
+// Delay level
+// /////////////////////////////////////////////////////////////
+
+func delaySecondsToLevel(s int) (l int) {
 	if s < 5 {
 		l = 1
 	} else if s >= 5 && s < 10 {
@@ -260,13 +398,13 @@ func (o *Received) delaySecondsToLevel(s int) (l int) {
 		l = 8
 	} else if s >= 300 && s < 360 {
 		l = 9
-	} else if s >= 360 && s < 360 {
+	} else if s >= 360 && s < 420 {
 		l = 10
-	} else if s >= 420 && s < 360 {
+	} else if s >= 420 && s < 480 {
 		l = 11
-	} else if s >= 480 && s < 360 {
+	} else if s >= 480 && s < 540 {
 		l = 12
-	} else if s >= 540 && s < 360 {
+	} else if s >= 540 && s < 600 {
 		l = 13
 	} else if s >= 600 && s < 1200 {
 		l = 14
@@ -0,0 +1,100 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package rocketmq
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/fuyibing/gmd/app/md/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDelaySecondsToLevel(t *testing.T) {
+	// Delay levels of broker
+	// 1s 5s 10s 30s 1m 2m 3m 4m 5m 6m 7m 8m 9m 10m 20m 30m 1h 2h.
+	for s, l := range map[int]int{
+		0: 1, 4: 1,
+		5: 2, 10: 3, 30: 4, 59: 4,
+		60: 5, 120: 6, 180: 7, 240: 8, 300: 9,
+		360: 10, 419: 10,
+		420: 11, 479: 11,
+		480: 12, 539: 12,
+		540: 13, 599: 13,
+		600: 14, 1200: 15, 1800: 16, 3600: 17,
+		7200: 18, 86400: 18,
+	} {
+		if n := delaySecondsToLevel(s); n != l {
+			t.Fatalf("seconds=%d: expected level=%d, got=%d", s, l, n)
+		}
+	}
+}
+
+func TestReceivedDeferredOrdered(t *testing.T) {
+	var (
+		deferrals = 0
+		task      = &base.Task{Id: 1, Ordered: true, Concurrency: 1, MaxRetry: 3}
+		o         = &Received{name: "received", task: task}
+	)
+
+	// Dispatch again
+	// after deferred duration, dequeue count not incremented.
+	o.dispatcher = func(_ *base.Task, m *base.Message) (bool, time.Duration) {
+		defer m.Release()
+		if m.Dequeue != 1 {
+			t.Fatalf("dequeue not incremented by deferral expected: %d", m.Dequeue)
+		}
+		if deferrals < DefaultReconsumeTimes*2 {
+			deferrals++
+			return true, time.Millisecond
+		}
+		return false, 0
+	}
+
+	res, _ := o.doConsume(context.Background(), &primitive.MessageExt{Message: primitive.Message{Topic: "Topic", Body: []byte("{}")}})
+	if res != consumer.ConsumeSuccess || deferrals != DefaultReconsumeTimes*2 {
+		t.Fatalf("consumed after deferrals expected: result=%v, deferrals=%d", res, deferrals)
+	}
+}
+
+func TestReceivedDeferredMessage(t *testing.T) {
+	var (
+		o = &Received{name: "received", task: &base.Task{Id: 1}, topic: "Topic", delayerTag: "GMD-DELAY-1-Tag"}
+		m = &primitive.MessageExt{Message: primitive.Message{Topic: "Topic", Body: []byte("{}")}, ReconsumeTimes: 2}
+	)
+
+	m.WithProperty(DefaultCodec, "gzip")
+	m.WithKeys([]string{"order-1"})
+
+	// Republished
+	// message of each deferral starts with zero reconsume times of
+	// broker, failed times kept in property.
+	for i := 0; i < DefaultReconsumeTimes*2; i++ {
+		deliverTime := time.Now().Add(time.Minute).UnixMilli()
+		x := o.delayMessage(m, "1700000000000", "1", "TOPIC-MESSAGE-ID", deliverTime, 5)
+
+		if s := x.GetProperty(DefaultDeliverTime); s != fmt.Sprintf("%d", deliverTime) {
+			t.Fatalf("deliver time of deferral expected: %s", s)
+		}
+		if x.GetProperty(DefaultCodec) != "gzip" || strings.TrimSpace(x.GetKeys()) != "order-1" || x.GetTags() != o.delayerTag {
+			t.Fatalf("properties copied expected: %s", x.String())
+		}
+
+		m = &primitive.MessageExt{Message: primitive.Message{Topic: x.Topic, Body: x.Body}}
+		m.WithProperties(x.GetProperties())
+		if n := failedTimes(m) + 1; n != 3 {
+			t.Fatalf("deferral %d: dequeue of failures only expected: %d", i, n)
+		}
+	}
+
+	// Failure of republished message
+	// counted with failed times kept.
+	m.ReconsumeTimes = 1
+	if n := failedTimes(m) + 1; n != 4 {
+		t.Fatalf("dequeue of failures expected: %d", n)
+	}
+}
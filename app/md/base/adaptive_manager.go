@@ -0,0 +1,140 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package base
+
+import (
+	"github.com/fuyibing/gmd/app/md/conf"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// Adaptive
+	// instance of adaptive concurrency manager.
+	Adaptive AdaptiveManager
+)
+
+type (
+	// AdaptiveManager
+	// interface of adaptive concurrency manager.
+	//
+	// Effective concurrency of task is tuned by AIMD algorithm, it
+	// increases additively if delivered succeed in time and decreases
+	// multiplicatively if delivered failed or latency exceeds the
+	// tolerance of baseline.
+	AdaptiveManager interface {
+		// Limit
+		// return effective concurrency of task.
+		//
+		// Return Task.Concurrency if adaptive mode not enabled.
+		Limit(t *Task) int32
+
+		// Observe
+		// record latency and result of delivering.
+		Observe(t *Task, latency time.Duration, err error)
+	}
+
+	adaptive struct {
+		mu     *sync.RWMutex
+		states map[int]*adaptiveState
+	}
+
+	adaptiveState struct {
+		mu *sync.Mutex
+
+		baseline  float64
+		decreased time.Time
+		limit     float64
+	}
+)
+
+func (o *adaptive) Limit(t *Task) int32 {
+	if !t.Adaptive {
+		return t.Concurrency
+	}
+
+	x := o.state(t)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return int32(x.clamp(t))
+}
+
+func (o *adaptive) Observe(t *Task, latency time.Duration, err error) {
+	if !t.Adaptive {
+		return
+	}
+
+	x := o.state(t)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	sec := latency.Seconds()
+
+	// Decrease multiplicatively
+	// if delivered failed or latency exceeds tolerance, limited
+	// once per second to react on one batch only.
+	if err != nil || (x.baseline > 0 && sec > x.baseline*conf.Config.Consumer.AdaptiveTolerance) {
+		if time.Since(x.decreased) >= time.Second {
+			x.decreased = time.Now()
+			x.limit *= conf.Config.Consumer.AdaptiveBackoff
+			x.clamp(t)
+		}
+		return
+	}
+
+	// Update baseline
+	// with minimum latency, drift slowly to adapt changed handler.
+	if x.baseline == 0 || sec < x.baseline {
+		x.baseline = sec
+	} else {
+		x.baseline = x.baseline*0.99 + sec*0.01
+	}
+
+	// Increase additively.
+	x.limit += 1 / math.Max(x.limit, 1)
+	x.clamp(t)
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *adaptive) init() *adaptive {
+	o.mu = &sync.RWMutex{}
+	o.states = make(map[int]*adaptiveState)
+	return o
+}
+
+func (o *adaptive) state(t *Task) *adaptiveState {
+	o.mu.RLock()
+	x, ok := o.states[t.Id]
+	o.mu.RUnlock()
+
+	if ok {
+		return x
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok = o.states[t.Id]; !ok {
+		x = &adaptiveState{mu: &sync.Mutex{}, limit: float64(t.ConcurrencyMin)}
+		o.states[t.Id] = x
+	}
+	return x
+}
+
+// Clamp
+// limit between minimum and maximum of task which may be changed.
+func (o *adaptiveState) clamp(t *Task) float64 {
+	if max := float64(t.Concurrency); o.limit > max {
+		o.limit = max
+	}
+	if min := float64(t.ConcurrencyMin); o.limit < min {
+		o.limit = min
+	}
+	return o.limit
+}
@@ -0,0 +1,240 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-20
+
+package base
+
+import (
+	"github.com/fuyibing/gmd/app/md/conf"
+	"sync"
+	"time"
+)
+
+var (
+	// Breaker
+	// instance of circuit breaker manager.
+	Breaker BreakerManager
+)
+
+type (
+	// BreakerManager
+	// interface of circuit breaker manager.
+	//
+	// Circuit breaker of subscriber address, shared by all tasks in
+	// memory and kept when memory reloaded.
+	BreakerManager interface {
+		// Allow
+		// return message can be delivered to address or not.
+		//
+		// Return true if closed, or half-open with probe quota
+		// left. Method Done must be called if true returned.
+		Allow(addr string) bool
+
+		// Done
+		// record delivered result of address.
+		Done(addr string, err error)
+
+		// IsOpen
+		// return consumption should be paused or not.
+		IsOpen(addr string) bool
+
+		// Remaining
+		// return duration until half-open.
+		Remaining(addr string) time.Duration
+
+		// State
+		// return current state of address.
+		State(addr string) BreakerState
+	}
+
+	// BreakerState
+	// state of circuit breaker.
+	BreakerState int
+
+	breaker struct {
+		mu     *sync.RWMutex
+		states map[string]*breakerState
+	}
+
+	breakerState struct {
+		mu *sync.Mutex
+
+		fails, probing, probed int
+		requests, errors       int
+
+		opened time.Time
+		state  BreakerState
+		window time.Time
+	}
+)
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (o *breaker) Allow(addr string) bool {
+	if !*conf.Config.Breaker.Enabled {
+		return true
+	}
+
+	x := o.state(addr)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	switch x.check() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if x.probing >= conf.Config.Breaker.Probes {
+			return false
+		}
+		x.probing++
+	}
+	return true
+}
+
+func (o *breaker) Done(addr string, err error) {
+	if !*conf.Config.Breaker.Enabled {
+		return
+	}
+
+	x := o.state(addr)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// Half-open.
+	if x.state == BreakerHalfOpen {
+		if x.probing > 0 {
+			x.probing--
+		}
+
+		// Reopen
+		// if probe failed.
+		if err != nil {
+			x.open()
+			return
+		}
+
+		// Close
+		// if probes succeed.
+		if x.probed++; x.probed >= conf.Config.Breaker.Probes {
+			x.reset(BreakerClosed)
+		}
+		return
+	}
+
+	// Ignore result
+	// of messages allowed before opened.
+	if x.state == BreakerOpen {
+		return
+	}
+
+	// Renew window.
+	if now := time.Now(); now.Sub(x.window) >= time.Duration(conf.Config.Breaker.WindowSeconds)*time.Second {
+		x.errors, x.requests, x.window = 0, 0, now
+	}
+
+	x.requests++
+
+	if err == nil {
+		x.fails = 0
+		return
+	}
+
+	x.errors++
+	x.fails++
+
+	// Open
+	// if consecutive failures or error rate reached.
+	if x.fails >= conf.Config.Breaker.Failures ||
+		(x.requests >= conf.Config.Breaker.MinRequests && float64(x.errors)/float64(x.requests) >= conf.Config.Breaker.ErrorRate) {
+		x.open()
+	}
+}
+
+func (o *breaker) IsOpen(addr string) bool {
+	if !*conf.Config.Breaker.Enabled {
+		return false
+	}
+
+	x := o.state(addr)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	switch x.check() {
+	case BreakerOpen:
+		return true
+	case BreakerHalfOpen:
+		return x.probing >= conf.Config.Breaker.Probes
+	}
+	return false
+}
+
+func (o *breaker) Remaining(addr string) time.Duration {
+	x := o.state(addr)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.check() == BreakerOpen {
+		return time.Until(x.opened.Add(time.Duration(conf.Config.Breaker.OpenSeconds) * time.Second))
+	}
+	return 0
+}
+
+func (o *breaker) State(addr string) BreakerState {
+	x := o.state(addr)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.check()
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *breaker) init() *breaker {
+	o.mu = &sync.RWMutex{}
+	o.states = make(map[string]*breakerState)
+	return o
+}
+
+func (o *breaker) state(addr string) *breakerState {
+	o.mu.RLock()
+	x, ok := o.states[addr]
+	o.mu.RUnlock()
+
+	if ok {
+		return x
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok = o.states[addr]; !ok {
+		x = &breakerState{mu: &sync.Mutex{}, window: time.Now()}
+		o.states[addr] = x
+	}
+	return x
+}
+
+// Check
+// state and switch to half-open if open duration elapsed.
+func (o *breakerState) check() BreakerState {
+	if o.state == BreakerOpen && time.Since(o.opened) >= time.Duration(conf.Config.Breaker.OpenSeconds)*time.Second {
+		o.reset(BreakerHalfOpen)
+	}
+	return o.state
+}
+
+func (o *breakerState) open() {
+	o.reset(BreakerOpen)
+	o.opened = time.Now()
+}
+
+func (o *breakerState) reset(state BreakerState) {
+	o.errors, o.fails, o.requests = 0, 0, 0
+	o.probed, o.probing = 0, 0
+	o.state = state
+	o.window = time.Now()
+}
@@ -0,0 +1,94 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"fmt"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpenByFailures(t *testing.T) {
+	var (
+		addr = "http://10.0.0.1/path"
+		b    = (&breaker{}).init()
+		err  = fmt.Errorf("failed")
+	)
+
+	for i := 0; i < conf.Config.Breaker.Failures; i++ {
+		if !b.Allow(addr) {
+			t.Fatalf("allow expected before opened: index=%d", i)
+		}
+		b.Done(addr, err)
+	}
+
+	if b.State(addr) != BreakerOpen || !b.IsOpen(addr) || b.Allow(addr) {
+		t.Fatalf("breaker should be open: state=%d", b.State(addr))
+	}
+	if d := b.Remaining(addr); d <= 0 {
+		t.Fatalf("remaining duration expected: %v", d)
+	}
+}
+
+func TestBreakerSucceedResetFailures(t *testing.T) {
+	var (
+		addr = "http://10.0.0.1/path"
+		b    = (&breaker{}).init()
+	)
+
+	for i := 0; i < conf.Config.Breaker.Failures*2; i++ {
+		b.Allow(addr)
+		if i%2 == 0 {
+			b.Done(addr, fmt.Errorf("failed"))
+		} else {
+			b.Done(addr, nil)
+		}
+	}
+
+	if b.State(addr) != BreakerClosed {
+		t.Fatalf("breaker should be closed: state=%d", b.State(addr))
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	var (
+		addr = "http://10.0.0.1/path"
+		b    = (&breaker{}).init()
+	)
+
+	// Open
+	// then expire open duration.
+	open := func() {
+		b.state(addr).open()
+		b.state(addr).opened = time.Now().Add(-time.Duration(conf.Config.Breaker.OpenSeconds+1) * time.Second)
+	}
+
+	// Reopen
+	// if probe failed.
+	open()
+	if !b.Allow(addr) || b.State(addr) != BreakerHalfOpen {
+		t.Fatalf("probe expected in half-open: state=%d", b.State(addr))
+	}
+	if conf.Config.Breaker.Probes == 1 && b.Allow(addr) {
+		t.Fatalf("probe quota exceeded")
+	}
+	b.Done(addr, fmt.Errorf("failed"))
+	if b.State(addr) != BreakerOpen {
+		t.Fatalf("breaker should be reopened: state=%d", b.State(addr))
+	}
+
+	// Close
+	// if probes succeed.
+	open()
+	for i := 0; i < conf.Config.Breaker.Probes; i++ {
+		if !b.Allow(addr) {
+			t.Fatalf("probe expected: index=%d", i)
+		}
+		b.Done(addr, nil)
+	}
+	if b.State(addr) != BreakerClosed {
+		t.Fatalf("breaker should be closed: state=%d", b.State(addr))
+	}
+}
@@ -0,0 +1,143 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-02
+
+package base
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"sync"
+)
+
+const (
+	// CodecMaxSize
+	// max bytes of decompressed message body.
+	CodecMaxSize = 16 << 20
+)
+
+var (
+	codecOnce    = new(sync.Once)
+	zstdDecoder  *zstd.Decoder
+	zstdEncoder  *zstd.Encoder
+	codecInitErr error
+)
+
+// Compress
+// return message body compressed with specified codec and the codec
+// applied, adapter records the codec in message property.
+//
+// Original body returned with none codec if codec is none or
+// compressed body is not smaller.
+func Compress(codec conf.Codec, body string) (string, conf.Codec, error) {
+	var (
+		buf []byte
+		err error
+	)
+
+	switch codec {
+	case conf.CodecGzip:
+		buf, err = gzipEncode([]byte(body))
+	case conf.CodecZstd:
+		buf, err = zstdEncode([]byte(body))
+	case conf.CodecNone, "":
+		return body, conf.CodecNone, nil
+	default:
+		err = fmt.Errorf("unknown codec: %s", codec)
+	}
+
+	if err != nil {
+		return body, conf.CodecNone, err
+	}
+
+	if len(buf) < len(body) {
+		return string(buf), codec, nil
+	}
+	return body, conf.CodecNone, nil
+}
+
+// Decompress
+// return original message body with codec of message property.
+//
+// Body returned directly if codec is none or not given, so messages
+// published before compression enabled are accepted.
+func Decompress(codec conf.Codec, body string) (string, error) {
+	var (
+		buf []byte
+		err error
+	)
+
+	switch codec {
+	case conf.CodecGzip:
+		buf, err = gzipDecode([]byte(body))
+	case conf.CodecZstd:
+		buf, err = zstdDecode([]byte(body))
+	case conf.CodecNone, "":
+		return body, nil
+	default:
+		err = fmt.Errorf("unknown codec: %s", codec)
+	}
+
+	if err != nil {
+		return body, err
+	}
+	return string(buf), nil
+}
+
+// /////////////////////////////////////////////////////////////
+// Codec methods.
+// /////////////////////////////////////////////////////////////
+
+func gzipDecode(buf []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	if buf, err = io.ReadAll(io.LimitReader(r, CodecMaxSize+1)); err == nil && len(buf) > CodecMaxSize {
+		err = fmt.Errorf("decompressed size exceeded %d bytes", CodecMaxSize)
+	}
+	return buf, err
+}
+
+func gzipEncode(buf []byte) ([]byte, error) {
+	b := &bytes.Buffer{}
+	w := gzip.NewWriter(b)
+	if _, err := w.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func zstdDecode(buf []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdDecoder.DecodeAll(buf, nil)
+}
+
+func zstdEncode(buf []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdEncoder.EncodeAll(buf, nil), nil
+}
+
+// Create
+// shared zstd encoder and decoder, they are safe for concurrent use
+// with EncodeAll and DecodeAll.
+func zstdInit() error {
+	codecOnce.Do(func() {
+		if zstdEncoder, codecInitErr = zstd.NewWriter(nil); codecInitErr == nil {
+			zstdDecoder, codecInitErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(CodecMaxSize))
+		}
+	})
+	return codecInitErr
+}
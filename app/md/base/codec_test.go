@@ -0,0 +1,93 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"github.com/fuyibing/gmd/app/md/conf"
+	"strings"
+	"testing"
+)
+
+func TestCodecCompress(t *testing.T) {
+	body := strings.Repeat(`{"id":1,"name":"compressed"}`, 64)
+
+	for _, codec := range []conf.Codec{conf.CodecGzip, conf.CodecZstd} {
+		s, used, err := Compress(codec, body)
+		if err != nil {
+			t.Fatalf("%s: compress error: %v", codec, err)
+		}
+		if used != codec || len(s) >= len(body) {
+			t.Fatalf("%s: compressed body expected: codec=%s, size=%d", codec, used, len(s))
+		}
+		if strings.HasPrefix(s, "gmd:") {
+			t.Fatalf("%s: body without envelope expected", codec)
+		}
+
+		if r, err := Decompress(used, s); err != nil || r != body {
+			t.Fatalf("%s: original body expected: error=%v", codec, err)
+		}
+	}
+}
+
+func TestCodecNotSmaller(t *testing.T) {
+	for _, codec := range []conf.Codec{conf.CodecGzip, conf.CodecNone, ""} {
+		if s, used, err := Compress(codec, "short"); err != nil || s != "short" || used != conf.CodecNone {
+			t.Fatalf("%s: original body with none codec expected: body=%s, codec=%s, error=%v", codec, s, used, err)
+		}
+	}
+}
+
+func TestCodecUnknown(t *testing.T) {
+	if _, used, err := Compress("lz4", "body"); err == nil || used != conf.CodecNone {
+		t.Fatalf("unknown codec error expected: codec=%s", used)
+	}
+	if _, err := Decompress("lz4", "body"); err == nil {
+		t.Fatalf("unknown codec error expected")
+	}
+}
+
+func TestCodecDecompress(t *testing.T) {
+	// Body returned directly
+	// if codec not recorded, include legacy body starts with prefix.
+	for _, body := range []string{"plain", "gmd:gzip:H4sIAAAAAAAA"} {
+		if s, err := Decompress("", body); err != nil || s != body {
+			t.Fatalf("original body expected: body=%s, error=%v", s, err)
+		}
+	}
+
+	// Return error
+	// if body is not compressed by codec.
+	for _, codec := range []conf.Codec{conf.CodecGzip, conf.CodecZstd} {
+		if _, err := Decompress(codec, "not compressed"); err == nil {
+			t.Fatalf("%s: decompress error expected", codec)
+		}
+	}
+}
+
+func TestCodecMaxSize(t *testing.T) {
+	s, used, err := Compress(conf.CodecGzip, strings.Repeat("a", CodecMaxSize+1))
+	if err != nil || used != conf.CodecGzip {
+		t.Fatalf("compressed body expected: codec=%s, error=%v", used, err)
+	}
+	if _, err = Decompress(used, s); err == nil {
+		t.Fatalf("size exceeded error expected")
+	}
+}
+
+func TestPayloadCodec(t *testing.T) {
+	threshold, codec := conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec
+	defer func() { conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec = threshold, codec }()
+	conf.Config.Producer.CompressThreshold, conf.Config.Producer.CompressCodec = 16, conf.CodecGzip
+
+	p := Pool.AcquirePayload()
+	p.MessageBody = strings.Repeat("payload", 32)
+	defer p.Release()
+
+	if p.Codec() != conf.CodecGzip || p.Body() == p.MessageBody {
+		t.Fatalf("compressed body expected: codec=%s", p.Codec())
+	}
+	if s, err := Decompress(p.Codec(), p.Body()); err != nil || s != p.MessageBody {
+		t.Fatalf("original body expected: error=%v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron
+// parsed cron expression with timezone.
+//
+// Standard 5 fields are accepted: minute, hour, day of month, month
+// and day of week. Each field accept *, number, range (1-5), step
+// (*/10, 1-30/5) and list (1,15,30). Descriptors @yearly, @monthly,
+// @weekly, @daily and @hourly are accepted too.
+//
+//	c, err := base.NewCron("*/5 9-18 * * 1-5", "Asia/Shanghai")
+//	c.Next(time.Now())
+type Cron struct {
+	Expression string
+	Location   *time.Location
+
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// NewCron
+// return parsed cron expression.
+//
+// Timezone is IANA name such as Asia/Shanghai, UTC used if empty.
+func NewCron(expression, timezone string) (*Cron, error) {
+	return (&Cron{Expression: strings.TrimSpace(expression)}).init(timezone)
+}
+
+// Next
+// return next fire time after specified time.
+//
+// Zero time returned if not fired in 5 years, for example
+// 0 0 30 2 *.
+func (o *Cron) Next(t time.Time) time.Time {
+	t = t.In(o.Location).Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+
+	for t.Before(end) {
+		// Go to first day of next month
+		// if month not matched.
+		if o.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, o.Location)
+			continue
+		}
+
+		// Go to next day
+		// if day not matched.
+		if !o.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, o.Location)
+			continue
+		}
+
+		// Go to next hour
+		// if hour not matched.
+		if o.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, o.Location)
+			continue
+		}
+
+		// Go to next minute
+		// if minute not matched.
+		if o.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// /////////////////////////////////////////////////////////////
+// Parse methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *Cron) matchDay(t time.Time) bool {
+	dom := o.dom&(1<<uint(t.Day())) > 0
+	dow := o.dow&(1<<uint(t.Weekday())) > 0
+
+	// Match either
+	// if both day of month and day of week restricted.
+	if !o.domAny && !o.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (o *Cron) parseField(s string, min, max int) (bits uint64, all bool, err error) {
+	all = s == "*"
+
+	for _, part := range strings.Split(s, ",") {
+		var (
+			lower, upper = min, max
+			step         = 1
+			expr         = part
+		)
+
+		// Step value
+		// after slash.
+		if n := strings.Index(part, "/"); n >= 0 {
+			if step, err = strconv.Atoi(part[n+1:]); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step: %s", part)
+			}
+			expr = part[:n]
+		}
+
+		// Range value
+		// or single value.
+		if expr != "*" {
+			if n := strings.Index(expr, "-"); n >= 0 {
+				if lower, err = strconv.Atoi(expr[:n]); err == nil {
+					upper, err = strconv.Atoi(expr[n+1:])
+				}
+			} else if lower, err = strconv.Atoi(expr); err == nil {
+				upper = lower
+				if step > 1 {
+					upper = max
+				}
+			}
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid value: %s", part)
+			}
+		}
+
+		if lower < min || upper > max || lower > upper {
+			return 0, false, fmt.Errorf("value out of range %d-%d: %s", min, max, part)
+		}
+
+		for i := lower; i <= upper; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return
+}
+
+func (o *Cron) init(timezone string) (*Cron, error) {
+	var (
+		err    error
+		expr   = o.Expression
+		fields []string
+	)
+
+	// Load timezone.
+	if timezone == "" {
+		o.Location = time.UTC
+	} else if o.Location, err = time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", timezone)
+	}
+
+	// Replace descriptor.
+	if s, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = s
+	}
+
+	// Return error
+	// if not 5 fields.
+	if fields = strings.Fields(expr); len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression: %s", o.Expression)
+	}
+
+	if o.minute, _, err = o.parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if o.hour, _, err = o.parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if o.dom, o.domAny, err = o.parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if o.month, _, err = o.parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if o.dow, o.dowAny, err = o.parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+
+	// Sunday
+	// is 0 or 7.
+	if o.dow&(1<<7) > 0 {
+		o.dow |= 1
+	}
+	return o, nil
+}
@@ -0,0 +1,82 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2023, 3, 3, 10, 7, 30, 0, time.UTC)
+
+	for expr, expected := range map[string]string{
+		"* * * * *":        "2023-03-03 10:08",
+		"*/5 * * * *":      "2023-03-03 10:10",
+		"0 9-18 * * 1-5":   "2023-03-03 11:00",
+		"30 8 * * 1":       "2023-03-06 08:30",
+		"0 0 1,15 * *":     "2023-03-15 00:00",
+		"0 0 1 1 *":        "2024-01-01 00:00",
+		"0 0 * * 7":        "2023-03-05 00:00",
+		"0 0 13 * 5":       "2023-03-10 00:00",
+		"@hourly":          "2023-03-03 11:00",
+		"@daily":           "2023-03-04 00:00",
+		"@monthly":         "2023-04-01 00:00",
+		"15 10-12/2 * * *": "2023-03-03 10:15",
+	} {
+		c, err := NewCron(expr, "")
+		if err != nil {
+			t.Fatalf("%s: parse error, %v", expr, err)
+		}
+		if next := c.Next(from).Format("2006-01-02 15:04"); next != expected {
+			t.Fatalf("%s: expected=%s, got=%s", expr, expected, next)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := NewCron("0 0 30 2 *", "")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if next := c.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("zero time expected: %v", next)
+	}
+}
+
+func TestCronTimezone(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	c, err := NewCron("0 9 * * *", "")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	c.Location = loc
+
+	next := c.Next(time.Date(2023, 3, 3, 2, 0, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2023, 3, 4, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("fire time of location expected: %v", next.UTC())
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := NewCron(expr, ""); err == nil {
+			t.Fatalf("%q: error expected", expr)
+		}
+	}
+	if _, err := NewCron("* * * * *", "Invalid/Zone"); err == nil {
+		t.Fatalf("timezone error expected")
+	}
+}
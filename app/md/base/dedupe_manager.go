@@ -0,0 +1,155 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package base
+
+import (
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"sync"
+)
+
+var (
+	// Dedupe
+	// instance of duplicate suppression manager.
+	Dedupe DedupeManager
+)
+
+type (
+	// DedupeManager
+	// interface of duplicate suppression manager.
+	//
+	// Recently delivered messages are kept in bounded local cache,
+	// message table is used if not cached.
+	DedupeManager interface {
+		// Claim
+		// return message should be delivered or not.
+		//
+		// Return false if message delivered already or delivering on
+		// other node, otherwise record of message is claimed as
+		// processing and true returned.
+		Claim(t *Task, m *Message) bool
+
+		// Done
+		// remember delivered message in local cache.
+		Done(t *Task, m *Message)
+	}
+
+	dedupe struct {
+		mu     *sync.Mutex
+		keys   map[string]bool
+		offset int
+		ring   []string
+	}
+)
+
+func (o *dedupe) Claim(t *Task, m *Message) bool {
+	if o.exists(o.name(t, m)) {
+		log.Infofc(m.GetContext(), "dedupe: delivered already, found in cache")
+		return false
+	}
+
+	var (
+		bean    *models.Message
+		ctx     = log.NewChild(m.GetContext())
+		err     error
+		service = services.NewMessageService(db.Connector.GetMasterWithContext(ctx, models.ConnectionName))
+	)
+
+	// Deliver
+	// if error occurred.
+	if bean, err = service.GetByMessageId(t.Id, m.MessageId); err != nil {
+		log.Errorfc(ctx, "dedupe: read message error, %v", err)
+		return true
+	}
+
+	// Add processing record,
+	// it fails if added by other node at the same time.
+	if bean == nil {
+		if bean, err = service.AddProcessing(&models.Message{
+			TaskId:           m.TaskId,
+			PayloadMessageId: m.PayloadMessageId,
+			MessageDequeue:   m.Dequeue,
+			MessageTime:      m.MessageTime,
+			MessageId:        m.MessageId,
+			Keyword:          m.Keyword,
+			MessageBody:      m.MessageBody,
+		}); err == nil {
+			m.SetClaimed(bean.Id, 0)
+			return true
+		}
+
+		// Skip
+		// if added by other node, deliver if other error occurred.
+		if services.IsDuplicate(err) {
+			log.Infofc(ctx, "dedupe: delivering on other node, added already")
+			return false
+		}
+		log.Errorfc(ctx, "dedupe: add processing record error, %v", err)
+		return true
+	}
+
+	switch bean.Status {
+	case models.StatusSucceed, models.StatusIgnored:
+		log.Infofc(ctx, "dedupe: delivered already, bean-id=%d", bean.Id)
+		o.add(o.name(t, m))
+		return false
+
+	case models.StatusFailed, models.StatusProcessing:
+		if affects, _ := service.SetStatusAsClaimed(bean.Id, t.DispatchTimeout()*2); affects > 0 {
+			m.SetClaimed(bean.Id, bean.Status)
+			return true
+		}
+	}
+
+	log.Infofc(ctx, "dedupe: delivering on other node, bean-id=%d, status=%d", bean.Id, bean.Status)
+	return false
+}
+
+func (o *dedupe) Done(t *Task, m *Message) {
+	o.add(o.name(t, m))
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+// Add key
+// into cache, the oldest key is removed if cache is full.
+func (o *dedupe) add(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.keys[key] {
+		return
+	}
+
+	if old := o.ring[o.offset]; old != "" {
+		delete(o.keys, old)
+	}
+
+	o.keys[key] = true
+	o.ring[o.offset] = key
+	o.offset = (o.offset + 1) % len(o.ring)
+}
+
+func (o *dedupe) exists(key string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.keys[key]
+}
+
+func (o *dedupe) init() *dedupe {
+	o.mu = &sync.Mutex{}
+	o.keys = make(map[string]bool)
+	o.ring = make([]string, conf.Config.Consumer.DedupeCacheSize)
+	return o
+}
+
+func (o *dedupe) name(t *Task, m *Message) string {
+	return fmt.Sprintf("%d:%s", t.Id, m.MessageId)
+}
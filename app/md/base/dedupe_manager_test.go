@@ -0,0 +1,48 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupeClaimCached(t *testing.T) {
+	var (
+		d    = (&dedupe{}).init()
+		m    = (&Message{}).init()
+		task = &Task{Id: 1}
+	)
+
+	m.MessageId = "message-id"
+	d.Done(task, m)
+
+	if d.Claim(task, m) {
+		t.Fatalf("delivered message should not be claimed")
+	}
+	if d.exists(d.name(&Task{Id: 2}, m)) {
+		t.Fatalf("message of other task should not be cached")
+	}
+}
+
+func TestDedupeCacheEvict(t *testing.T) {
+	d := (&dedupe{}).init()
+	d.ring = make([]string, 3)
+
+	for i := 0; i < 4; i++ {
+		d.add(fmt.Sprintf("key-%d", i))
+	}
+
+	if d.exists("key-0") {
+		t.Fatalf("oldest key should be evicted")
+	}
+	for i := 1; i < 4; i++ {
+		if k := fmt.Sprintf("key-%d", i); !d.exists(k) {
+			t.Fatalf("key should be cached: %s", k)
+		}
+	}
+	if n := len(d.keys); n != 3 {
+		t.Fatalf("cached keys count: expected=3, got=%d", n)
+	}
+}
@@ -0,0 +1,117 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-20
+
+package base
+
+import (
+	"github.com/fuyibing/gmd/app/md/conf"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Health
+	// instance of health manager.
+	Health HealthManager
+)
+
+type (
+	// HealthManager
+	// interface of health manager.
+	//
+	// Statistic of delivering address, shared by all tasks in
+	// memory and kept when memory reloaded.
+	HealthManager interface {
+		// Acquire
+		// increment in-flight count of address.
+		Acquire(addr string)
+
+		// Available
+		// return address can be selected or not.
+		//
+		// Return false if address ejected for consecutive
+		// failures, otherwise true returned.
+		Available(addr string) bool
+
+		// Inflight
+		// return in-flight count of address.
+		Inflight(addr string) int32
+
+		// Release
+		// decrement in-flight count of address and record
+		// delivered result.
+		Release(addr string, err error)
+	}
+
+	health struct {
+		mu     *sync.RWMutex
+		states map[string]*healthState
+	}
+
+	healthState struct {
+		ejected  int64
+		fails    int32
+		inflight int32
+	}
+)
+
+func (o *health) Acquire(addr string) {
+	atomic.AddInt32(&o.state(addr).inflight, 1)
+}
+
+func (o *health) Available(addr string) bool {
+	return atomic.LoadInt64(&o.state(addr).ejected) <= time.Now().UnixNano()
+}
+
+func (o *health) Inflight(addr string) int32 {
+	return atomic.LoadInt32(&o.state(addr).inflight)
+}
+
+func (o *health) Release(addr string, err error) {
+	x := o.state(addr)
+	atomic.AddInt32(&x.inflight, -1)
+
+	// Reset
+	// consecutive failures if delivered succeed.
+	if err == nil {
+		atomic.StoreInt32(&x.fails, 0)
+		return
+	}
+
+	// Eject address
+	// if consecutive failures reached.
+	if atomic.AddInt32(&x.fails, 1) >= conf.Config.Consumer.EndpointMaxFails {
+		atomic.StoreInt32(&x.fails, 0)
+		atomic.StoreInt64(&x.ejected, time.Now().Add(time.Duration(conf.Config.Consumer.EndpointEjectSeconds)*time.Second).UnixNano())
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *health) init() *health {
+	o.mu = &sync.RWMutex{}
+	o.states = make(map[string]*healthState)
+	return o
+}
+
+func (o *health) state(addr string) *healthState {
+	o.mu.RLock()
+	x, ok := o.states[addr]
+	o.mu.RUnlock()
+
+	if ok {
+		return x
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok = o.states[addr]; !ok {
+		x = &healthState{}
+		o.states[addr] = x
+	}
+	return x
+}
@@ -11,8 +11,15 @@ import (
 
 func init() {
 	new(sync.Once).Do(func() {
+		Adaptive = (&adaptive{}).init()
+		Breaker = (&breaker{}).init()
+		Dedupe = (&dedupe{}).init()
+		Health = (&health{}).init()
+		Limiter = (&limiter{}).init()
 		Memory = (&memory{}).init()
 		Pool = (&pool{}).init()
+		Quota = (&quota{}).init()
 		Result = (&result{}).init()
+		Sequencer = (&sequencer{}).init()
 	})
 }
@@ -0,0 +1,171 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package base
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// Limiter
+	// instance of rate limiter manager.
+	Limiter LimiterManager
+)
+
+type (
+	// LimiterManager
+	// interface of rate limiter manager.
+	//
+	// Token bucket of subscription task, shared by all consumers of
+	// the task on this node and kept when memory reloaded. Rate is
+	// applied by Task.Rate(), task can be throttled in a duration
+	// if subscriber asked to slow down.
+	LimiterManager interface {
+		// Empty
+		// return token bucket of task is empty or not.
+		//
+		// Return false if rate limit not configured.
+		Empty(t *Task) bool
+
+		// Reserve
+		// take a token from bucket of task.
+		//
+		// Return duration to wait before dispatch. Token is not
+		// taken and false returned if duration greater than max.
+		Reserve(t *Task, max time.Duration) (wait time.Duration, ok bool)
+
+		// Throttle
+		// pause delivering of task in specified duration.
+		Throttle(t *Task, d time.Duration)
+	}
+
+	limiter struct {
+		mu      *sync.RWMutex
+		buckets map[int]*limiterBucket
+	}
+
+	limiterBucket struct {
+		mu *sync.Mutex
+
+		burst  float64
+		rate   float64
+		tokens float64
+		last   time.Time
+
+		throttled time.Time
+	}
+)
+
+func (o *limiter) Empty(t *Task) bool {
+	x := o.bucket(t)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if time.Now().Before(x.throttled) {
+		return true
+	}
+
+	if limit, _ := t.Rate(); limit <= 0 {
+		return false
+	}
+
+	return x.refill(t) < 1
+}
+
+func (o *limiter) Reserve(t *Task, max time.Duration) (wait time.Duration, ok bool) {
+	x := o.bucket(t)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// Return false
+	// if throttled longer than max.
+	if wait = time.Until(x.throttled); wait > max {
+		return wait, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+
+	if limit, _ := t.Rate(); limit <= 0 {
+		return wait, true
+	}
+
+	// Return throttled duration
+	// if token left.
+	if x.refill(t) >= 1 {
+		x.tokens--
+		return wait, true
+	}
+
+	// Return false
+	// if wait duration greater than max.
+	if d := time.Duration((1 - x.tokens) / x.rate * float64(time.Second)); d > wait {
+		if wait = d; wait > max {
+			return wait, false
+		}
+	}
+
+	x.tokens--
+	return wait, true
+}
+
+func (o *limiter) Throttle(t *Task, d time.Duration) {
+	x := o.bucket(t)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if u := time.Now().Add(d); u.After(x.throttled) {
+		x.throttled = u
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *limiter) bucket(t *Task) *limiterBucket {
+	o.mu.RLock()
+	x, ok := o.buckets[t.Id]
+	o.mu.RUnlock()
+
+	if ok {
+		return x
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok = o.buckets[t.Id]; !ok {
+		_, burst := t.Rate()
+		x = &limiterBucket{mu: &sync.Mutex{}, last: time.Now(), tokens: float64(burst)}
+		o.buckets[t.Id] = x
+	}
+	return x
+}
+
+func (o *limiter) init() *limiter {
+	o.mu = &sync.RWMutex{}
+	o.buckets = make(map[int]*limiterBucket)
+	return o
+}
+
+// Refill
+// tokens since last called and return tokens.
+func (o *limiterBucket) refill(t *Task) float64 {
+	now := time.Now()
+
+	// Apply
+	// rate and burst of task which may be changed.
+	limit, burst := t.Rate()
+	o.burst, o.rate = float64(burst), limit
+
+	if o.tokens += now.Sub(o.last).Seconds() * o.rate; o.tokens > o.burst {
+		o.tokens = o.burst
+	}
+
+	o.last = now
+	return o.tokens
+}
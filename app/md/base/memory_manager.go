@@ -9,6 +9,7 @@ import (
 	"github.com/fuyibing/db/v8"
 	"github.com/fuyibing/gmd/app/models"
 	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
 	"strings"
 	"sync"
 )
@@ -162,6 +163,12 @@ func (o *memory) loadRegistry() (err error) {
 		mapper[bean.Id] = (&Registry{}).init(bean)
 	}
 
+	// Assign latest schema
+	// of registries, registry keep previous schema if parse failed.
+	if err = o.loadSchema(mapper); err != nil {
+		return
+	}
+
 	// Set buffer
 	// as memory.
 	o.mu.Lock()
@@ -171,6 +178,28 @@ func (o *memory) loadRegistry() (err error) {
 	return
 }
 
+func (o *memory) loadSchema(mapper map[int]*Registry) (err error) {
+	var list []*models.RegistrySchema
+
+	// List latest schemas
+	// from database.
+	if list, err = services.NewRegistrySchemaService().ListLatest(); err != nil {
+		return
+	}
+
+	for _, bean := range list {
+		if registry, ok := mapper[bean.RegistryId]; ok {
+			if pe := registry.initSchema(bean); pe != nil {
+				log.Errorf("memory manager: parse schema failed, registry-id=%d, version=%d, error=%v", bean.RegistryId, bean.Version, pe)
+				if prev := o.GetRegistry(bean.RegistryId); prev != nil {
+					registry.Schema, registry.SchemaMode, registry.SchemaVersion = prev.Schema, prev.SchemaMode, prev.SchemaVersion
+				}
+			}
+		}
+	}
+	return
+}
+
 func (o *memory) loadTask() (err error) {
 	var list []*models.Task
 
@@ -11,19 +11,34 @@ import (
 	"github.com/fuyibing/gmd/app/models"
 	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
+	"time"
 )
 
 type (
 	// Message
 	// struct for message consumed properties.
 	Message struct {
+		accepted    bool
+		ackDeadline int64
+		ackStored   bool
+		ackToken    string
+
+		claimed     int64
+		claimedFrom int
+		duplicated  bool
+		expired     bool
+		stored      bool
+
 		body     []byte
 		c        context.Context
+		deferred time.Duration
 		duration float64
 		err      error
 		ignored  bool
 
+		Codec            conf.Codec
 		Dequeue          int
+		ExpiresAt        int64
 		Keyword          string
 		MessageBody      string
 		MessageId        string
@@ -33,15 +48,55 @@ type (
 	}
 )
 
+func (o *Message) GetAccepted() bool                     { return o.accepted }
+func (o *Message) GetAckToken() string                   { return o.ackToken }
 func (o *Message) GetContext() context.Context           { return o.c }
+func (o *Message) GetDeferred() time.Duration            { return o.deferred }
+func (o *Message) GetDuplicated() bool                   { return o.duplicated }
 func (o *Message) GetError() error                       { return o.err }
+func (o *Message) GetExpired() bool                      { return o.expired }
 func (o *Message) GetIgnored() bool                      { return o.ignored }
+func (o *Message) GetStored() bool                       { return o.stored }
 func (o *Message) Release()                              { Pool.ReleaseMessage(o) }
+func (o *Message) SetAccepted(a bool) *Message           { o.accepted = a; return o }
 func (o *Message) SetBody(b []byte) *Message             { o.body = b; return o }
 func (o *Message) SetContext(c context.Context) *Message { o.c = c; return o }
+func (o *Message) SetDeferred(d time.Duration) *Message  { o.deferred = d; return o }
+func (o *Message) SetDuplicated(d bool) *Message         { o.duplicated = d; return o }
 func (o *Message) SetDuration(d float64) *Message        { o.duration = d; return o }
 func (o *Message) SetError(e error) *Message             { o.err = e; return o }
+func (o *Message) SetExpired(e bool) *Message            { o.expired = e; return o }
 func (o *Message) SetIgnored(i bool) *Message            { o.ignored = i; return o }
+func (o *Message) SetStored(s bool) *Message             { o.stored = s; return o }
+
+// SetAck
+// set token and deadline of asynchronous acknowledgement.
+func (o *Message) SetAck(token string, deadline int64) *Message {
+	o.ackToken = token
+	o.ackDeadline = deadline
+	return o
+}
+
+// StoreAccepted
+// save accepted message with acknowledgement token into database
+// synchronously, so it can be acknowledged as soon as subscriber
+// responded.
+func (o *Message) StoreAccepted() error {
+	if err := o.save(); err != nil {
+		return err
+	}
+	o.ackStored = true
+	return nil
+}
+
+// SetClaimed
+// set record id claimed as processing before delivery and status of
+// record before claimed, status is 0 if record added by claim.
+func (o *Message) SetClaimed(id int64, status int) *Message {
+	o.claimed = id
+	o.claimedFrom = status
+	return o
+}
 
 // /////////////////////////////////////////////////////////////
 // Access methods.
@@ -49,33 +104,56 @@ func (o *Message) SetIgnored(i bool) *Message            { o.ignored = i; return
 
 func (o *Message) after() {
 	// Call save
-	// if enabled.
-	if o.ignored {
+	// if enabled, deferred message is not delivered and skipped,
+	// accepted message is always saved for acknowledgement, claimed
+	// message is always saved for duplicate check, expired message
+	// is always saved for history. Accepted message stored when
+	// dispatched is not saved again, it may be acknowledged already.
+	switch {
+	case o.duplicated:
+	case o.accepted && o.ackStored:
+	case o.deferred > 0:
+		if o.claimed > 0 {
+			o.unclaim()
+		}
+	case o.accepted, o.claimed > 0, o.expired:
+		o.save()
+	case o.ignored:
 		if *conf.Config.Consumer.StoreDispatchIgnored {
 			o.save()
 		}
-	} else {
-		if o.err != nil {
-			if *conf.Config.Consumer.StoreDispatchFailed {
-				o.save()
-			}
-		} else {
-			if *conf.Config.Consumer.StoreDispatchSucceed {
-				o.save()
-			}
+	case o.err != nil:
+		if *conf.Config.Consumer.StoreDispatchFailed {
+			o.save()
+		}
+	default:
+		if *conf.Config.Consumer.StoreDispatchSucceed {
+			o.save()
 		}
 	}
 
 	// Reset
 	// access properties.
+	o.accepted = false
+	o.ackDeadline = 0
+	o.ackStored = false
+	o.ackToken = ""
+	o.claimed = 0
+	o.claimedFrom = 0
+	o.duplicated = false
+	o.expired = false
+	o.stored = false
 	o.body = nil
 	o.c = nil
+	o.deferred = 0
 	o.duration = 0
 	o.err = nil
 
 	// Reset
 	// data properties.
+	o.Codec = ""
 	o.Dequeue = 0
+	o.ExpiresAt = 0
 	o.Keyword = ""
 	o.MessageBody = ""
 	o.MessageId = ""
@@ -92,7 +170,7 @@ func (o *Message) init() *Message {
 	return o
 }
 
-func (o *Message) save() {
+func (o *Message) save() (err error) {
 	log.Infofc(o.c, "consumed message: store into database")
 
 	var (
@@ -100,7 +178,6 @@ func (o *Message) save() {
 		bean    *models.Message
 		beanId  int64
 		ctx     = log.NewChild(o.c)
-		err     error
 		sess    = db.Connector.GetMasterWithContext(ctx, models.ConnectionName)
 		service = services.NewMessageService(sess)
 	)
@@ -140,13 +217,21 @@ func (o *Message) save() {
 			PayloadMessageId: o.PayloadMessageId,
 			MessageDequeue:   o.Dequeue,
 			MessageTime:      o.MessageTime,
+			ExpiresAt:        o.ExpiresAt,
 			MessageId:        o.MessageId,
+			Keyword:          o.Keyword,
 			MessageBody:      o.MessageBody,
 			ResponseBody:     string(o.body),
 		}
 
 		// Add record.
-		if o.ignored {
+		if o.expired {
+			bean, err = service.AddExpired(req)
+		} else if o.accepted {
+			req.AckToken = o.ackToken
+			req.AckDeadline = o.ackDeadline
+			bean, err = service.AddAccepted(req)
+		} else if o.ignored {
 			bean, err = service.AddIgnored(req)
 		} else {
 			if o.err != nil {
@@ -165,7 +250,11 @@ func (o *Message) save() {
 
 	// Update status
 	// if saved already.
-	if o.ignored {
+	if o.expired {
+		affects, err = service.SetStatusAsExpired(bean.Id)
+	} else if o.accepted {
+		affects, err = service.SetStatusAsAccepted(bean.Id, o.duration, string(o.body), o.ackToken, o.ackDeadline)
+	} else if o.ignored {
 		affects, err = service.SetStatusAsIgnored(bean.Id)
 	} else {
 		if o.err != nil {
@@ -174,4 +263,24 @@ func (o *Message) save() {
 			affects, err = service.SetStatusAsSucceed(bean.Id, o.duration, string(o.body))
 		}
 	}
+	return
+}
+
+// Unclaim
+// record of deferred message, so it can be claimed when redelivered.
+func (o *Message) unclaim() {
+	var (
+		err     error
+		service = services.NewMessageService()
+	)
+
+	if o.claimedFrom == 0 {
+		_, err = service.DeleteProcessing(o.claimed)
+	} else {
+		_, err = service.SetStatusAsUnclaimed(o.claimed, models.StatusFailed)
+	}
+
+	if err != nil {
+		log.Errorfc(o.c, "consumed message: unclaim error, bean-id=%d, %v", o.claimed, err)
+	}
 }
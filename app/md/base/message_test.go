@@ -0,0 +1,26 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"testing"
+)
+
+func TestMessageAckReset(t *testing.T) {
+	m := (&Message{}).init()
+	m.SetAck("token", 1700000000).SetAccepted(true)
+	m.ackStored = true
+	m.MessageId = "message-id"
+
+	// Accepted message stored when dispatched
+	// must not be saved again, it is reset only.
+	m.after()
+
+	if m.GetAccepted() || m.GetAckToken() != "" || m.ackDeadline != 0 || m.ackStored {
+		t.Fatalf("ack state should be reset: accepted=%v, token=%s, deadline=%d, stored=%v", m.accepted, m.ackToken, m.ackDeadline, m.ackStored)
+	}
+	if m.MessageId != "" {
+		t.Fatalf("message id should be reset: %s", m.MessageId)
+	}
+}
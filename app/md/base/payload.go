@@ -11,25 +11,36 @@ import (
 	"github.com/fuyibing/gmd/app/models"
 	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
+	"time"
 )
 
 type (
 	// Payload
 	// struct for message publish properties.
 	Payload struct {
+		body      string
 		c         context.Context
+		codec     conf.Codec
 		duration  float64
 		err       error
 		ignored   bool
 		messageId string
+		requeued  bool
+		stored    bool
+		sync      bool
+		transient bool
 
+		DeliverAt        int64
+		ExpiresAt        int64
 		FilterTag        string
 		Hash             string
+		IdempotencyKey   string
 		Keyword          string
 		MessageBody      string
 		MessageMessageId string
 		MessageTaskId    int
 		Offset           int
+		Priority         Priority
 		RegistryId       int
 		TopicName        string
 		TopicTag         string
@@ -37,52 +48,112 @@ type (
 )
 
 func (o *Payload) GetContext() context.Context           { return o.c }
+func (o *Payload) GetDelay() time.Duration               { return time.Until(time.Unix(o.DeliverAt, 0)) }
 func (o *Payload) GetError() error                       { return o.err }
 func (o *Payload) GetIgnored() bool                      { return o.ignored }
 func (o *Payload) GetMessageId() string                  { return o.messageId }
+func (o *Payload) GetStored() bool                       { return o.stored }
+func (o *Payload) GetSync() bool                         { return o.sync }
 func (o *Payload) Release()                              { Pool.ReleasePayload(o) }
 func (o *Payload) SetContext(c context.Context) *Payload { o.c = c; return o }
 func (o *Payload) SetDuration(d float64) *Payload        { o.duration = d; return o }
 func (o *Payload) SetError(e error) *Payload             { o.err = e; return o }
 func (o *Payload) SetIgnored(b bool) *Payload            { o.ignored = b; return o }
 func (o *Payload) SetMessageId(s string) *Payload        { o.messageId = s; return o }
+func (o *Payload) SetRequeued(b bool) *Payload           { o.requeued = b; return o }
+func (o *Payload) SetStored(b bool) *Payload             { o.stored = b; return o }
+func (o *Payload) SetSync(b bool) *Payload               { o.sync = b; return o }
+func (o *Payload) SetTransient(b bool) *Payload          { o.transient = b; return o }
+
+// Body
+// return message body sent to adapter, it is compressed with codec of
+// producer config if size reached threshold.
+//
+// Adapter must record codec of Codec method in message property if
+// compressed body sent, otherwise use MessageBody instead.
+func (o *Payload) Body() string {
+	if o.body != "" {
+		return o.body
+	}
+
+	o.body, o.codec = o.MessageBody, conf.CodecNone
+	if len(o.MessageBody) >= conf.Config.Producer.CompressThreshold {
+		if s, codec, err := Compress(conf.Config.Producer.CompressCodec, o.MessageBody); err != nil {
+			log.Warnfc(o.c, "produced payload: compress failed, codec=%s, error=%v", conf.Config.Producer.CompressCodec, err)
+		} else {
+			o.body, o.codec = s, codec
+		}
+	}
+	return o.body
+}
+
+// Codec
+// return codec of message body returned by Body method.
+func (o *Payload) Codec() conf.Codec {
+	o.Body()
+	return o.codec
+}
 
 // /////////////////////////////////////////////////////////////
 // Access methods.
 // /////////////////////////////////////////////////////////////
 
 func (o *Payload) after() {
+	// Update message id
+	// of idempotency key if published.
+	if o.IdempotencyKey != "" && o.messageId != "" {
+		o.saveIdempotency()
+	}
+
 	// Call save
 	// if enabled.
-	if o.ignored {
+	switch {
+	case o.requeued:
+		// Keep processing status, it is published again by retry
+		// manager after node left cluster.
+	case o.transient:
+		// Not stored, payload is owned by caller such as schedule,
+		// cron or outbox record, or not published at all. Caller
+		// retries it if failed.
+	case o.stored:
+		o.save()
+	case o.ignored:
 		if *conf.Config.Producer.StorePublishIgnored {
 			o.save()
 		}
-	} else {
-		if o.err != nil {
-			if *conf.Config.Producer.StorePublishFailed {
-				o.save()
-			}
-		} else {
-			if *conf.Config.Producer.StorePublishSucceed {
-				o.save()
-			}
+	case o.err != nil:
+		if *conf.Config.Producer.StorePublishFailed {
+			o.save()
+		}
+	default:
+		if *conf.Config.Producer.StorePublishSucceed {
+			o.save()
 		}
 	}
 
 	// Reset
 	// access properties.
+	o.body = ""
 	o.c = nil
+	o.codec = ""
 	o.duration = 0
 	o.err = nil
 	o.messageId = ""
+	o.requeued = false
+	o.stored = false
+	o.sync = false
+	o.transient = false
 
 	// Reset
 	// data properties.
+	o.DeliverAt = 0
+	o.ExpiresAt = 0
 	o.MessageMessageId = ""
 	o.MessageTaskId = 0
 	o.Hash = ""
+	o.IdempotencyKey = ""
 	o.Offset = 0
+	o.Priority = PriorityNormal
 	o.RegistryId = 0
 	o.TopicName = ""
 	o.TopicTag = ""
@@ -147,6 +218,11 @@ func (o *Payload) save() {
 			MessageMessageId: o.MessageMessageId,
 			Hash:             o.Hash,
 			Offset:           o.Offset,
+			Keyword:          o.Keyword,
+			Priority:         int(o.Priority),
+			IdempotencyKey:   o.IdempotencyKey,
+			DeliverAt:        o.DeliverAt,
+			ExpiresAt:        o.ExpiresAt,
 			RegistryId:       o.RegistryId,
 			MessageId:        o.messageId,
 			MessageBody:      o.MessageBody,
@@ -162,7 +238,7 @@ func (o *Payload) save() {
 			bean, err = service.AddIgnored(req)
 		} else {
 			if o.err != nil {
-				if conf.Config.Producer.MaxRetry > 1 {
+				if !o.sync && conf.Config.Producer.MaxRetry > 1 {
 					bean, err = service.AddWaiting(req)
 				} else {
 					bean, err = service.AddFailed(req)
@@ -185,7 +261,7 @@ func (o *Payload) save() {
 		affects, err = service.SetStatusAsIgnored(bean.Id)
 	} else {
 		if o.err != nil {
-			if (bean.Retry + 1) < conf.Config.Producer.MaxRetry {
+			if !o.sync && (bean.Retry+1) < conf.Config.Producer.MaxRetry {
 				affects, err = service.SetStatusAsWaiting(bean.Id, o.duration, o.err.Error())
 			} else {
 				affects, err = service.SetStatusAsFailed(bean.Id, o.duration, o.err.Error())
@@ -195,3 +271,9 @@ func (o *Payload) save() {
 		}
 	}
 }
+
+func (o *Payload) saveIdempotency() {
+	if _, err := services.NewIdempotencyService().SetMessageId(o.Hash, o.Offset, o.messageId); err != nil {
+		log.Errorfc(o.c, "produced payload: store message id of idempotency key error, %v", err)
+	}
+}
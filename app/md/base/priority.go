@@ -0,0 +1,62 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-26
+
+package base
+
+import (
+	"strings"
+)
+
+// Priority
+// publish priority of payload, payloads in higher lane are popped from
+// producer bucket earlier.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityLow
+)
+
+// Priorities
+// lanes of producer bucket in draining order.
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+var priorityNames = map[Priority]string{
+	PriorityHigh:   "high",
+	PriorityNormal: "normal",
+	PriorityLow:    "low",
+}
+
+// ParsePriority
+// return priority of name, normal returned if name not recognized.
+//
+//	base.ParsePriority("high")
+func ParsePriority(s string) Priority {
+	for p, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return p
+		}
+	}
+	return PriorityNormal
+}
+
+// Lane
+// return lane index of producer bucket.
+func (o Priority) Lane() int {
+	for i, p := range Priorities {
+		if p == o {
+			return i
+		}
+	}
+	return PriorityNormal.Lane()
+}
+
+// String
+// return priority name.
+func (o Priority) String() string {
+	if s, ok := priorityNames[o]; ok {
+		return s
+	}
+	return priorityNames[PriorityNormal]
+}
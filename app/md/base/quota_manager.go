@@ -0,0 +1,164 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package base
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// Quota
+	// instance of cluster quota manager.
+	Quota QuotaManager
+)
+
+type (
+	// QuotaManager
+	// interface of cluster quota manager.
+	//
+	// Global limits of task are shared by alive nodes, each node takes
+	// its share only and shares of all nodes add up to global limit.
+	QuotaManager interface {
+		// Acquire
+		// increment consuming count of task on this node.
+		//
+		// Return false if share of global concurrency reached.
+		Acquire(t *Task) bool
+
+		// Busy
+		// return share of global concurrency reached or not.
+		Busy(t *Task) bool
+
+		// Nodes
+		// return alive nodes count in cluster.
+		Nodes() int
+
+		// Release
+		// decrement consuming count of task on this node.
+		Release(t *Task)
+
+		// SetNodes
+		// update alive nodes count in cluster and rank of this node
+		// in alive nodes, rank begin with 0.
+		SetNodes(n, rank int)
+
+		// Share
+		// return share of global value on this node.
+		//
+		// Global value is divided by nodes, remainder is assigned to
+		// nodes by rank one each. Nodes take remainder in turn every
+		// minute, so node of zero share is not starved if global value
+		// less than nodes.
+		Share(n int) int
+
+		// ShareRate
+		// return share of global rate on this node, it is exact
+		// quotient of global rate and nodes.
+		ShareRate(n int) float64
+	}
+
+	quota struct {
+		mu          *sync.RWMutex
+		nodes, rank int32
+		consuming   map[int]*int32
+	}
+)
+
+func (o *quota) Acquire(t *Task) bool {
+	if t.GlobalConcurrency <= 0 {
+		return true
+	}
+
+	x := o.counter(t.Id)
+
+	if atomic.AddInt32(x, 1) > int32(o.Share(t.GlobalConcurrency)) {
+		atomic.AddInt32(x, -1)
+		return false
+	}
+	return true
+}
+
+func (o *quota) Busy(t *Task) bool {
+	if t.GlobalConcurrency <= 0 {
+		return false
+	}
+	return atomic.LoadInt32(o.counter(t.Id)) >= int32(o.Share(t.GlobalConcurrency))
+}
+
+func (o *quota) Nodes() int {
+	return int(atomic.LoadInt32(&o.nodes))
+}
+
+func (o *quota) Release(t *Task) {
+	if t.GlobalConcurrency <= 0 {
+		return
+	}
+	atomic.AddInt32(o.counter(t.Id), -1)
+}
+
+func (o *quota) SetNodes(n, rank int) {
+	if n < 1 {
+		n = 1
+	}
+	if rank < 0 || rank >= n {
+		rank = 0
+	}
+	atomic.StoreInt32(&o.nodes, int32(n))
+	atomic.StoreInt32(&o.rank, int32(rank))
+}
+
+func (o *quota) Share(n int) int {
+	return o.share(n, time.Now().Unix()/60)
+}
+
+func (o *quota) ShareRate(n int) float64 {
+	return float64(n) / float64(o.Nodes())
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *quota) counter(id int) *int32 {
+	o.mu.RLock()
+	x, ok := o.consuming[id]
+	o.mu.RUnlock()
+
+	if ok {
+		return x
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok = o.consuming[id]; !ok {
+		x = new(int32)
+		o.consuming[id] = x
+	}
+	return x
+}
+
+func (o *quota) share(n int, turn int64) int {
+	var (
+		nodes = int64(o.Nodes())
+		rank  = int64(atomic.LoadInt32(&o.rank))
+		share = n / int(nodes)
+	)
+
+	// Remainder
+	// assigned by rank in turn.
+	if rem := int64(n) % nodes; (rank+turn%nodes)%nodes < rem {
+		share++
+	}
+	return share
+}
+
+func (o *quota) init() *quota {
+	o.mu = &sync.RWMutex{}
+	o.nodes = 1
+	o.consuming = make(map[int]*int32)
+	return o
+}
@@ -0,0 +1,77 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"testing"
+)
+
+func TestQuotaShareSum(t *testing.T) {
+	for _, c := range []struct{ limit, nodes int }{
+		{1, 1}, {1, 3}, {5, 3}, {6, 3}, {10, 4}, {2, 5}, {100, 7},
+	} {
+		for turn := int64(0); turn < int64(c.nodes)*2; turn++ {
+			sum := 0
+			for rank := 0; rank < c.nodes; rank++ {
+				q := (&quota{}).init()
+				q.SetNodes(c.nodes, rank)
+				share := q.share(c.limit, turn)
+
+				if min := c.limit / c.nodes; share < min || share > min+1 {
+					t.Errorf("share out of range: limit=%d, nodes=%d, rank=%d, share=%d", c.limit, c.nodes, rank, share)
+				}
+				sum += share
+			}
+			if sum != c.limit {
+				t.Errorf("shares not add up to limit: limit=%d, nodes=%d, turn=%d, sum=%d", c.limit, c.nodes, turn, sum)
+			}
+		}
+	}
+}
+
+func TestQuotaShareTurn(t *testing.T) {
+	q := (&quota{}).init()
+	q.SetNodes(3, 2)
+
+	// Node of rank 2
+	// takes the only one in turn.
+	got := 0
+	for turn := int64(0); turn < 3; turn++ {
+		got += q.share(1, turn)
+	}
+	if got != 1 {
+		t.Fatalf("remainder should be taken once in 3 turns: got=%d", got)
+	}
+}
+
+func TestQuotaShareRate(t *testing.T) {
+	q := (&quota{}).init()
+	q.SetNodes(3, 0)
+
+	if r := q.ShareRate(5); r*3 != 5 {
+		t.Fatalf("rate shares not add up to limit: %v", r)
+	}
+}
+
+func TestQuotaAcquire(t *testing.T) {
+	var (
+		q    = (&quota{}).init()
+		task = &Task{Id: 1, GlobalConcurrency: 4}
+	)
+
+	q.SetNodes(2, 0)
+	for i := 0; i < 2; i++ {
+		if !q.Acquire(task) {
+			t.Fatalf("acquire expected: index=%d", i)
+		}
+	}
+	if q.Acquire(task) || !q.Busy(task) {
+		t.Fatalf("share of node reached")
+	}
+
+	q.Release(task)
+	if q.Busy(task) {
+		t.Fatalf("not busy after release")
+	}
+}
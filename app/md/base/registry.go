@@ -4,8 +4,10 @@
 package base
 
 import (
+	"context"
 	"fmt"
 	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/log/v8"
 	"strings"
 )
 
@@ -14,6 +16,34 @@ import (
 type Registry struct {
 	Id                             int
 	FilterTag, TopicTag, TopicName string
+
+	// Priority
+	// default publish priority of registry.
+	Priority Priority
+
+	// Schema
+	// json schema of message, validate disabled if nil.
+	Schema        *Schema
+	SchemaMode    int
+	SchemaVersion int
+}
+
+// Validate
+// message body with json schema.
+//
+// Return nil if schema not configured, error is logged as warning
+// and not returned in soft mode.
+func (o *Registry) Validate(ctx context.Context, body string) error {
+	if o.Schema == nil {
+		return nil
+	}
+
+	err := o.Schema.Validate(body)
+	if err != nil && o.SchemaMode == models.SchemaModeSoft {
+		log.Warnfc(ctx, "registry schema: message not matched, registry-id=%d, version=%d, error=%v", o.Id, o.SchemaVersion, err)
+		return nil
+	}
+	return err
 }
 
 // /////////////////////////////////////////////////////////////
@@ -25,6 +55,7 @@ func (o *Registry) init(m *models.Registry) *Registry {
 	o.Id = m.Id
 	o.TopicName = strings.ToUpper(m.TopicName)
 	o.TopicTag = strings.ToUpper(m.TopicTag)
+	o.Priority = Priority(m.Priority)
 
 	// Execution field.
 	if o.FilterTag = strings.ToUpper(m.FilterTag); o.FilterTag == "" {
@@ -33,3 +64,11 @@ func (o *Registry) init(m *models.Registry) *Registry {
 
 	return o
 }
+
+func (o *Registry) initSchema(m *models.RegistrySchema) (err error) {
+	if o.Schema, err = NewSchema(m.Content); err == nil {
+		o.SchemaMode = m.Mode
+		o.SchemaVersion = m.Version
+	}
+	return
+}
@@ -0,0 +1,491 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema
+// parsed json schema of registry message.
+//
+// Keywords of draft-07 subset are accepted: type, properties, required,
+// additionalProperties, items, enum, minimum, maximum, minLength,
+// maxLength, pattern, minItems and maxItems. Other keywords are ignored.
+// Subschema of properties, additionalProperties and items can be
+// boolean, true accepts any value and false accepts nothing.
+//
+//	s, err := base.NewSchema(`{"type":"object","required":["id"]}`)
+//	s.Validate(`{"id":1}`)
+type Schema struct {
+	Content string
+
+	additional           *Schema
+	additionalDenied     bool
+	enum                 []interface{}
+	items                *Schema
+	minimum, maximum     *float64
+	minLength, maxLength *int
+	minItems, maxItems   *int
+	never                bool
+	pattern              *regexp.Regexp
+	properties           map[string]*Schema
+	required             []string
+	types                []string
+}
+
+var schemaTypes = map[string]bool{
+	"array": true, "boolean": true, "integer": true, "null": true,
+	"number": true, "object": true, "string": true,
+}
+
+// NewSchema
+// return parsed json schema.
+func NewSchema(content string) (*Schema, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &m); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %v", err)
+	}
+
+	o, err := parseSchema(m, "$")
+	if err != nil {
+		return nil, err
+	}
+	o.Content = content
+	return o, nil
+}
+
+// Compatible
+// return error if schema is not backward compatible with old, message
+// accepted by old schema may be rejected by this one.
+func (o *Schema) Compatible(old *Schema) error {
+	return o.compatible(old, "$")
+}
+
+// Validate
+// return error if message body not matched.
+func (o *Schema) Validate(body string) error {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return fmt.Errorf("message is not json: %v", err)
+	}
+	return o.validate(v, "$")
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *Schema) compatible(old *Schema, path string) error {
+	// Nothing
+	// accepted by old false schema, or by this one.
+	if old.never {
+		return nil
+	}
+	if o.never {
+		return fmt.Errorf("%s: denied", path)
+	}
+
+	// Type
+	// of old schema must be accepted.
+	if len(o.types) > 0 {
+		if len(old.types) == 0 {
+			return fmt.Errorf("%s: type restricted", path)
+		}
+		for _, t := range old.types {
+			if !o.hasType(t) {
+				return fmt.Errorf("%s: type %s removed", path, t)
+			}
+		}
+	}
+
+	// Required
+	// fields can not be added.
+	for _, k := range o.required {
+		if !contains(old.required, k) {
+			return fmt.Errorf("%s: required field %s added", path, k)
+		}
+	}
+
+	// Enum
+	// values can not be removed.
+	if o.enum != nil {
+		if old.enum == nil {
+			return fmt.Errorf("%s: enum restricted", path)
+		}
+		for _, v := range old.enum {
+			if !o.inEnum(v) {
+				return fmt.Errorf("%s: enum value %v removed", path, v)
+			}
+		}
+	}
+
+	// Pattern
+	// can not be added or changed.
+	if o.pattern != nil && (old.pattern == nil || old.pattern.String() != o.pattern.String()) {
+		return fmt.Errorf("%s: pattern changed", path)
+	}
+
+	// Limits
+	// can not be stricter.
+	if err := compatibleMin(o.minimum, old.minimum, path, "minimum"); err != nil {
+		return err
+	}
+	if err := compatibleMax(o.maximum, old.maximum, path, "maximum"); err != nil {
+		return err
+	}
+	if err := compatibleMin(intToFloat(o.minLength), intToFloat(old.minLength), path, "minLength"); err != nil {
+		return err
+	}
+	if err := compatibleMax(intToFloat(o.maxLength), intToFloat(old.maxLength), path, "maxLength"); err != nil {
+		return err
+	}
+	if err := compatibleMin(intToFloat(o.minItems), intToFloat(old.minItems), path, "minItems"); err != nil {
+		return err
+	}
+	if err := compatibleMax(intToFloat(o.maxItems), intToFloat(old.maxItems), path, "maxItems"); err != nil {
+		return err
+	}
+
+	// Properties
+	// of old schema must be compatible, or accepted as additional.
+	for k, p := range old.properties {
+		if n, ok := o.properties[k]; ok {
+			if err := n.compatible(p, path+"."+k); err != nil {
+				return err
+			}
+			continue
+		}
+		if o.additionalDenied {
+			return fmt.Errorf("%s: property %s removed", path, k)
+		}
+		if o.additional != nil {
+			if err := o.additional.compatible(p, path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Additional properties
+	// can not be denied or restricted.
+	if !old.additionalDenied {
+		if o.additionalDenied {
+			return fmt.Errorf("%s: additional properties denied", path)
+		}
+		if o.additional != nil {
+			if old.additional == nil {
+				return fmt.Errorf("%s: additional properties restricted", path)
+			}
+			if err := o.additional.compatible(old.additional, path+".*"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Items
+	// of array.
+	if o.items != nil {
+		if old.items == nil {
+			return fmt.Errorf("%s: items restricted", path)
+		}
+		if err := o.items.compatible(old.items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Schema) hasType(t string) bool {
+	if contains(o.types, t) {
+		return true
+	}
+	return t == "integer" && contains(o.types, "number")
+}
+
+func (o *Schema) inEnum(v interface{}) bool {
+	for _, x := range o.enum {
+		if reflect.DeepEqual(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Schema) validate(v interface{}, path string) error {
+	// False schema.
+	if o.never {
+		return fmt.Errorf("%s: not allowed", path)
+	}
+
+	// Type.
+	if len(o.types) > 0 && !o.hasType(typeOf(v)) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(o.types, " or "), typeOf(v))
+	}
+
+	// Enum.
+	if o.enum != nil && !o.inEnum(v) {
+		return fmt.Errorf("%s: value not in enum", path)
+	}
+
+	switch x := v.(type) {
+	case float64:
+		if o.minimum != nil && x < *o.minimum {
+			return fmt.Errorf("%s: less than minimum %v", path, *o.minimum)
+		}
+		if o.maximum != nil && x > *o.maximum {
+			return fmt.Errorf("%s: greater than maximum %v", path, *o.maximum)
+		}
+
+	case string:
+		n := len([]rune(x))
+		if o.minLength != nil && n < *o.minLength {
+			return fmt.Errorf("%s: shorter than minLength %d", path, *o.minLength)
+		}
+		if o.maxLength != nil && n > *o.maxLength {
+			return fmt.Errorf("%s: longer than maxLength %d", path, *o.maxLength)
+		}
+		if o.pattern != nil && !o.pattern.MatchString(x) {
+			return fmt.Errorf("%s: not matched pattern %s", path, o.pattern.String())
+		}
+
+	case []interface{}:
+		if o.minItems != nil && len(x) < *o.minItems {
+			return fmt.Errorf("%s: fewer than minItems %d", path, *o.minItems)
+		}
+		if o.maxItems != nil && len(x) > *o.maxItems {
+			return fmt.Errorf("%s: more than maxItems %d", path, *o.maxItems)
+		}
+		if o.items != nil {
+			for i, item := range x {
+				if err := o.items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		for _, k := range o.required {
+			if _, ok := x[k]; !ok {
+				return fmt.Errorf("%s: required field %s missing", path, k)
+			}
+		}
+
+		// Sorted keys
+		// to return the same error for the same message.
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if p, ok := o.properties[k]; ok {
+				if err := p.validate(x[k], path+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			if o.additionalDenied {
+				return fmt.Errorf("%s: additional field %s not allowed", path, k)
+			}
+			if o.additional != nil {
+				if err := o.additional.validate(x[k], path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// /////////////////////////////////////////////////////////////
+// Parse methods.
+// /////////////////////////////////////////////////////////////
+
+func parseSchema(m map[string]interface{}, path string) (o *Schema, err error) {
+	o = &Schema{}
+
+	// Type
+	// accept string or string list.
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		o.types = []string{t}
+	case []interface{}:
+		for _, x := range t {
+			s, _ := x.(string)
+			o.types = append(o.types, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s: invalid type", path)
+	}
+	for _, t := range o.types {
+		if !schemaTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type %s", path, t)
+		}
+	}
+
+	// Properties.
+	if x, ok := m["properties"]; ok {
+		props, is := x.(map[string]interface{})
+		if !is {
+			return nil, fmt.Errorf("%s: invalid properties", path)
+		}
+		o.properties = make(map[string]*Schema)
+		for k, p := range props {
+			if o.properties[k], err = parseChild(p, path+"."+k); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Required.
+	if x, ok := m["required"]; ok {
+		list, is := x.([]interface{})
+		if !is {
+			return nil, fmt.Errorf("%s: invalid required", path)
+		}
+		for _, k := range list {
+			s, is := k.(string)
+			if !is {
+				return nil, fmt.Errorf("%s: invalid required", path)
+			}
+			o.required = append(o.required, s)
+		}
+	}
+
+	// Additional properties
+	// accept boolean or schema.
+	switch x := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		o.additionalDenied = !x
+	default:
+		if o.additional, err = parseChild(x, path+".*"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Items.
+	if x, ok := m["items"]; ok {
+		if o.items, err = parseChild(x, path+"[]"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Enum.
+	if x, ok := m["enum"]; ok {
+		if o.enum, ok = x.([]interface{}); !ok {
+			return nil, fmt.Errorf("%s: invalid enum", path)
+		}
+	}
+
+	// Pattern.
+	if x, ok := m["pattern"]; ok {
+		s, is := x.(string)
+		if !is {
+			return nil, fmt.Errorf("%s: invalid pattern", path)
+		}
+		if o.pattern, err = regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+	}
+
+	// Limits.
+	for _, x := range []struct {
+		key string
+		f   **float64
+	}{{"minimum", &o.minimum}, {"maximum", &o.maximum}} {
+		if v, ok := m[x.key]; ok {
+			n, is := v.(float64)
+			if !is {
+				return nil, fmt.Errorf("%s: invalid %s", path, x.key)
+			}
+			*x.f = &n
+		}
+	}
+	for _, x := range []struct {
+		key string
+		i   **int
+	}{{"minLength", &o.minLength}, {"maxLength", &o.maxLength}, {"minItems", &o.minItems}, {"maxItems", &o.maxItems}} {
+		if v, ok := m[x.key]; ok {
+			n, is := v.(float64)
+			if !is || n < 0 || n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s: invalid %s", path, x.key)
+			}
+			i := int(n)
+			*x.i = &i
+		}
+	}
+	return o, nil
+}
+
+func parseChild(v interface{}, path string) (*Schema, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return parseSchema(x, path)
+	case bool:
+		// Accept anything if true, nothing if false.
+		return &Schema{never: !x}, nil
+	}
+	return nil, fmt.Errorf("%s: invalid schema", path)
+}
+
+func compatibleMax(n, old *float64, path, key string) error {
+	if n != nil && (old == nil || *n < *old) {
+		return fmt.Errorf("%s: %s decreased", path, key)
+	}
+	return nil
+}
+
+func compatibleMin(n, old *float64, path, key string) error {
+	if n != nil && (old == nil || *n > *old) {
+		return fmt.Errorf("%s: %s increased", path, key)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func intToFloat(i *int) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
+
+func typeOf(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
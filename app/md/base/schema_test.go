@@ -0,0 +1,158 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"testing"
+)
+
+func TestSchemaParse(t *testing.T) {
+	for _, content := range []string{
+		`not json`,
+		`{"type":"unknown"}`,
+		`{"type":1}`,
+		`{"properties":[]}`,
+		`{"properties":{"id":1}}`,
+		`{"required":"id"}`,
+		`{"required":[1]}`,
+		`{"items":"string"}`,
+		`{"enum":"a"}`,
+		`{"pattern":"("}`,
+		`{"minimum":"1"}`,
+		`{"minLength":-1}`,
+		`{"maxItems":1.5}`,
+	} {
+		if _, err := NewSchema(content); err == nil {
+			t.Fatalf("%s: parse error expected", content)
+		}
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	for i, c := range []struct {
+		schema, body string
+		ok           bool
+	}{
+		// Integer and number.
+		{`{"type":"integer"}`, `1`, true},
+		{`{"type":"integer"}`, `1.5`, false},
+		{`{"type":"number"}`, `1`, true},
+		{`{"type":"number"}`, `1.5`, true},
+		{`{"type":["string","null"]}`, `null`, true},
+		{`{"type":["string","null"]}`, `1`, false},
+
+		// Required.
+		{`{"type":"object","required":["id"]}`, `{"id":1}`, true},
+		{`{"type":"object","required":["id"]}`, `{"name":"a"}`, false},
+
+		// Additional properties.
+		{`{"properties":{"id":{"type":"integer"}}}`, `{"id":1,"name":"a"}`, true},
+		{`{"properties":{"id":{}},"additionalProperties":true}`, `{"id":1,"name":"a"}`, true},
+		{`{"properties":{"id":{}},"additionalProperties":false}`, `{"id":1}`, true},
+		{`{"properties":{"id":{}},"additionalProperties":false}`, `{"id":1,"name":"a"}`, false},
+		{`{"additionalProperties":{"type":"string"}}`, `{"name":"a"}`, true},
+		{`{"additionalProperties":{"type":"string"}}`, `{"name":1}`, false},
+
+		// Boolean subschema.
+		{`{"properties":{"id":true}}`, `{"id":[1]}`, true},
+		{`{"properties":{"id":false}}`, `{"name":"a"}`, true},
+		{`{"properties":{"id":false}}`, `{"id":1}`, false},
+		{`{"items":false}`, `[]`, true},
+		{`{"items":false}`, `[1]`, false},
+
+		// Enum.
+		{`{"enum":["a",1]}`, `1`, true},
+		{`{"enum":["a",1]}`, `"b"`, false},
+
+		// Limits and pattern.
+		{`{"minimum":1,"maximum":3}`, `3`, true},
+		{`{"minimum":1,"maximum":3}`, `4`, false},
+		{`{"minLength":2,"maxLength":3}`, `"中文"`, true},
+		{`{"minLength":2,"maxLength":3}`, `"a"`, false},
+		{`{"pattern":"^[a-z]+$"}`, `"abc"`, true},
+		{`{"pattern":"^[a-z]+$"}`, `"ABC"`, false},
+		{`{"items":{"type":"integer"},"minItems":1,"maxItems":2}`, `[1,2]`, true},
+		{`{"items":{"type":"integer"},"minItems":1,"maxItems":2}`, `[1,"a"]`, false},
+		{`{"items":{"type":"integer"},"minItems":1,"maxItems":2}`, `[1,2,3]`, false},
+
+		// Not json.
+		{`{}`, `{`, false},
+	} {
+		s, err := NewSchema(c.schema)
+		if err != nil {
+			t.Fatalf("case %d: parse error: %v", i, err)
+		}
+		if err = s.Validate(c.body); (err == nil) != c.ok {
+			t.Fatalf("case %d: schema=%s, body=%s, ok expected=%v, error=%v", i, c.schema, c.body, c.ok, err)
+		}
+	}
+}
+
+func TestSchemaCompatible(t *testing.T) {
+	for i, c := range []struct {
+		old, schema string
+		ok          bool
+	}{
+		// Integer and number.
+		{`{"type":"integer"}`, `{"type":"number"}`, true},
+		{`{"type":"number"}`, `{"type":"integer"}`, false},
+		{`{"type":"string"}`, `{"type":["string","null"]}`, true},
+		{`{"type":["string","null"]}`, `{"type":"string"}`, false},
+		{`{}`, `{"type":"string"}`, false},
+
+		// Required.
+		{`{"required":["id"]}`, `{"required":["id"]}`, true},
+		{`{"required":["id","name"]}`, `{"required":["id"]}`, true},
+		{`{"required":["id"]}`, `{"required":["id","name"]}`, false},
+
+		// Enum.
+		{`{"enum":["a"]}`, `{"enum":["a","b"]}`, true},
+		{`{"enum":["a","b"]}`, `{"enum":["a"]}`, false},
+		{`{"enum":["a"]}`, `{}`, true},
+		{`{}`, `{"enum":["a"]}`, false},
+
+		// Additional properties.
+		{`{"additionalProperties":false}`, `{"additionalProperties":true}`, true},
+		{`{"additionalProperties":false}`, `{}`, true},
+		{`{"additionalProperties":true}`, `{"additionalProperties":false}`, false},
+		{`{}`, `{"additionalProperties":{"type":"string"}}`, false},
+		{`{"additionalProperties":{"type":"string"}}`, `{}`, true},
+		{`{"additionalProperties":{"type":"integer"}}`, `{"additionalProperties":{"type":"number"}}`, true},
+		{`{"additionalProperties":{"type":"number"}}`, `{"additionalProperties":{"type":"integer"}}`, false},
+		{`{"properties":{"id":{"type":"integer"}},"additionalProperties":false}`, `{"additionalProperties":{"type":"number"}}`, true},
+		{`{"properties":{"id":{"type":"string"}},"additionalProperties":false}`, `{"additionalProperties":{"type":"number"}}`, false},
+		{`{"properties":{"id":{}}}`, `{"additionalProperties":false}`, false},
+
+		// Properties.
+		{`{"properties":{"id":{"type":"integer"}}}`, `{"properties":{"id":{"type":"number"}}}`, true},
+		{`{"properties":{"id":{"type":"number"}}}`, `{"properties":{"id":{"type":"integer"}}}`, false},
+		{`{"properties":{"id":{}}}`, `{"properties":{"id":{},"name":{}}}`, true},
+
+		// Boolean subschema.
+		{`{"properties":{"id":false}}`, `{"properties":{"id":true}}`, true},
+		{`{"properties":{"id":true}}`, `{"properties":{"id":false}}`, false},
+		{`{"items":false}`, `{"items":{"type":"string"}}`, true},
+
+		// Limits and pattern.
+		{`{"minimum":1}`, `{"minimum":0}`, true},
+		{`{"minimum":1}`, `{"minimum":2}`, false},
+		{`{"maxLength":3}`, `{"maxLength":2}`, false},
+		{`{}`, `{"pattern":"^a"}`, false},
+		{`{"pattern":"^a"}`, `{"pattern":"^a"}`, true},
+		{`{"items":{"type":"integer"}}`, `{"items":{"type":"number"}}`, true},
+		{`{}`, `{"items":{"type":"number"}}`, false},
+	} {
+		old, err := NewSchema(c.old)
+		if err != nil {
+			t.Fatalf("case %d: parse old error: %v", i, err)
+		}
+		s, err := NewSchema(c.schema)
+		if err != nil {
+			t.Fatalf("case %d: parse error: %v", i, err)
+		}
+		if err = s.Compatible(old); (err == nil) != c.ok {
+			t.Fatalf("case %d: old=%s, schema=%s, ok expected=%v, error=%v", i, c.old, c.schema, c.ok, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package base
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	// Sequencer
+	// instance of ordered delivery manager.
+	Sequencer SequencerManager
+)
+
+type (
+	// SequencerManager
+	// interface of ordered delivery manager.
+	//
+	// Messages with the same key of task are delivered one at a time
+	// in arrived order, different keys are not blocked each other.
+	SequencerManager interface {
+		// Acquire
+		// block until previous message of the key released.
+		Acquire(t *Task, key string)
+
+		// Release
+		// wake up next message of the key.
+		Release(t *Task, key string)
+	}
+
+	sequencer struct {
+		mu       *sync.Mutex
+		sequence map[string]*sequence
+	}
+
+	sequence struct {
+		ch      chan bool
+		waiting int
+	}
+)
+
+func (o *sequencer) Acquire(t *Task, key string) {
+	o.get(o.name(t, key)).ch <- true
+}
+
+func (o *sequencer) Release(t *Task, key string) {
+	name := o.name(t, key)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if x, ok := o.sequence[name]; ok {
+		<-x.ch
+
+		// Remove
+		// if no message waiting.
+		if x.waiting--; x.waiting == 0 {
+			delete(o.sequence, name)
+		}
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+// Get sequence
+// and increment waiting count. Channel of sequence is buffered with
+// one, blocked senders are woken up in first-in-first-out order.
+func (o *sequencer) get(name string) *sequence {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	x, ok := o.sequence[name]
+	if !ok {
+		x = &sequence{ch: make(chan bool, 1)}
+		o.sequence[name] = x
+	}
+	x.waiting++
+	return x
+}
+
+func (o *sequencer) init() *sequencer {
+	o.mu = &sync.Mutex{}
+	o.sequence = make(map[string]*sequence)
+	return o
+}
+
+func (o *sequencer) name(t *Task, key string) string {
+	return fmt.Sprintf("%d:%s", t.Id, key)
+}
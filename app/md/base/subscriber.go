@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type (
@@ -80,6 +81,12 @@ type (
 		IgnoreCodes  []string
 		Protocol     SubscriberProtocol
 		ResponseType SubscriberResponseType
+
+		Balance   SubscriberBalance
+		Endpoints []*SubscriberEndpoint
+
+		mu     *sync.Mutex
+		offset uint64
 	}
 )
 
@@ -92,21 +99,21 @@ func NewSubscriber(m *models.Task, t SubscriberType) (s *Subscriber) {
 			s = (&Subscriber{
 				Addr: m.Handler, Method: m.HandlerMethod, Timeout: m.HandlerTimeout,
 				ResponseType: SubscriberResponseType(m.HandlerResponseType),
-			}).init(m.HandlerCondition, m.HandlerIgnoreCodes)
+			}).init(m.HandlerCondition, m.HandlerIgnoreCodes).initEndpoints(m.HandlerEndpoints, m.HandlerBalance)
 		}
 	case SubscriberTypeFailed:
 		if m.Failed != "" {
 			s = (&Subscriber{
 				Addr: m.Failed, Method: m.FailedMethod, Timeout: m.FailedTimeout,
 				ResponseType: SubscriberResponseType(m.FailedResponseType),
-			}).init(m.FailedCondition, m.FailedIgnoreCodes)
+			}).init(m.FailedCondition, m.FailedIgnoreCodes).initEndpoints("", 0)
 		}
 	case SubscriberTypeSucceed:
 		if m.Succeed != "" {
 			s = (&Subscriber{
 				Addr: m.Succeed, Method: m.SucceedMethod, Timeout: m.SucceedTimeout,
 				ResponseType: SubscriberResponseType(m.SucceedResponseType),
-			}).init(m.SucceedCondition, m.SucceedIgnoreCodes)
+			}).init(m.SucceedCondition, m.SucceedIgnoreCodes).initEndpoints("", 0)
 		}
 	}
 
@@ -118,6 +125,8 @@ func NewSubscriber(m *models.Task, t SubscriberType) (s *Subscriber) {
 // /////////////////////////////////////////////////////////////
 
 func (o *Subscriber) init(sc, ic string) *Subscriber {
+	o.mu = &sync.Mutex{}
+
 	// Condition definition.
 	if sc = strings.TrimSpace(sc); sc != "" {
 		o.Condition = (&condition{s: sc}).init()
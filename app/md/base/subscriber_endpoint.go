@@ -0,0 +1,190 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-20
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fuyibing/log/v8"
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	SubscriberBalance int
+)
+
+const (
+	SubscriberBalanceRoundRobin SubscriberBalance = iota
+	SubscriberBalanceWeighted
+	SubscriberBalanceLeastInflight
+)
+
+type (
+	// SubscriberEndpoint
+	// struct for subscription endpoint.
+	SubscriberEndpoint struct {
+		Addr     string             `json:"addr"`
+		Weight   int                `json:"weight"`
+		Protocol SubscriberProtocol `json:"-"`
+
+		current int
+	}
+)
+
+// ParseEndpoints
+// return extra endpoints in json string.
+//
+// Return error if json invalid, address empty or weight not in range
+// 0 to 100. Empty list returned for empty string.
+func ParseEndpoints(str string) ([]*SubscriberEndpoint, error) {
+	list := make([]*SubscriberEndpoint, 0)
+
+	if str = strings.TrimSpace(str); str == "" {
+		return list, nil
+	}
+
+	if err := json.Unmarshal([]byte(str), &list); err != nil {
+		return nil, fmt.Errorf("invalid handler endpoints: %v", err)
+	}
+
+	for i, x := range list {
+		if x == nil {
+			return nil, fmt.Errorf("invalid handler endpoint: index=%d, endpoint is null", i)
+		}
+		if x.Addr = strings.TrimSpace(x.Addr); x.Addr == "" {
+			return nil, fmt.Errorf("invalid handler endpoint: index=%d, address is empty", i)
+		}
+		if x.Weight < 0 || x.Weight > 100 {
+			return nil, fmt.Errorf("invalid handler endpoint: index=%d, weight not in range 0-100", i)
+		}
+	}
+	return list, nil
+}
+
+// Select
+// return endpoint of subscriber for delivering.
+//
+// Endpoints in excludes or ejected by health manager are skipped,
+// ejected endpoints are used only if no healthy endpoint left. Return
+// nil if all endpoints excluded.
+func (o *Subscriber) Select(excludes map[string]bool) *SubscriberEndpoint {
+	var (
+		list = make([]*SubscriberEndpoint, 0, len(o.Endpoints))
+	)
+
+	// Healthy endpoints.
+	for _, x := range o.Endpoints {
+		if !excludes[x.Addr] && Health.Available(x.Addr) {
+			list = append(list, x)
+		}
+	}
+
+	// Fallback to ejected endpoints.
+	if len(list) == 0 {
+		for _, x := range o.Endpoints {
+			if !excludes[x.Addr] {
+				list = append(list, x)
+			}
+		}
+	}
+
+	// Return nil
+	// if no endpoint left.
+	if len(list) == 0 {
+		return nil
+	}
+
+	// Return endpoint
+	// by balance strategy.
+	switch o.Balance {
+	case SubscriberBalanceWeighted:
+		return o.selectWeighted(list)
+	case SubscriberBalanceLeastInflight:
+		return o.selectLeastInflight(list)
+	}
+	return list[(atomic.AddUint64(&o.offset, 1)-1)%uint64(len(list))]
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *Subscriber) initEndpoints(str string, balance int) *Subscriber {
+	var (
+		err    error
+		list   []*SubscriberEndpoint
+		mapper = make(map[string]*SubscriberEndpoint)
+	)
+
+	o.Balance = SubscriberBalance(balance)
+
+	// Primary endpoint
+	// which configured on handler.
+	o.Endpoints = []*SubscriberEndpoint{{Addr: o.Addr, Protocol: o.Protocol, Weight: 1}}
+	mapper[o.Addr] = o.Endpoints[0]
+
+	// Extra endpoints
+	// in json string, primary endpoint used only if invalid.
+	if list, err = ParseEndpoints(str); err != nil {
+		log.Warnf("subscriber endpoints ignored: addr=%s, %v", o.Addr, err)
+		return o
+	}
+
+	for _, x := range list {
+		x.Protocol, x.Addr = o.parseProtocol(x.Addr)
+		if x.Weight < 1 {
+			x.Weight = 1
+		}
+
+		// Override weight
+		// if endpoint defined already.
+		if e, ok := mapper[x.Addr]; ok {
+			e.Weight = x.Weight
+			continue
+		}
+
+		mapper[x.Addr] = x
+		o.Endpoints = append(o.Endpoints, x)
+	}
+	return o
+}
+
+func (o *Subscriber) parseProtocol(addr string) (SubscriberProtocol, string) {
+	if m := regexSubscriberProtocol.FindStringSubmatch(addr); len(m) == 2 {
+		if v, ok := SubscriberProtocolList[strings.TrimSpace(strings.ToLower(m[1]))]; ok {
+			return v, addr
+		}
+		return SubscriberProtocolHttp, addr
+	}
+	return SubscriberProtocolHttp, fmt.Sprintf("%v://%s", SubscriberProtocolDefault, addr)
+}
+
+func (o *Subscriber) selectLeastInflight(list []*SubscriberEndpoint) (e *SubscriberEndpoint) {
+	var n, min int32
+	for _, x := range list {
+		if n = Health.Inflight(x.Addr); e == nil || n < min {
+			e, min = x, n
+		}
+	}
+	return
+}
+
+// Smooth weighted round-robin.
+func (o *Subscriber) selectWeighted(list []*SubscriberEndpoint) (e *SubscriberEndpoint) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	total := 0
+	for _, x := range list {
+		x.current += x.Weight
+		total += x.Weight
+		if e == nil || x.current > e.current {
+			e = x
+		}
+	}
+
+	e.current -= total
+	return
+}
@@ -0,0 +1,49 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package base
+
+import (
+	"testing"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	for _, c := range []struct {
+		str   string
+		count int
+		fail  bool
+	}{
+		{"", 0, false},
+		{`[{"addr":"http://10.0.0.2/path","weight":3}]`, 1, false},
+		{`[{"addr":"http://10.0.0.2/path"`, 0, true},
+		{`{"addr":"http://10.0.0.2/path"}`, 0, true},
+		{`[{"addr":" ","weight":1}]`, 0, true},
+		{`[{"addr":"http://10.0.0.2/path","weight":101}]`, 0, true},
+		{`[null]`, 0, true},
+	} {
+		list, err := ParseEndpoints(c.str)
+		if c.fail {
+			if err == nil {
+				t.Errorf("ParseEndpoints(%s): error expected", c.str)
+			}
+			continue
+		}
+		if err != nil || len(list) != c.count {
+			t.Errorf("ParseEndpoints(%s): count=%d, error=%v", c.str, len(list), err)
+		}
+	}
+}
+
+func TestSubscriberInitEndpoints(t *testing.T) {
+	s := (&Subscriber{Addr: "http://10.0.0.1/path"}).init("", "").initEndpoints(`[{"addr":"http://10.0.0.1/path","weight":5},{"addr":"http://10.0.0.2/path"}]`, int(SubscriberBalanceWeighted))
+	if len(s.Endpoints) != 2 || s.Endpoints[0].Weight != 5 || s.Endpoints[1].Weight != 1 {
+		t.Fatalf("endpoints not merged: %+v", s.Endpoints)
+	}
+
+	// Invalid json
+	// falls back to primary endpoint.
+	s = (&Subscriber{Addr: "http://10.0.0.1/path"}).init("", "").initEndpoints(`[{`, 0)
+	if len(s.Endpoints) != 1 {
+		t.Fatalf("primary endpoint only expected: %+v", s.Endpoints)
+	}
+}
@@ -18,9 +18,23 @@ type (
 
 		Parallels    int
 		Concurrency  int32
+		Adaptive     bool
 		MaxRetry     int
 		DelaySeconds int
 		Broadcasting bool
+		Ordered      bool
+		Dedupe       bool
+		AckTimeout   int
+
+		MaxAgeSeconds int
+
+		RateLimit int
+		RateBurst int
+
+		GlobalConcurrency int
+		GlobalRateLimit   int
+
+		ConcurrencyMin int32
 
 		RegistryId int
 		TopicName  string
@@ -47,6 +61,61 @@ func (o *Task) IsNotification() bool        { return o.isNotification }
 func (o *Task) IsNotificationFailed() bool  { return o.isNotificationFailed }
 func (o *Task) IsNotificationSucceed() bool { return o.isNotificationSucceed }
 
+// CurrentConcurrency
+// return effective concurrency per consumer.
+//
+// Return concurrency tuned by adaptive manager if adaptive mode
+// enabled, otherwise Concurrency returned.
+func (o *Task) CurrentConcurrency() int32 {
+	return Adaptive.Limit(o)
+}
+
+// DispatchTimeout
+// return maximum seconds of delivering a message.
+//
+// Include failover on all endpoints of handler subscriber.
+func (o *Task) DispatchTimeout() int {
+	if o.HandlerSubscriber == nil || o.isNotification {
+		return conf.Config.Consumer.DispatchTimeout
+	}
+	return o.HandlerSubscriber.Timeout * len(o.HandlerSubscriber.Endpoints)
+}
+
+// Paused
+// return consumption of task should be paused or not.
+//
+// Return true if circuit breaker of handler subscriber opened, token
+// bucket of rate limit is empty or share of global concurrency
+// reached.
+func (o *Task) Paused() bool {
+	if Limiter.Empty(o) || Quota.Busy(o) {
+		return true
+	}
+	return !o.isNotification && o.HandlerSubscriber != nil && Breaker.IsOpen(o.HandlerSubscriber.Addr)
+}
+
+// Rate
+// return rate limit and burst on this node.
+//
+// Share of global rate limit is used if it is less than rate limit
+// of node. Burst is at least 1 if share of global rate limit is less
+// than 1 per second.
+func (o *Task) Rate() (limit float64, burst int) {
+	limit, burst = float64(o.RateLimit), o.RateBurst
+
+	if o.GlobalRateLimit > 0 {
+		if share := Quota.ShareRate(o.GlobalRateLimit); limit == 0 || share < limit {
+			limit = share
+		}
+		if burst == 0 || float64(burst) > limit {
+			if burst = int(limit); burst < 1 {
+				burst = 1
+			}
+		}
+	}
+	return
+}
+
 // /////////////////////////////////////////////////////////////
 // Access methods.
 // /////////////////////////////////////////////////////////////
@@ -64,6 +133,10 @@ func (o *Task) init(m *models.Task) *Task {
 	o.Title = m.Title
 	o.DelaySeconds = m.DelaySeconds
 	o.Broadcasting = m.Broadcasting == models.StatusEnabled
+	o.Ordered = m.Ordered == models.StatusEnabled
+	o.Dedupe = m.Dedupe == models.StatusEnabled
+	o.AckTimeout = m.AckTimeout
+	o.MaxAgeSeconds = m.MaxAgeSeconds
 
 	if o.Parallels = m.Parallels; o.Parallels == 0 {
 		o.Parallels = conf.Config.Consumer.Parallels
@@ -74,6 +147,21 @@ func (o *Task) init(m *models.Task) *Task {
 	if o.MaxRetry = m.MaxRetry; o.MaxRetry == 0 {
 		o.MaxRetry = conf.Config.Consumer.MaxRetry
 	}
+	if o.RateLimit = m.RateLimit; o.RateLimit > 0 {
+		if o.RateBurst = m.RateBurst; o.RateBurst < 1 {
+			o.RateBurst = o.RateLimit
+		}
+	}
+	if o.Adaptive = m.ConcurrencyAdaptive == models.StatusEnabled; o.Adaptive {
+		if o.ConcurrencyMin = m.ConcurrencyMin; o.ConcurrencyMin < 1 {
+			o.ConcurrencyMin = 1
+		}
+		if o.ConcurrencyMin > o.Concurrency {
+			o.ConcurrencyMin = o.Concurrency
+		}
+	}
+	o.GlobalConcurrency = m.GlobalConcurrency
+	o.GlobalRateLimit = m.GlobalRateLimit
 	if n := m.GmtUpdated.Time().Unix(); n > 0 {
 		o.Updated = n
 	}
@@ -16,6 +16,20 @@ var Boot BootManager
 
 type (
 	BootManager interface {
+		// Ack
+		// return ack manager interface.
+		//
+		//   x := md.Boot.Ack()
+		//   x.Done(ctx, bean, true, "")
+		Ack() AckManager
+
+		// Cluster
+		// return cluster manager interface.
+		//
+		//   x := md.Boot.Cluster()
+		//   x.Node()
+		Cluster() ClusterManager
+
 		// Consumer
 		// return consumer manager interface.
 		//
@@ -23,6 +37,20 @@ type (
 		//   x.Container().Flush(ctx)
 		Consumer() ConsumerManager
 
+		// Cron
+		// return cron manager interface.
+		//
+		//   x := md.Boot.Cron()
+		//   x.Poll()
+		Cron() CronManager
+
+		// Outbox
+		// return outbox manager interface.
+		//
+		//   x := md.Boot.Outbox()
+		//   x.Poll()
+		Outbox() OutboxManager
+
 		// Processor
 		// return boot processor interface.
 		//
@@ -50,13 +78,25 @@ type (
 		//   x := md.Boot.Remoter()
 		//   x.Build(task)
 		Remoter() RemoterManager
+
+		// Scheduler
+		// return scheduler manager interface.
+		//
+		//   x := md.Boot.Scheduler()
+		//   x.Poll()
+		Scheduler() SchedulerManager
 	}
 
 	boot struct {
-		consumer ConsumerManager
-		producer ProducerManager
-		retry    RetryManager
-		remoter  RemoterManager
+		ack       AckManager
+		cluster   ClusterManager
+		consumer  ConsumerManager
+		cron      CronManager
+		outbox    OutboxManager
+		producer  ProducerManager
+		retry     RetryManager
+		remoter   RemoterManager
+		scheduler SchedulerManager
 
 		children  []process.Processor
 		processor process.Processor
@@ -67,11 +107,16 @@ type (
 // Interface methods.
 // /////////////////////////////////////////////////////////////
 
+func (o *boot) Ack() AckManager              { return o.ack }
+func (o *boot) Cluster() ClusterManager      { return o.cluster }
 func (o *boot) Consumer() ConsumerManager    { return o.consumer }
+func (o *boot) Cron() CronManager            { return o.cron }
+func (o *boot) Outbox() OutboxManager        { return o.outbox }
 func (o *boot) Processor() process.Processor { return o.processor }
 func (o *boot) Producer() ProducerManager    { return o.producer }
 func (o *boot) Retry() RetryManager          { return o.retry }
 func (o *boot) Remoter() RemoterManager      { return o.remoter }
+func (o *boot) Scheduler() SchedulerManager  { return o.scheduler }
 
 // /////////////////////////////////////////////////////////////
 // Event methods.
@@ -168,17 +213,27 @@ func (o *boot) OnPanic(ctx context.Context, v interface{}) {
 
 func (o *boot) init() *boot {
 	// Prepare child managers.
+	o.ack = (&ack{}).init()
+	o.cluster = (&cluster{}).init()
 	o.consumer = (&consumer{}).init()
+	o.cron = (&cron{}).init()
+	o.outbox = (&outbox{}).init()
 	o.producer = (&producer{}).init()
 	o.retry = (&retry{}).init()
 	o.remoter = (&remoter{}).init()
+	o.scheduler = (&scheduler{}).init()
 
 	// Initialize child processors.
 	o.children = []process.Processor{
+		o.ack.Processor(),
+		o.cluster.Processor(),
 		o.consumer.Processor(),
+		o.cron.Processor(),
+		o.outbox.Processor(),
 		o.producer.Processor(),
 		o.retry.Processor(),
 		o.remoter.Processor(),
+		o.scheduler.Processor(),
 	}
 
 	// Register boot processor event callbacks.
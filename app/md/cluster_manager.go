@@ -0,0 +1,177 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/process"
+	"github.com/google/uuid"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	ClusterManager interface {
+		// Node
+		// return node identify in cluster.
+		Node() string
+
+		// Processor
+		// return cluster processor interface.
+		//
+		//   x := md.Boot.Cluster().Processor()
+		//   x.Start(ctx)
+		Processor() process.Processor
+	}
+
+	cluster struct {
+		node      string
+		processor process.Processor
+	}
+)
+
+// /////////////////////////////////////////////////////////////
+// Interface methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *cluster) Node() string                 { return o.node }
+func (o *cluster) Processor() process.Processor { return o.processor }
+
+// /////////////////////////////////////////////////////////////
+// Event methods.
+// /////////////////////////////////////////////////////////////
+
+// OnAfter
+// called when processor stopped.
+func (o *cluster) OnAfter(_ context.Context) (ignored bool) {
+	log.Debugf("cluster manager: processor stopped")
+	return
+}
+
+// OnBefore
+// called when processor start.
+func (o *cluster) OnBefore(_ context.Context) (ignored bool) {
+	log.Debugf("cluster manager: start processor, node=%s", o.node)
+	return
+}
+
+// OnCallChannel
+// send heartbeat and count alive nodes periodically.
+func (o *cluster) OnCallChannel(ctx context.Context) (ignored bool) {
+	log.Debugf("cluster manager: listen channel signal")
+
+	ticker := time.NewTicker(time.Duration(conf.Config.Cluster.HeartbeatSeconds) * time.Second)
+	defer ticker.Stop()
+
+	// Join cluster
+	// when begin.
+	o.Heartbeat()
+
+	for {
+		select {
+		case <-ticker.C:
+			go o.Heartbeat()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnCallLeave
+// remove node from cluster, other nodes rebalance when next
+// heartbeat.
+func (o *cluster) OnCallLeave(_ context.Context) (ignored bool) {
+	if _, err := services.NewNodeService().DeleteByNode(o.node); err != nil {
+		log.Errorf("cluster manager: leave cluster failed, error=%v", err)
+		return
+	}
+
+	log.Infof("cluster manager: leave cluster, node=%s", o.node)
+	return
+}
+
+// OnPanic
+// called with panic at runtime.
+func (o *cluster) OnPanic(ctx context.Context, v interface{}) {
+	log.Panicfc(ctx, "cluster manager: %v", v)
+}
+
+// /////////////////////////////////////////////////////////////
+// Action methods.
+// /////////////////////////////////////////////////////////////
+
+// Heartbeat
+// refresh heartbeat time of node then count alive nodes.
+func (o *cluster) Heartbeat() {
+	var (
+		err     error
+		list    []*models.Node
+		rank    int
+		service = services.NewNodeService()
+	)
+
+	// Return
+	// if heartbeat failed, last nodes count is kept.
+	if err = service.Heartbeat(o.node); err != nil {
+		log.Errorf("cluster manager: heartbeat failed, error=%v", err)
+		return
+	}
+
+	// Remove
+	// expired nodes.
+	if _, err = service.DeleteExpired(conf.Config.Cluster.ExpireSeconds); err != nil {
+		log.Warnf("cluster manager: delete expired nodes failed, error=%v", err)
+	}
+
+	// Return
+	// if list alive nodes failed.
+	if list, err = service.ListAlive(conf.Config.Cluster.ExpireSeconds); err != nil {
+		log.Errorf("cluster manager: list alive nodes failed, error=%v", err)
+		return
+	}
+
+	// Rank
+	// of this node in alive nodes ordered by name.
+	for i, x := range list {
+		if x.Node == o.node {
+			rank = i
+			break
+		}
+	}
+
+	// Rebalance
+	// if alive nodes changed.
+	if n := len(list); n != base.Quota.Nodes() {
+		log.Infof("cluster manager: alive nodes changed, from=%d, to=%d, rank=%d", base.Quota.Nodes(), n, rank)
+	}
+	base.Quota.SetNodes(len(list), rank)
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *cluster) init() *cluster {
+	host, _ := os.Hostname()
+	o.node = fmt.Sprintf("%s-%s", host, strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))[:8])
+
+	// Register cluster processor event callbacks.
+	o.processor = process.New("cluster manager").After(
+		o.OnAfter,
+	).Before(
+		o.OnBefore,
+	).Callback(
+		o.OnCallChannel,
+		o.OnCallLeave,
+	).Panic(o.OnPanic)
+
+	return o
+}
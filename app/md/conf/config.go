@@ -25,9 +25,13 @@ type (
 		// for connection adapter.
 		Account *AccountConfig `yaml:"account" json:"account"`
 
-		Consumer *ConsumerConfig `yaml:"consumer" json:"consumer"`
-		Producer *ProducerConfig `yaml:"producer" json:"producer"`
-		Retry    *RetryConfig    `yaml:"retry" json:"retry"`
+		Breaker   *BreakerConfig   `yaml:"breaker" json:"breaker"`
+		Cluster   *ClusterConfig   `yaml:"cluster" json:"cluster"`
+		Consumer  *ConsumerConfig  `yaml:"consumer" json:"consumer"`
+		Outbox    *OutboxConfig    `yaml:"outbox" json:"outbox"`
+		Producer  *ProducerConfig  `yaml:"producer" json:"producer"`
+		Retry     *RetryConfig     `yaml:"retry" json:"retry"`
+		Scheduler *SchedulerConfig `yaml:"scheduler" json:"scheduler"`
 	}
 )
 
@@ -79,11 +83,26 @@ func (o *Configuration) initDefaults() {
 	}
 	o.Account.initDefaults()
 
+	if o.Breaker == nil {
+		o.Breaker = (&BreakerConfig{}).init()
+	}
+	o.Breaker.initDefaults()
+
+	if o.Cluster == nil {
+		o.Cluster = (&ClusterConfig{}).init()
+	}
+	o.Cluster.initDefaults()
+
 	if o.Consumer == nil {
 		o.Consumer = (&ConsumerConfig{}).init()
 	}
 	o.Consumer.initDefaults()
 
+	if o.Outbox == nil {
+		o.Outbox = (&OutboxConfig{}).init()
+	}
+	o.Outbox.initDefaults()
+
 	if o.Producer == nil {
 		o.Producer = (&ProducerConfig{}).init()
 	}
@@ -93,4 +112,9 @@ func (o *Configuration) initDefaults() {
 		o.Retry = (&RetryConfig{}).init()
 	}
 	o.Retry.initDefaults()
+
+	if o.Scheduler == nil {
+		o.Scheduler = (&SchedulerConfig{}).init()
+	}
+	o.Scheduler.initDefaults()
 }
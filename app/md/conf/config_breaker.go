@@ -0,0 +1,97 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-20
+
+package conf
+
+type (
+	// BreakerConfig
+	// configurations for circuit breaker of subscriber.
+	BreakerConfig struct {
+		// Enabled
+		// 是否启用熔断.
+		//
+		// 默认: true
+		Enabled *bool `yaml:"enabled" json:"enabled"`
+
+		// ErrorRate
+		// 熔断错误率.
+		//
+		// 统计窗口内投递次数不少于 MinRequests, 且失败率不低于此值时熔
+		// 断.
+		//
+		// 默认: 0.5
+		ErrorRate float64 `yaml:"error-rate" json:"error-rate"`
+
+		// Failures
+		// 熔断连续失败次数.
+		//
+		// 默认: 5
+		Failures int `yaml:"failures" json:"failures"`
+
+		// MinRequests
+		// 统计窗口内最少投递次数.
+		//
+		// 默认: 20
+		MinRequests int `yaml:"min-requests" json:"min-requests"`
+
+		// OpenSeconds
+		// 熔断时长.
+		//
+		// 熔断后, 在指定秒数内暂停消费, 之后进入半开状态并允许探测.
+		//
+		// 默认: 30
+		OpenSeconds int `yaml:"open-seconds" json:"open-seconds"`
+
+		// Probes
+		// 半开状态探测次数.
+		//
+		// 半开状态下允许同时投递的消息数, 全部成功后关闭熔断.
+		//
+		// 默认: 1
+		Probes int `yaml:"probes" json:"probes"`
+
+		// WindowSeconds
+		// 错误率统计窗口.
+		//
+		// 默认: 60
+		WindowSeconds int `yaml:"window-seconds" json:"window-seconds"`
+	}
+)
+
+func (o *BreakerConfig) init() *BreakerConfig {
+	return o
+}
+
+func (o *BreakerConfig) initDefaults() {
+	var (
+		bt = true
+	)
+
+	if o.Enabled == nil {
+		o.Enabled = &bt
+	}
+
+	if o.ErrorRate <= 0 {
+		o.ErrorRate = 0.5
+	}
+
+	if o.Failures == 0 {
+		o.Failures = 5
+	}
+
+	if o.MinRequests == 0 {
+		o.MinRequests = 20
+	}
+
+	if o.OpenSeconds == 0 {
+		o.OpenSeconds = 30
+	}
+
+	if o.Probes == 0 {
+		o.Probes = 1
+	}
+
+	if o.WindowSeconds == 0 {
+		o.WindowSeconds = 60
+	}
+}
@@ -0,0 +1,39 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package conf
+
+type (
+	// ClusterConfig
+	// configurations for cluster manager.
+	ClusterConfig struct {
+		// ExpireSeconds
+		// 节点过期时长.
+		//
+		// 节点在指定秒数内没有心跳, 视为已离开集群, 其它节点重新分配全
+		// 局限额.
+		//
+		// 默认: 30
+		ExpireSeconds int `yaml:"expire-seconds" json:"expire-seconds"`
+
+		// HeartbeatSeconds
+		// 节点心跳间隔.
+		//
+		// 默认: 10
+		HeartbeatSeconds int `yaml:"heartbeat-seconds" json:"heartbeat-seconds"`
+	}
+)
+
+func (o *ClusterConfig) init() *ClusterConfig {
+	return o
+}
+
+func (o *ClusterConfig) initDefaults() {
+	if o.HeartbeatSeconds == 0 {
+		o.HeartbeatSeconds = 10
+	}
+
+	if o.ExpireSeconds == 0 {
+		o.ExpireSeconds = o.HeartbeatSeconds * 3
+	}
+}
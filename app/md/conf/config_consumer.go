@@ -7,10 +7,67 @@ type (
 	// ConsumerConfig
 	// configurations for consumer manager.
 	ConsumerConfig struct {
+		// AdaptiveBackoff
+		// 自适应并发递减系数.
+		//
+		// 投递失败或耗时超出容忍倍数时, 有效并发数乘以此系数.
+		//
+		// 默认: 0.9
+		AdaptiveBackoff float64 `yaml:"adaptive-backoff" json:"adaptive-backoff"`
+
+		// AdaptiveTolerance
+		// 自适应并发耗时容忍倍数.
+		//
+		// 投递耗时超过基准耗时(最低耗时)的指定倍数时, 递减有效并发数.
+		//
+		// 默认: 2
+		AdaptiveTolerance float64 `yaml:"adaptive-tolerance" json:"adaptive-tolerance"`
+
+		// BackpressureMaxSeconds
+		// 订阅方限流最大暂停时长.
+		//
+		// 订阅方返回 429/503 时, 按 Retry-After 暂停投递此任务, 不超
+		// 过此值.
+		//
+		// 默认: 60
+		BackpressureMaxSeconds int `yaml:"backpressure-max-seconds" json:"backpressure-max-seconds"`
+
+		// BackpressureSeconds
+		// 订阅方限流默认暂停时长.
+		//
+		// 订阅方返回 429/503 且未指定 Retry-After 时生效.
+		//
+		// 默认: 5
+		BackpressureSeconds int `yaml:"backpressure-seconds" json:"backpressure-seconds"`
+
 		Concurrency int32 `yaml:"concurrency" json:"concurrency"`
 
+		// DedupeCacheSize
+		// 去重本地缓存容量.
+		//
+		// 开启去重的订阅任务, 在本地缓存最近投递成功的消息ID, 缓存命中时
+		// 无需查询DB.
+		//
+		// 默认: 10000
+		DedupeCacheSize int `yaml:"dedupe-cache-size" json:"dedupe-cache-size"`
+
 		DispatchTimeout int `yaml:"dispatch-timeout" json:"dispatch-timeout"`
 
+		// EndpointEjectSeconds
+		// 投递地址摘除时长.
+		//
+		// 当投递地址连续失败次数达到 EndpointMaxFails 时, 在指定秒数内
+		// 不再选择此地址.
+		//
+		// 默认: 30
+		EndpointEjectSeconds int `yaml:"endpoint-eject-seconds" json:"endpoint-eject-seconds"`
+
+		// EndpointMaxFails
+		// 投递地址最大连续失败数.
+		//
+		// 默认: 3
+		EndpointMaxFails int32 `yaml:"endpoint-max-fails" json:"endpoint-max-fails"`
+
 		// StoreDispatchFailed
 		// 投递失败消息是否存储.
 		StoreDispatchFailed *bool `yaml:"store-dispatch-failed" json:"store-dispatch-failed"`
@@ -68,14 +125,42 @@ func (o *ConsumerConfig) initDefaults() {
 		bt = true
 	)
 
+	if o.AdaptiveBackoff <= 0 || o.AdaptiveBackoff >= 1 {
+		o.AdaptiveBackoff = 0.9
+	}
+
+	if o.AdaptiveTolerance <= 1 {
+		o.AdaptiveTolerance = 2
+	}
+
+	if o.BackpressureMaxSeconds == 0 {
+		o.BackpressureMaxSeconds = 60
+	}
+
+	if o.BackpressureSeconds == 0 {
+		o.BackpressureSeconds = 5
+	}
+
 	if o.Concurrency == 0 {
 		o.Concurrency = 10
 	}
 
+	if o.DedupeCacheSize == 0 {
+		o.DedupeCacheSize = 10000
+	}
+
 	if o.DispatchTimeout == 0 {
 		o.DispatchTimeout = 10
 	}
 
+	if o.EndpointEjectSeconds == 0 {
+		o.EndpointEjectSeconds = 30
+	}
+
+	if o.EndpointMaxFails == 0 {
+		o.EndpointMaxFails = 3
+	}
+
 	if o.StoreDispatchFailed == nil {
 		o.StoreDispatchFailed = &bt
 	}
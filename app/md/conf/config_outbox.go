@@ -0,0 +1,81 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-27
+
+package conf
+
+type (
+	// OutboxConfig
+	// configurations for outbox manager.
+	OutboxConfig struct {
+		// Connection
+		// 发件箱数据库连接.
+		//
+		// 对应db.yaml中databases下的连接名称, 业务服务在自己的事务中写入
+		// 发件箱表, 由GMD轮询并发布. 为空时不开启.
+		//
+		// 默认: 空
+		Connection string `yaml:"connection" json:"connection"`
+
+		// Count
+		// 单次认领数量.
+		//
+		// 每次轮询最多认领的发件箱记录数量.
+		//
+		// 默认: 100
+		Count int `yaml:"count" json:"count"`
+
+		// LeaseSeconds
+		// 认领租约时长.
+		//
+		// 节点认领的记录在指定秒数内未完成发布, 其它节点可以重新认领.
+		//
+		// 默认: 60
+		LeaseSeconds int `yaml:"lease-seconds" json:"lease-seconds"`
+
+		// MaxRetry
+		// 最大重试次数.
+		//
+		// 发布失败达到此次数后标记为失败, 相同聚合键的后续记录继续发布.
+		//
+		// 默认: 5
+		MaxRetry int `yaml:"max-retry" json:"max-retry"`
+
+		// PollSeconds
+		// 轮询间隔.
+		//
+		// 默认: 1
+		PollSeconds int `yaml:"poll-seconds" json:"poll-seconds"`
+
+		// Table
+		// 发件箱表名.
+		//
+		// 默认: gmd_outbox
+		Table string `yaml:"table" json:"table"`
+	}
+)
+
+func (o *OutboxConfig) init() *OutboxConfig {
+	return o
+}
+
+func (o *OutboxConfig) initDefaults() {
+	if o.Count == 0 {
+		o.Count = 100
+	}
+
+	if o.LeaseSeconds == 0 {
+		o.LeaseSeconds = 60
+	}
+
+	if o.MaxRetry == 0 {
+		o.MaxRetry = 5
+	}
+
+	if o.PollSeconds == 0 {
+		o.PollSeconds = 1
+	}
+
+	if o.Table == "" {
+		o.Table = "gmd_outbox"
+	}
+}
@@ -20,6 +20,88 @@ type (
 		// 默认: 30000
 		BucketSize int `yaml:"bucket-size" json:"bucket-size"`
 
+		// BucketPersistent
+		// 数据桶持久化.
+		//
+		// 开启后, 消息在返回发布成功前写入payload表, 节点重启或崩溃后,
+		// 数据桶中未发送的消息由重试管理器继续发布.
+		//
+		// 默认: false
+		BucketPersistent bool `yaml:"bucket-persistent" json:"bucket-persistent"`
+
+		// BucketOverflow
+		// 数据桶溢出策略.
+		//
+		// 数据桶已满或注册关系超出配额时的处理方式, 可选值:
+		// reject: 拒绝发布, 返回HTTP 429.
+		// block: 等待数据桶空闲, 超过BucketBlockTimeout后拒绝.
+		// spill: 写入payload表等待状态, 由重试管理器发布.
+		//
+		// 默认: reject
+		BucketOverflow Overflow `yaml:"bucket-overflow" json:"bucket-overflow"`
+
+		// BucketBlockTimeout
+		// 数据桶等待时长.
+		//
+		// 溢出策略为block时, 最多等待多少毫秒.
+		//
+		// 默认: 1000
+		BucketBlockTimeout int `yaml:"bucket-block-timeout" json:"bucket-block-timeout"`
+
+		// BucketRegistryShare
+		// 注册关系配额.
+		//
+		// 每个注册关系最多占用数据桶百分之多少, 避免一个发布方占满数据桶
+		// 导致其它主题无法发布.
+		//
+		// 默认: 100
+		BucketRegistryShare int `yaml:"bucket-registry-share" json:"bucket-registry-share"`
+
+		// BucketRetryAfter
+		// 拒绝后重试间隔.
+		//
+		// 因数据桶溢出拒绝发布时, 通过Retry-After头通知发布方多少秒后重试.
+		//
+		// 默认: 1
+		BucketRetryAfter int `yaml:"bucket-retry-after" json:"bucket-retry-after"`
+
+		// BucketWeights
+		// 优先级通道权重.
+		//
+		// 数据桶按high, normal, low三个通道存放消息, 按权重轮流取出, 避免
+		// 大批量回补消息延误紧急消息, 同时低优先级消息不会饿死.
+		//
+		// 默认: [6, 3, 1]
+		BucketWeights []int `yaml:"bucket-weights" json:"bucket-weights"`
+
+		// BatchSize
+		// 批量发布数量.
+		//
+		// 适配器支持批量发布时(如: rocketmq), 每次从数据桶中最多取出多少条
+		// 消息, 相同主题的消息通过一次请求发布.
+		//
+		// 默认: 32
+		BatchSize int `yaml:"batch-size" json:"batch-size"`
+
+		// CompressCodec
+		// 消息压缩算法.
+		//
+		// 消息内容超过CompressThreshold时压缩后发布, 压缩算法记录在消息
+		// 属性中, 消费者在条件过滤与投递前解压, 订阅方收到原始内容. 仅
+		// 支持自定义消息属性的适配器(如: rocketmq)压缩, aliyunmns 按原始
+		// 内容发布. 可选值: gzip, zstd, none(不压缩).
+		//
+		// 默认: none
+		CompressCodec Codec `yaml:"compress-codec" json:"compress-codec"`
+
+		// CompressThreshold
+		// 消息压缩阈值.
+		//
+		// 消息内容达到多少字节时压缩, 压缩后未变小则按原始内容发布.
+		//
+		// 默认: 4096
+		CompressThreshold int `yaml:"compress-threshold" json:"compress-threshold"`
+
 		// Concurrency
 		// 生产者最大并发.
 		//
@@ -29,6 +111,15 @@ type (
 		// 默认: 100
 		Concurrency int32 `yaml:"concurrency" json:"concurrency"`
 
+		// IdempotencySeconds
+		// 幂等窗口时长.
+		//
+		// 发布时指定幂等键(idempotency_key)后, 在此时长(秒)内重复发布
+		// 相同键的消息, 不再发布并返回首次发布的哈希与消息ID.
+		//
+		// 默认: 86400
+		IdempotencySeconds int `yaml:"idempotency-seconds" json:"idempotency-seconds"`
+
 		// MaxRetry
 		// 最大重试次数.
 		//
@@ -62,10 +153,50 @@ func (o *ProducerConfig) initDefaults() {
 		o.BucketSize = 30000
 	}
 
+	switch o.BucketOverflow {
+	case OverflowBlock, OverflowReject, OverflowSpill:
+	default:
+		o.BucketOverflow = OverflowReject
+	}
+
+	if o.BucketBlockTimeout == 0 {
+		o.BucketBlockTimeout = 1000
+	}
+
+	if o.BucketRegistryShare <= 0 || o.BucketRegistryShare > 100 {
+		o.BucketRegistryShare = 100
+	}
+
+	if o.BucketRetryAfter == 0 {
+		o.BucketRetryAfter = 1
+	}
+
+	if len(o.BucketWeights) != 3 || o.BucketWeights[0] <= 0 || o.BucketWeights[1] <= 0 || o.BucketWeights[2] <= 0 {
+		o.BucketWeights = []int{6, 3, 1}
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = 32
+	}
+
+	switch o.CompressCodec {
+	case CodecGzip, CodecNone, CodecZstd:
+	default:
+		o.CompressCodec = CodecNone
+	}
+
+	if o.CompressThreshold <= 0 {
+		o.CompressThreshold = 4096
+	}
+
 	if o.Concurrency == 0 {
 		o.Concurrency = 100
 	}
 
+	if o.IdempotencySeconds == 0 {
+		o.IdempotencySeconds = 86400
+	}
+
 	if o.MaxRetry == 0 {
 		o.MaxRetry = 5
 	}
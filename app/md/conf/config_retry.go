@@ -7,6 +7,9 @@ type (
 	// RetryConfig
 	// configurations for retry manager.
 	RetryConfig struct {
+		AckCount   int
+		AckSeconds int
+
 		MessageCount   int
 		MessageSeconds int
 
@@ -20,6 +23,12 @@ func (o *RetryConfig) init() *RetryConfig {
 }
 
 func (o *RetryConfig) initDefaults() {
+	if o.AckCount == 0 {
+		o.AckCount = 10
+	}
+	if o.AckSeconds == 0 {
+		o.AckSeconds = 10
+	}
 	if o.MessageCount == 0 {
 		o.MessageCount = 10
 	}
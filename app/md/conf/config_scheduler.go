@@ -0,0 +1,51 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-24
+
+package conf
+
+type (
+	// SchedulerConfig
+	// configurations for scheduler and cron manager.
+	SchedulerConfig struct {
+		// Count
+		// 单次认领数量.
+		//
+		// 每次轮询最多认领的到期投递记录(或定时发布)数量.
+		//
+		// 默认: 100
+		Count int `yaml:"count" json:"count"`
+
+		// LeaseSeconds
+		// 认领租约时长.
+		//
+		// 节点认领的投递记录(或定时发布)在指定秒数内未完成发布, 视为节点
+		// 已失效, 其它节点可以重新认领.
+		//
+		// 默认: 60
+		LeaseSeconds int `yaml:"lease-seconds" json:"lease-seconds"`
+
+		// PollSeconds
+		// 轮询间隔.
+		//
+		// 默认: 5
+		PollSeconds int `yaml:"poll-seconds" json:"poll-seconds"`
+	}
+)
+
+func (o *SchedulerConfig) init() *SchedulerConfig {
+	return o
+}
+
+func (o *SchedulerConfig) initDefaults() {
+	if o.Count == 0 {
+		o.Count = 100
+	}
+
+	if o.PollSeconds == 0 {
+		o.PollSeconds = 5
+	}
+
+	if o.LeaseSeconds == 0 {
+		o.LeaseSeconds = 60
+	}
+}
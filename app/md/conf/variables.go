@@ -8,7 +8,9 @@ import (
 )
 
 const (
-	EventSleepDuration = time.Millisecond * 10
+	EventSleepDuration  = time.Millisecond * 10
+	PausedSleepDuration = time.Millisecond * 200
+	RateLimitMaxWait    = time.Second * 3
 )
 
 type Adapter string
@@ -18,3 +20,19 @@ const (
 	Rabbitmq  Adapter = "rabbitmq"
 	Rocketmq  Adapter = "rocketmq"
 )
+
+type Overflow string
+
+const (
+	OverflowBlock  Overflow = "block"
+	OverflowReject Overflow = "reject"
+	OverflowSpill  Overflow = "spill"
+)
+
+type Codec string
+
+const (
+	CodecGzip Codec = "gzip"
+	CodecNone Codec = "none"
+	CodecZstd Codec = "zstd"
+)
@@ -5,6 +5,7 @@ package md
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/fuyibing/gmd/app"
 	"github.com/fuyibing/gmd/app/md/base"
@@ -25,7 +26,19 @@ type (
 		// - Dispatch message
 		// - Send notification if enabled.
 		// - Store received message
-		Do(t *base.Task, m *base.Message) (retry bool)
+		//
+		// Delay is greater than zero if message deferred, adapter
+		// should redeliver message after it and deferral must not be
+		// counted as failure of message dequeue.
+		Do(t *base.Task, m *base.Message) (retry bool, delay time.Duration)
+
+		// DoNotify
+		// send notification of message.
+		DoNotify(m *base.Message, topic, tag string)
+
+		// Expired
+		// return count of expired messages since started.
+		Expired() int64
 
 		// IsIdle
 		// return idle status.
@@ -36,25 +49,62 @@ type (
 
 	worker struct {
 		consuming, notifying, releasing int32
+		expired                         int64
 	}
 )
 
 // Do
 // worker process.
-func (o *worker) Do(t *base.Task, m *base.Message) (retry bool) {
+func (o *worker) Do(t *base.Task, m *base.Message) (retry bool, delay time.Duration) {
 	var ignored bool
 
 	if m.TaskId == 0 {
 		m.TaskId = t.Id
 	}
 
+	// Decompress
+	// message body with codec of message property before claimed,
+	// so claimed, stored and retried message has original body.
+	// Failed immediately as the same body never decompressed in retry.
+	body, err := base.Decompress(m.Codec, m.MessageBody)
+	if err != nil {
+		m.SetError(err)
+		log.Errorfc(m.GetContext(), "consumer worker: decompress failed, message-id=%s, error=%v", m.MessageId, err)
+		o.DoRelease(m)
+		return
+	}
+	m.Codec, m.MessageBody = conf.CodecNone, body
+
+	// Wait previous message
+	// of the same key delivered if ordered.
+	if t.Ordered && m.Keyword != "" {
+		base.Sequencer.Acquire(t, m.Keyword)
+		defer base.Sequencer.Release(t, m.Keyword)
+	}
+
+	// Skip message
+	// if delivered already or delivering on other node, message
+	// loaded from database is claimed already.
+	if t.Dedupe && !m.GetStored() && !base.Dedupe.Claim(t, m) {
+		m.SetDuplicated(true)
+		o.DoRelease(m)
+		return
+	}
+
 	// Consume message
 	// in sync coroutine.
 	ignored, retry = o.DoConsume(t, m)
 
+	// Remember
+	// delivered message for duplicate check.
+	if t.Dedupe && !retry && !m.GetAccepted() && m.GetError() == nil {
+		base.Dedupe.Done(t, m)
+	}
+
 	// Send notification
-	// in async coroutine if enabled and message not ignored.
-	if !ignored && !retry {
+	// in async coroutine if enabled and message not ignored, it is
+	// sent when acknowledged if message accepted.
+	if !ignored && !retry && !m.GetAccepted() {
 		if m.GetError() != nil {
 			if t.EnNotificationFailed() {
 				o.DoNotify(m, conf.Config.Producer.NotificationTopic, conf.Config.Producer.NotificationTagFailed)
@@ -66,12 +116,24 @@ func (o *worker) Do(t *base.Task, m *base.Message) (retry bool) {
 		}
 	}
 
+	// Read
+	// deferred duration before release.
+	if retry {
+		delay = m.GetDeferred()
+	}
+
 	// Call
 	// release process.
 	o.DoRelease(m)
 	return
 }
 
+// Expired
+// return count of expired messages.
+func (o *worker) Expired() int64 {
+	return atomic.LoadInt64(&o.expired)
+}
+
 // IsIdle
 // return busy status.
 func (o *worker) IsIdle() bool {
@@ -104,10 +166,20 @@ func (o *worker) DoConsume(t *base.Task, m *base.Message) (ignored, retry bool)
 		m.SetIgnored(ignored)
 
 		// Execute
-		// retry status.
-		retry = !ignored && err != nil && m.Dequeue < t.MaxRetry
+		// retry status, deferred message not counted.
+		retry = !ignored && err != nil && (m.GetDeferred() > 0 || m.Dequeue < t.MaxRetry)
 	}()
 
+	// Skip message
+	// if expired, it is not delivered and stored as expired.
+	if o.isExpired(t, m) {
+		ignored = true
+		m.SetExpired(true)
+		atomic.AddInt64(&o.expired, 1)
+		log.Warnfc(c, "dispatcher skipped: message expired, message-time=%d, expires-at=%d, max-age=%d", m.MessageTime, m.ExpiresAt, t.MaxAgeSeconds)
+		return
+	}
+
 	// Subscriber selector
 	// with task and message.
 	if s, raw, err = o.getSubscriber(c, t, m); err != nil {
@@ -120,6 +192,44 @@ func (o *worker) DoConsume(t *base.Task, m *base.Message) (ignored, retry bool)
 		return
 	}
 
+	// Defer message
+	// if share of global concurrency not acquired in max wait
+	// duration.
+	if !o.waitQuota(t) {
+		err = fmt.Errorf("global concurrency limited on task: %d", t.Id)
+		m.SetDeferred(o.getDeferred(0)).SetError(err)
+		log.Warnfc(c, "dispatcher deferred: %v", err)
+		return
+	}
+	defer base.Quota.Release(t)
+
+	// Defer message
+	// if token of rate limit not reserved in max wait duration.
+	if d, ok := base.Limiter.Reserve(t, conf.RateLimitMaxWait); !ok {
+		err = fmt.Errorf("rate limited on task: %d", t.Id)
+		m.SetDeferred(o.getDeferred(d)).SetError(err)
+		log.Warnfc(c, "dispatcher deferred: %v", err)
+		return
+	} else if d > 0 {
+		log.Infofc(c, "rate limited: wait=%v", d)
+		time.Sleep(d)
+	}
+
+	// Defer message
+	// if circuit breaker of subscriber opened.
+	if !base.Breaker.Allow(s.Addr) {
+		err = fmt.Errorf("circuit breaker opened on: %s", s.Addr)
+		m.SetDeferred(o.getDeferred(base.Breaker.Remaining(s.Addr))).SetError(err)
+		log.Warnfc(c, "dispatcher deferred: %v", err)
+		return
+	}
+
+	// Generate ack token
+	// if asynchronous acknowledgement enabled.
+	if t.AckTimeout > 0 && !t.IsNotification() {
+		m.SetAck(strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")), time.Now().Unix()+int64(t.AckTimeout))
+	}
+
 	// Dispatch process
 	// in sync coroutine.
 	err = o.runDispatcher(c, t, m, s, raw)
@@ -185,8 +295,8 @@ func (o *worker) DoRelease(m *base.Message) {
 // Dispatch methods.
 // /////////////////////////////////////////////////////////////
 
-func (o *worker) dispatchHttp(c context.Context, t *base.Task, m *base.Message, s *base.Subscriber, raw string) (body []byte, err error) {
-	log.Infofc(c, "dispatcher call: type=http, method=%s, addr=%s, timeout=%d", s.Method, s.Addr, s.Timeout)
+func (o *worker) dispatchHttp(c context.Context, t *base.Task, m *base.Message, s *base.Subscriber, e *base.SubscriberEndpoint, raw string) (body []byte, err error) {
+	log.Infofc(c, "dispatcher call: type=http, method=%s, addr=%s, timeout=%d", s.Method, e.Addr, s.Timeout)
 
 	// Acquire
 	// http dispatcher and release when end.
@@ -195,7 +305,7 @@ func (o *worker) dispatchHttp(c context.Context, t *base.Task, m *base.Message,
 
 	// Set request
 	// method and address.
-	x.Request.SetRequestURI(s.Addr)
+	x.Request.SetRequestURI(e.Addr)
 	x.Request.Header.SetMethod(s.Method)
 
 	// Set user agent.
@@ -215,6 +325,12 @@ func (o *worker) dispatchHttp(c context.Context, t *base.Task, m *base.Message,
 		x.Request.Header.Set(k, fmt.Sprintf("%v", v))
 	}
 
+	// Set ack token
+	// if asynchronous acknowledgement enabled.
+	if token := m.GetAckToken(); token != "" {
+		x.Request.Header.Set("X-Gmd-Ack-Token", token)
+	}
+
 	// Set
 	// request body.
 	if raw != "" {
@@ -226,19 +342,19 @@ func (o *worker) dispatchHttp(c context.Context, t *base.Task, m *base.Message,
 	return
 }
 
-func (o *worker) dispatchTcp(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ string) (body []byte, err error) {
+func (o *worker) dispatchTcp(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ *base.SubscriberEndpoint, _ string) (body []byte, err error) {
 	// todo : tcp dispatcher
 	err = fmt.Errorf("tcp dispatcher not support")
 	return
 }
 
-func (o *worker) dispatchRpc(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ string) (body []byte, err error) {
+func (o *worker) dispatchRpc(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ *base.SubscriberEndpoint, _ string) (body []byte, err error) {
 	// todo : rpc dispatcher
 	err = fmt.Errorf("rpc dispatcher not support")
 	return
 }
 
-func (o *worker) dispatchWebsocket(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ string) (body []byte, err error) {
+func (o *worker) dispatchWebsocket(_ context.Context, _ *base.Task, _ *base.Message, _ *base.Subscriber, _ *base.SubscriberEndpoint, _ string) (body []byte, err error) {
 	// todo : websocket dispatcher
 	err = fmt.Errorf("websocket dispatcher not support")
 	return
@@ -258,6 +374,35 @@ func (o *worker) resultErrnoIsZero(body []byte) (code string, err error) {
 // Action methods.
 // /////////////////////////////////////////////////////////////
 
+// Return error
+// counted as failure by circuit breaker and endpoint health. Subscriber
+// responded backpressure is alive and asked to slow down, so nil
+// returned and task is throttled only.
+func (o *worker) failure(err error) error {
+	var bp *dispatchers.BackpressureError
+	if errors.As(err, &bp) {
+		return nil
+	}
+	return err
+}
+
+func (o *worker) getBackpressure(d time.Duration) time.Duration {
+	if d <= 0 {
+		d = time.Duration(conf.Config.Consumer.BackpressureSeconds) * time.Second
+	}
+	if max := time.Duration(conf.Config.Consumer.BackpressureMaxSeconds) * time.Second; d > max {
+		d = max
+	}
+	return o.getDeferred(d)
+}
+
+func (o *worker) getDeferred(d time.Duration) time.Duration {
+	if d < time.Second {
+		return time.Second
+	}
+	return d
+}
+
 func (o *worker) getSubscriber(c context.Context, t *base.Task, m *base.Message) (s *base.Subscriber, raw string, err error) {
 	log.Infofc(c, "get subscriber")
 
@@ -336,6 +481,19 @@ func (o *worker) getSubscriber(c context.Context, t *base.Task, m *base.Message)
 	return
 }
 
+func (o *worker) isExpired(t *base.Task, m *base.Message) bool {
+	now := time.Now()
+
+	if m.ExpiresAt > 0 && now.Unix() >= m.ExpiresAt {
+		return true
+	}
+
+	// Message time
+	// is in milliseconds.
+	return t.MaxAgeSeconds > 0 && m.MessageTime > 0 &&
+		now.UnixMilli()-m.MessageTime >= int64(t.MaxAgeSeconds)*1000
+}
+
 func (o *worker) runCondition(c context.Context, s *base.Subscriber, raw string) (ignored bool, err error) {
 	// Return
 	// if not enabled.
@@ -358,9 +516,12 @@ func (o *worker) runCondition(c context.Context, s *base.Subscriber, raw string)
 
 func (o *worker) runDispatcher(c context.Context, t *base.Task, m *base.Message, s *base.Subscriber, raw string) (err error) {
 	var (
-		body []byte
-		code string
-		ct   = time.Now()
+		body     []byte
+		code     string
+		ct       = time.Now()
+		excludes = make(map[string]bool)
+
+		bp *dispatchers.BackpressureError
 	)
 
 	// Called
@@ -383,24 +544,58 @@ func (o *worker) runDispatcher(c context.Context, t *base.Task, m *base.Message,
 		m.SetBody(body).SetDuration(dur).SetError(err)
 	}()
 
-	// Switch dispatcher
-	// by protocol.
-	switch s.Protocol {
-	case base.SubscriberProtocolHttp:
-		body, err = o.dispatchHttp(c, t, m, s, raw)
-	case base.SubscriberProtocolTcp:
-		body, err = o.dispatchTcp(c, t, m, s, raw)
-	case base.SubscriberProtocolRpc:
-		body, err = o.dispatchRpc(c, t, m, s, raw)
-	case base.SubscriberProtocolWebsocket:
-		body, err = o.dispatchWebsocket(c, t, m, s, raw)
-	default:
-		err = fmt.Errorf("unknown protocol on: %s", s.Addr)
+	// Select endpoint
+	// and failover to next endpoint in same attempt if
+	// delivered failed.
+	for {
+		e := s.Select(excludes)
+
+		// Return error
+		// if no endpoint left.
+		if e == nil {
+			if err == nil {
+				err = fmt.Errorf("endpoint not found on: %s", s.Addr)
+			}
+			break
+		}
+
+		// Break
+		// if delivered succeed or subscriber asked to slow down.
+		if body, err = o.runEndpoint(c, t, m, s, e, raw); err == nil || errors.As(err, &bp) {
+			break
+		}
+
+		excludes[e.Addr] = true
+		log.Warnfc(c, "dispatcher failover: addr=%s, reason=%v", e.Addr, err)
+	}
+
+	// Record
+	// delivered result to adaptive concurrency and circuit breaker,
+	// backpressure is not failure of circuit breaker.
+	base.Adaptive.Observe(t, time.Now().Sub(ct), err)
+	if base.Breaker.Done(s.Addr, o.failure(err)); err != nil {
+		// Defer message and throttle task
+		// if subscriber asked to slow down.
+		if bp != nil {
+			d := o.getBackpressure(bp.RetryAfter)
+			base.Limiter.Throttle(t, d)
+			m.SetDeferred(d)
+			log.Warnfc(c, "dispatcher backpressure: task throttled, duration=%v", d)
+		}
+		return
 	}
 
 	// Return
-	// if error occurred.
-	if err != nil {
+	// if accepted, result is acknowledged later. Record with token
+	// must be stored before return, otherwise acknowledgement sent by
+	// subscriber can not be found, message is redelivered if failed.
+	if m.GetAccepted() {
+		if err = m.StoreAccepted(); err != nil {
+			m.SetAccepted(false)
+			log.Errorfc(c, "dispatcher accepted: store error, ack-token=%s, %v", m.GetAckToken(), err)
+			return
+		}
+		log.Infofc(c, "dispatcher accepted: ack-token=%s", m.GetAckToken())
 		return
 	}
 
@@ -426,6 +621,46 @@ func (o *worker) runDispatcher(c context.Context, t *base.Task, m *base.Message,
 	return
 }
 
+func (o *worker) runEndpoint(c context.Context, t *base.Task, m *base.Message, s *base.Subscriber, e *base.SubscriberEndpoint, raw string) (body []byte, err error) {
+	// Record
+	// in-flight and result of endpoint.
+	base.Health.Acquire(e.Addr)
+	defer func() { base.Health.Release(e.Addr, o.failure(err)) }()
+
+	// Switch dispatcher
+	// by protocol.
+	switch e.Protocol {
+	case base.SubscriberProtocolHttp:
+		body, err = o.dispatchHttp(c, t, m, s, e, raw)
+	case base.SubscriberProtocolTcp:
+		body, err = o.dispatchTcp(c, t, m, s, e, raw)
+	case base.SubscriberProtocolRpc:
+		body, err = o.dispatchRpc(c, t, m, s, e, raw)
+	case base.SubscriberProtocolWebsocket:
+		body, err = o.dispatchWebsocket(c, t, m, s, e, raw)
+	default:
+		err = fmt.Errorf("unknown protocol on: %s", e.Addr)
+	}
+
+	// Accepted
+	// if asynchronous acknowledgement enabled.
+	if m.GetAckToken() != "" && errors.Is(err, dispatchers.ErrAccepted) {
+		m.SetAccepted(true)
+		err = nil
+	}
+	return
+}
+
+func (o *worker) waitQuota(t *base.Task) bool {
+	for ct := time.Now(); !base.Quota.Acquire(t); {
+		if time.Since(ct) >= conf.RateLimitMaxWait {
+			return false
+		}
+		time.Sleep(conf.EventSleepDuration)
+	}
+	return true
+}
+
 // /////////////////////////////////////////////////////////////
 // Constructor methods.
 // /////////////////////////////////////////////////////////////
@@ -0,0 +1,370 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/process"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	CronManager interface {
+		// Poll
+		// claim due crons in database then call publish.
+		//
+		// - Claim due crons:
+		//   UPDATE `cron` SET `lease_node` = ? WHERE `status` = 1 AND `next_at` <= UNIX_TIMESTAMP() LIMIT 100
+		//
+		// - Call:
+		//   x := md.Boot.Cron()
+		//   x.Poll()
+		Poll()
+
+		// Processor
+		// return cron processor interface.
+		//
+		//   x := md.Boot.Cron().Processor()
+		//   x.Start(ctx)
+		Processor() process.Processor
+	}
+
+	cron struct {
+		ch        chan bool
+		doing     bool
+		mu        *sync.RWMutex
+		processor process.Processor
+	}
+)
+
+// /////////////////////////////////////////////////////////////
+// Interface methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *cron) Poll()                        { o.chanPoll() }
+func (o *cron) Processor() process.Processor { return o.processor }
+
+// /////////////////////////////////////////////////////////////
+// Event methods.
+// /////////////////////////////////////////////////////////////
+
+// OnAfter
+// called when processor stopped.
+func (o *cron) OnAfter(_ context.Context) (ignored bool) {
+	log.Debugf("cron manager: processor stopped")
+	return
+}
+
+// OnBefore
+// called when processor start.
+func (o *cron) OnBefore(_ context.Context) (ignored bool) {
+	log.Debugf("cron manager: start processor")
+	return
+}
+
+// OnCallChannel
+// listen channel signal.
+func (o *cron) OnCallChannel(ctx context.Context) (ignored bool) {
+	log.Debugf("cron manager: listen channel signal")
+
+	// Create
+	// channel and ticker.
+	o.ch = make(chan bool)
+	tk := time.NewTicker(time.Duration(conf.Config.Scheduler.PollSeconds) * time.Second)
+
+	// Unset
+	// channel and ticker.
+	defer func() {
+		close(o.ch)
+		o.ch = nil
+
+		tk.Stop()
+		tk = nil
+	}()
+
+	// Range
+	// channel message.
+	for {
+		select {
+		case <-o.ch:
+			go o.CallPoll()
+		case <-tk.C:
+			go o.CallPoll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnPanic
+// called with panic at runtime.
+func (o *cron) OnPanic(ctx context.Context, v interface{}) {
+	log.Panicfc(ctx, "cron manager: %v", v)
+}
+
+// /////////////////////////////////////////////////////////////
+// Actions methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *cron) CallPoll() {
+	// Return
+	// if process is running.
+	if o.lockExists() {
+		return
+	}
+
+	// Lock
+	// when begin.
+	o.lockSet()
+
+	// Unlock
+	// when end.
+	redo := false
+	defer func() {
+		o.lockUnset()
+
+		// Recall
+		// if claimed count reached limit, more due crons are
+		// waiting.
+		if redo {
+			o.CallPoll()
+		}
+	}()
+
+	// Wait
+	// cron publish process.
+	redo = o.SendCrons() >= conf.Config.Scheduler.Count
+}
+
+func (o *cron) SendCron(ctx context.Context, bean *models.Cron, index int) {
+	var (
+		err      error
+		expr     *base.Cron
+		history  int64
+		next     time.Time
+		node     = Boot.Cluster().Node()
+		payload  *base.Payload
+		registry *base.Registry
+		sess     = db.Connector.GetMasterWithContext(ctx)
+		service  = services.NewCronService(sess)
+		hs       = services.NewCronHistoryService(sess)
+	)
+
+	// Called
+	// when end.
+	defer func() {
+		_ = sess.Close()
+
+		// Release payload.
+		if payload != nil {
+			payload.Release()
+		}
+	}()
+
+	// Pause cron
+	// if expression can not be parsed.
+	if expr, err = base.NewCron(bean.Expression, bean.Timezone); err != nil {
+		log.Errorfc(ctx,
+			"cron manager: parse expression failed, bean-id=%d, index=%d, error=%v",
+			bean.Id,
+			index,
+			err,
+		)
+		_, _ = service.SetStatusAsDisabled(bean.Id)
+		_, _ = service.SetNextAt(bean.Id, node, bean.NextAt, 0)
+		return
+	}
+
+	// Return
+	// if add run history failed, cron is claimed again when lease
+	// expired.
+	hash := strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
+	if history, err = hs.AddProcessing(bean.Id, bean.NextAt, node, hash); err != nil {
+		log.Errorfc(ctx,
+			"cron manager: add run history failed, bean-id=%d, index=%d, error=%v",
+			bean.Id,
+			index,
+			err,
+		)
+		return
+	}
+
+	// Advance
+	// next fire time when end. Ticks missed while node down or cron
+	// paused are not fired.
+	defer func() {
+		if next = expr.Next(time.Now()); next.IsZero() {
+			_, _ = service.SetStatusAsDisabled(bean.Id)
+			_, _ = service.SetNextAt(bean.Id, node, bean.NextAt, 0)
+			return
+		}
+		if affects, _ := service.SetNextAt(bean.Id, node, bean.NextAt, next.Unix()); affects == 0 {
+			log.Warnfc(ctx,
+				"cron manager: cron lease lost, bean-id=%d, index=%d",
+				bean.Id,
+				index,
+			)
+		}
+	}()
+
+	// Return
+	// if fire time run already by other node.
+	if history == 0 {
+		log.Infofc(ctx,
+			"cron manager: cron fired by other node, bean-id=%d, index=%d, fire-at=%d",
+			bean.Id,
+			index,
+			bean.NextAt,
+		)
+		return
+	}
+
+	// Return
+	// if registry not found.
+	if registry = base.Memory.GetRegistry(bean.RegistryId); registry == nil {
+		err = fmt.Errorf("registry not found")
+		log.Errorfc(ctx,
+			"cron manager: registry belongs to not found, bean-id=%d, index=%d, registry=%d",
+			bean.Id,
+			index,
+			bean.RegistryId,
+		)
+		_, _ = hs.SetStatusAsFailed(history, err.Error())
+		return
+	}
+
+	// Prepare payload.
+	payload = base.Pool.AcquirePayload().SetContext(ctx)
+	payload.FilterTag = registry.FilterTag
+	payload.Hash = hash
+	payload.MessageBody = bean.MessageBody
+	payload.Offset = 0
+	payload.RegistryId = bean.RegistryId
+	payload.TopicName = registry.TopicName
+	payload.TopicTag = registry.TopicTag
+
+	// Update run history
+	// with publish result. Payload is not stored if publish failed,
+	// failed fire is recorded in history only.
+	if err = Boot.Producer().PublishDirect(payload); err != nil {
+		payload.SetTransient(true)
+		_, _ = hs.SetStatusAsFailed(history, err.Error())
+		return
+	}
+	_, _ = hs.SetStatusAsSucceed(history, payload.GetMessageId())
+}
+
+func (o *cron) SendCrons() (count int) {
+	var (
+		affects int64
+		ctx     context.Context
+		err     error
+		list    []*models.Cron
+		node    = Boot.Cluster().Node()
+		service = services.NewCronService()
+		wg      *sync.WaitGroup
+	)
+
+	// Return
+	// if claim due crons failed or nothing claimed.
+	if affects, err = service.Claim(node, conf.Config.Scheduler.LeaseSeconds, conf.Config.Scheduler.Count); err != nil {
+		log.Errorf("cron manager: claim due cron failed, error=%v", err)
+		return
+	}
+	if affects == 0 {
+		return
+	}
+
+	// Return
+	// if list claimed crons failed, they are claimed again when
+	// lease expired.
+	if list, err = service.ListLeased(node); err != nil {
+		log.Errorf("cron manager: list claimed cron failed, error=%v", err)
+		return
+	}
+
+	// Return
+	// if cron not found.
+	if count = len(list); count == 0 {
+		return
+	}
+
+	// Publish with parallel mode.
+	ctx = log.NewContext()
+	log.Infofc(ctx, "cron manager: claim due crons, count=%d, node=%s", count, node)
+
+	wg = &sync.WaitGroup{}
+	for i0, b0 := range list {
+		wg.Add(1)
+		c0 := log.NewChild(ctx)
+		go func(c1 context.Context, b1 *models.Cron, i1 int) {
+			defer wg.Done()
+			o.SendCron(c1, b1, i1)
+		}(c0, b0, i0)
+	}
+	wg.Wait()
+
+	return
+}
+
+// /////////////////////////////////////////////////////////////
+// Channel send
+// /////////////////////////////////////////////////////////////
+
+func (o *cron) chanPoll() {
+	if o.processor.Healthy() && o.ch != nil {
+		o.ch <- true
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Locker operations
+// /////////////////////////////////////////////////////////////
+
+func (o *cron) lockExists() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.doing
+}
+
+func (o *cron) lockSet() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = true
+}
+
+func (o *cron) lockUnset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = false
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *cron) init() *cron {
+	o.mu = &sync.RWMutex{}
+
+	// Register cron processor event callbacks.
+	o.processor = process.New("cron manager").After(
+		o.OnAfter,
+	).Before(
+		o.OnBefore,
+	).Callback(
+		o.OnCallChannel,
+	).Panic(o.OnPanic)
+
+	return o
+}
@@ -7,10 +7,28 @@ import (
 	"fmt"
 	"github.com/valyala/fasthttp"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	// ErrAccepted
+	// error of subscriber accepted message and acknowledge later.
+	ErrAccepted = fmt.Errorf("HTTP %d %s", http.StatusAccepted, http.StatusText(http.StatusAccepted))
+)
+
 type (
+	// BackpressureError
+	// error of subscriber asked to slow down.
+	//
+	// Returned if response status code is 429 or 503, RetryAfter is
+	// parsed from Retry-After header and zero if not specified.
+	BackpressureError struct {
+		Code       int
+		RetryAfter time.Duration
+	}
+
 	// HttpDispatcher
 	// struct of http dispatcher.
 	HttpDispatcher struct {
@@ -48,8 +66,15 @@ func (o *HttpDispatcher) Run(timeout int) (body []byte, err error) {
 		body = o.Response.Body()
 
 		// Set error
-		// if response status code not matched.
-		if code := o.Response.StatusCode(); code != http.StatusOK {
+		// if response status code not matched, backpressure error
+		// returned if subscriber asked to slow down.
+		switch code := o.Response.StatusCode(); code {
+		case http.StatusOK:
+		case http.StatusAccepted:
+			err = ErrAccepted
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			err = &BackpressureError{Code: code, RetryAfter: o.retryAfter()}
+		default:
 			err = fmt.Errorf("HTTP %d %s", code, http.StatusText(code))
 		}
 	}
@@ -61,6 +86,10 @@ func (o *HttpDispatcher) Run(timeout int) (body []byte, err error) {
 // Access methods.
 // /////////////////////////////////////////////////////////////
 
+func (o *BackpressureError) Error() string {
+	return fmt.Sprintf("HTTP %d %s, retry-after=%v", o.Code, http.StatusText(o.Code), o.RetryAfter)
+}
+
 func (o *HttpDispatcher) after() {
 	fasthttp.ReleaseRequest(o.Request)
 	o.Request = nil
@@ -77,3 +106,27 @@ func (o *HttpDispatcher) before() {
 func (o *HttpDispatcher) init() *HttpDispatcher {
 	return o
 }
+
+// Parse
+// Retry-After header in delay seconds or http date.
+func (o *HttpDispatcher) retryAfter() time.Duration {
+	str := strings.TrimSpace(string(o.Response.Header.Peek("Retry-After")))
+
+	if str == "" {
+		return 0
+	}
+
+	if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+		if n > 0 {
+			return time.Duration(n) * time.Second
+		}
+		return 0
+	}
+
+	if t, err := http.ParseTime(str); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,346 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-27
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/process"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	OutboxManager interface {
+		// Poll
+		// claim waiting records of outbox table in external database
+		// then call publish.
+		//
+		// Records of the same aggregate key are published one by one
+		// in id order, table schema is documented in docs/outbox.sql.
+		//
+		//   x := md.Boot.Outbox()
+		//   x.Poll()
+		Poll()
+
+		// Processor
+		// return outbox processor interface.
+		//
+		//   x := md.Boot.Outbox().Processor()
+		//   x.Start(ctx)
+		Processor() process.Processor
+	}
+
+	outbox struct {
+		ch        chan bool
+		doing     bool
+		mu        *sync.RWMutex
+		processor process.Processor
+	}
+)
+
+// /////////////////////////////////////////////////////////////
+// Interface methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *outbox) Poll()                        { o.chanPoll() }
+func (o *outbox) Processor() process.Processor { return o.processor }
+
+// /////////////////////////////////////////////////////////////
+// Event methods.
+// /////////////////////////////////////////////////////////////
+
+// OnAfter
+// called when processor stopped.
+func (o *outbox) OnAfter(_ context.Context) (ignored bool) {
+	log.Debugf("outbox manager: processor stopped")
+	return
+}
+
+// OnBefore
+// called when processor start.
+func (o *outbox) OnBefore(_ context.Context) (ignored bool) {
+	log.Debugf("outbox manager: start processor")
+	return
+}
+
+// OnCallChannel
+// listen channel signal.
+func (o *outbox) OnCallChannel(ctx context.Context) (ignored bool) {
+	// Wait
+	// until stopped if connection not configured.
+	if conf.Config.Outbox.Connection == "" {
+		log.Debugf("outbox manager: connection not configured")
+		<-ctx.Done()
+		return
+	}
+
+	log.Debugf("outbox manager: listen channel signal, connection=%s, table=%s", conf.Config.Outbox.Connection, conf.Config.Outbox.Table)
+
+	// Create
+	// channel and ticker.
+	o.ch = make(chan bool)
+	tk := time.NewTicker(time.Duration(conf.Config.Outbox.PollSeconds) * time.Second)
+
+	// Unset
+	// channel and ticker.
+	defer func() {
+		close(o.ch)
+		o.ch = nil
+
+		tk.Stop()
+		tk = nil
+	}()
+
+	// Range
+	// channel message.
+	for {
+		select {
+		case <-o.ch:
+			go o.CallPoll()
+		case <-tk.C:
+			go o.CallPoll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnPanic
+// called with panic at runtime.
+func (o *outbox) OnPanic(ctx context.Context, v interface{}) {
+	log.Panicfc(ctx, "outbox manager: %v", v)
+}
+
+// /////////////////////////////////////////////////////////////
+// Actions methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *outbox) CallPoll() {
+	// Return
+	// if process is running.
+	if o.lockExists() {
+		return
+	}
+
+	// Lock
+	// when begin.
+	o.lockSet()
+
+	// Unlock
+	// when end.
+	redo := false
+	defer func() {
+		o.lockUnset()
+
+		// Recall
+		// if listed count reached limit, more records are waiting.
+		if redo {
+			o.CallPoll()
+		}
+	}()
+
+	// Wait
+	// outbox publish process.
+	redo = o.SendOutboxes() >= conf.Config.Outbox.Count
+}
+
+// SendOutbox
+// publish outbox record, then continue with next record of the same
+// aggregate key until failed or no more waiting.
+func (o *outbox) SendOutbox(ctx context.Context, bean *models.Outbox, index int) {
+	var (
+		err     error
+		node    = Boot.Cluster().Node()
+		service = services.NewOutboxService(conf.Config.Outbox.Connection, conf.Config.Outbox.Table)
+	)
+
+	for n := 0; bean != nil && n < conf.Config.Outbox.Count; n++ {
+		// Return
+		// if published already or claimed by other node.
+		if affects, ce := service.Claim(bean.Id, node, conf.Config.Outbox.LeaseSeconds); ce != nil || affects == 0 {
+			if ce != nil {
+				log.Errorfc(ctx, "outbox manager: claim record failed, bean-id=%d, index=%d, error=%v", bean.Id, index, ce)
+			}
+			return
+		}
+
+		// Return
+		// if publish failed, following records of the same key wait
+		// until this one published or failed finally.
+		if !o.send(ctx, service, node, bean, index) || bean.AggregateKey == "" {
+			return
+		}
+
+		// Continue
+		// with next record of the same key.
+		if bean, err = service.GetHead(bean.AggregateKey); err != nil {
+			log.Errorfc(ctx, "outbox manager: read next record failed, index=%d, error=%v", index, err)
+			return
+		}
+	}
+}
+
+func (o *outbox) SendOutboxes() (count int) {
+	var (
+		ctx  context.Context
+		err  error
+		list []*models.Outbox
+		wg   *sync.WaitGroup
+	)
+
+	// Return
+	// if list waiting records failed.
+	if list, err = services.NewOutboxService(conf.Config.Outbox.Connection, conf.Config.Outbox.Table).ListHeads(conf.Config.Outbox.Count); err != nil {
+		log.Errorf("outbox manager: list waiting record failed, error=%v", err)
+		return
+	}
+
+	// Return
+	// if no record waiting.
+	if count = len(list); count == 0 {
+		return
+	}
+
+	// Publish with parallel mode, each aggregate key in one
+	// coroutine.
+	ctx = log.NewContext()
+	log.Infofc(ctx, "outbox manager: list waiting records, count=%d", count)
+
+	wg = &sync.WaitGroup{}
+	for i0, b0 := range list {
+		wg.Add(1)
+		c0 := log.NewChild(ctx)
+		go func(c1 context.Context, b1 *models.Outbox, i1 int) {
+			defer wg.Done()
+			o.SendOutbox(c1, b1, i1)
+		}(c0, b0, i0)
+	}
+	wg.Wait()
+
+	return
+}
+
+// /////////////////////////////////////////////////////////////
+// Channel send
+// /////////////////////////////////////////////////////////////
+
+func (o *outbox) chanPoll() {
+	if o.processor.Healthy() && o.ch != nil {
+		o.ch <- true
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods
+// /////////////////////////////////////////////////////////////
+
+// Publish record
+// and update status, return true if published or failed finally.
+func (o *outbox) send(ctx context.Context, service *services.OutboxService, node string, bean *models.Outbox, index int) bool {
+	var (
+		err      error
+		payload  *base.Payload
+		registry *base.Registry
+	)
+
+	// Called
+	// when end.
+	defer func() {
+		if payload != nil {
+			payload.Release()
+		}
+	}()
+
+	// Publish
+	// if registry found.
+	if registry = base.Memory.GetRegistryByName(bean.TopicName, bean.TopicTag); registry == nil {
+		err = fmt.Errorf("registry not found")
+	} else {
+		payload = base.Pool.AcquirePayload().SetContext(ctx)
+		payload.FilterTag = registry.FilterTag
+		payload.Hash = strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
+		payload.Keyword = bean.AggregateKey
+		payload.MessageBody = bean.MessageBody
+		payload.Offset = 0
+		payload.RegistryId = registry.Id
+		payload.TopicName = registry.TopicName
+		payload.TopicTag = registry.TopicTag
+		err = Boot.Producer().PublishDirect(payload)
+	}
+
+	// Published.
+	if err == nil {
+		if _, ue := service.SetStatusAsSucceed(bean.Id, node, payload.GetMessageId()); ue != nil {
+			log.Errorfc(ctx, "outbox manager: change status as succeed failed, bean-id=%d, index=%d, error=%v", bean.Id, index, ue)
+		}
+		return true
+	}
+
+	// Not stored
+	// if publish failed, outbox record is the only retry source and
+	// keeps order of messages with same aggregate key.
+	if payload != nil {
+		payload.SetTransient(true)
+	}
+
+	// Failed finally
+	// if retry count reached maximum.
+	log.Errorfc(ctx, "outbox manager: publish failed, bean-id=%d, index=%d, retry=%d, error=%v", bean.Id, index, bean.Retry, err)
+	if (bean.Retry + 1) >= conf.Config.Outbox.MaxRetry {
+		_, _ = service.SetStatusAsFailed(bean.Id, node, err.Error())
+		return true
+	}
+
+	_, _ = service.SetStatusAsWaiting(bean.Id, node, err.Error())
+	return false
+}
+
+// /////////////////////////////////////////////////////////////
+// Locker operations
+// /////////////////////////////////////////////////////////////
+
+func (o *outbox) lockExists() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.doing
+}
+
+func (o *outbox) lockSet() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = true
+}
+
+func (o *outbox) lockUnset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = false
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *outbox) init() *outbox {
+	o.mu = &sync.RWMutex{}
+
+	// Register outbox processor event callbacks.
+	o.processor = process.New("outbox manager").After(
+		o.OnAfter,
+	).Before(
+		o.OnBefore,
+	).Callback(
+		o.OnCallChannel,
+	).Panic(o.OnPanic)
+
+	return o
+}
@@ -4,13 +4,38 @@
 package md
 
 import (
-	"fmt"
+	"errors"
 	"github.com/fuyibing/gmd/app/md/base"
 	"sync"
 )
 
+var (
+	// ErrBucketFull
+	// returned if payloads count of bucket reached size.
+	ErrBucketFull = errors.New("bucket is full")
+
+	// ErrBucketQuota
+	// returned if payloads count of registry reached quota.
+	ErrBucketQuota = errors.New("bucket quota of registry exceeded")
+)
+
+// IsOverflow
+// return true if error returned for bucket full or quota of registry
+// exceeded.
+func IsOverflow(err error) bool {
+	return errors.Is(err, ErrBucketFull) || errors.Is(err, ErrBucketQuota)
+}
+
 type (
 	ProducerBucket interface {
+		// Acceptable
+		// return error if specified count payloads of registry can not
+		// be pushed.
+		//
+		// Return ErrBucketFull if bucket is full, ErrBucketQuota if
+		// registry used up its share of bucket.
+		Acceptable(registryId, n int) error
+
 		// IsEmpty
 		// return bucket is empty or not.
 		//
@@ -29,26 +54,44 @@ type (
 		// return payloads count in bucket.
 		Length() (count int)
 
+		// Lengths
+		// return payloads count of each priority lane.
+		//
+		//   map[string]int{"high": 0, "normal": 30, "low": 2000}
+		Lengths() (counts map[string]int)
+
 		// Pop
 		// get one payload from left cached.
 		Pop() (payload *base.Payload)
 
 		// Popn
-		// get specified count payloads from left cached.
+		// get specified count payloads from left cached. Lanes are
+		// drained by weights, payloads of the same lane in FIFO.
 		Popn(n int) (list []*base.Payload, count int)
 
 		// Push
-		// add payload to right cached.
+		// add payload to right cached of priority lane.
 		Push(ps ...*base.Payload) error
 	}
 
 	bucket struct {
-		cached []*base.Payload
-		mu     *sync.Mutex
-		size   int
+		counts             map[int]int
+		cursor, length     int
+		lanes              [][]*base.Payload
+		mu                 *sync.Mutex
+		quota, share, size int
+		sequence, weights  []int
 	}
 )
 
+// Acceptable
+// return error if payloads can not be pushed.
+func (o *bucket) Acceptable(registryId, n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.acceptable(registryId, n)
+}
+
 // IsEmpty
 // return bucket is empty or not.
 func (o *bucket) IsEmpty() bool {
@@ -66,7 +109,20 @@ func (o *bucket) IsFull() bool {
 func (o *bucket) Length() int {
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	return len(o.cached)
+	return o.length
+}
+
+// Lengths
+// return payloads count of each lane.
+func (o *bucket) Lengths() map[string]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	counts := make(map[string]int)
+	for i, p := range base.Priorities {
+		counts[p.String()] = len(o.lanes[i])
+	}
+	return counts
 }
 
 // Pop
@@ -84,22 +140,35 @@ func (o *bucket) Popn(n int) (list []*base.Payload, count int) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	var total int
+	for count < n {
+		var (
+			lane int
+			p    *base.Payload
+		)
 
-	if total = len(o.cached); total == 0 {
-		return
-	}
+		// Break
+		// if all lanes are empty.
+		if lane = o.next(); lane < 0 {
+			break
+		}
 
-	if n >= total {
-		count = total
-		list = o.cached[0:]
-		o.cached = []*base.Payload{}
-		return
-	}
+		// Shift
+		// from left of lane.
+		p = o.lanes[lane][0]
+		if o.lanes[lane] = o.lanes[lane][1:]; len(o.lanes[lane]) == 0 {
+			o.lanes[lane] = make([]*base.Payload, 0)
+		}
+		o.length--
 
-	count = n
-	list = o.cached[0:n]
-	o.cached = o.cached[n:]
+		// Decrease
+		// payloads count of registry.
+		if o.counts[p.RegistryId]--; o.counts[p.RegistryId] <= 0 {
+			delete(o.counts, p.RegistryId)
+		}
+
+		list = append(list, p)
+		count++
+	}
 	return
 }
 
@@ -114,11 +183,32 @@ func (o *bucket) Push(ps ...*base.Payload) error {
 		return nil
 	}
 
-	if (n + len(o.cached)) > o.size {
-		return fmt.Errorf("bucket is full")
+	// Return error
+	// if bucket is full.
+	if (n + o.length) > o.size {
+		return ErrBucketFull
+	}
+
+	// Return error
+	// if quota of registry exceeded.
+	counts := make(map[int]int)
+	for _, p := range ps {
+		counts[p.RegistryId]++
+	}
+	for id, c := range counts {
+		if err := o.acceptable(id, c); err != nil {
+			return err
+		}
 	}
 
-	o.cached = append(o.cached, ps...)
+	for id, c := range counts {
+		o.counts[id] += c
+	}
+	for _, p := range ps {
+		lane := p.Priority.Lane()
+		o.lanes[lane] = append(o.lanes[lane], p)
+	}
+	o.length += n
 	return nil
 }
 
@@ -126,8 +216,81 @@ func (o *bucket) Push(ps ...*base.Payload) error {
 // Access methods.
 // /////////////////////////////////////////////////////////////
 
+func (o *bucket) acceptable(registryId, n int) error {
+	if (n + o.length) > o.size {
+		return ErrBucketFull
+	}
+	if o.quota > 0 && (n+o.counts[registryId]) > o.quota {
+		return ErrBucketQuota
+	}
+	return nil
+}
+
 func (o *bucket) init() *bucket {
-	o.cached = make([]*base.Payload, 0)
+	o.counts = make(map[int]int)
 	o.mu = &sync.Mutex{}
+
+	// Prepare
+	// priority lanes.
+	o.lanes = make([][]*base.Payload, len(base.Priorities))
+	for i := range o.lanes {
+		o.lanes[i] = make([]*base.Payload, 0)
+	}
+
+	// Quota of registry
+	// disabled if share is 100 percent.
+	if o.share > 0 && o.share < 100 {
+		o.quota = o.size * o.share / 100
+	}
+
+	o.initSequence()
 	return o
 }
+
+// Build lane sequence
+// with smooth weighted round-robin, weights 6:3:1 generate
+// H N H H N H L H N H, so low lane is not starved.
+func (o *bucket) initSequence() {
+	var (
+		current = make([]int, len(o.lanes))
+		total   int
+	)
+
+	// Use equal weights
+	// if not specified for each lane.
+	if len(o.weights) != len(o.lanes) {
+		o.weights = make([]int, len(o.lanes))
+		for i := range o.weights {
+			o.weights[i] = 1
+		}
+	}
+	for _, w := range o.weights {
+		total += w
+	}
+
+	o.sequence = make([]int, 0)
+	for n := 0; n < total; n++ {
+		best := 0
+		for i := range current {
+			if current[i] += o.weights[i]; current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		o.sequence = append(o.sequence, best)
+	}
+}
+
+// Return next lane
+// with payloads in weighted sequence, return -1 if all lanes are
+// empty.
+func (o *bucket) next() int {
+	for range o.sequence {
+		lane := o.sequence[o.cursor]
+		o.cursor = (o.cursor + 1) % len(o.sequence)
+		if len(o.lanes[lane]) > 0 {
+			return lane
+		}
+	}
+	return -1
+}
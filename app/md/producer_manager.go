@@ -5,10 +5,13 @@ package md
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/fuyibing/gmd/app/md/adapters"
 	"github.com/fuyibing/gmd/app/md/base"
 	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
 	"github.com/fuyibing/log/v8"
 	"github.com/fuyibing/util/v8/process"
 	"sync"
@@ -16,6 +19,28 @@ import (
 	"time"
 )
 
+var (
+	// ErrSyncDelay
+	// returned in sync mode if delay is longer than adapter supported,
+	// payload is stored by scheduler and message id can not be
+	// returned.
+	ErrSyncDelay = errors.New("delay longer than adapter supported is not accepted in sync mode")
+)
+
+type (
+	// OrderedError
+	// returned by Publish if payload with key failed. Payloads with
+	// key before Index are published, payload of Index failed with Err
+	// and remaining are released without publish.
+	OrderedError struct {
+		Err   error
+		Index int
+	}
+)
+
+func (o *OrderedError) Error() string { return fmt.Sprintf("index=%d: %v", o.Index, o.Err) }
+func (o *OrderedError) Unwrap() error { return o.Err }
+
 type (
 	ProducerManager interface {
 		// Bucket
@@ -35,7 +60,8 @@ type (
 		// PublishDirect
 		// send payload directly in sync coroutine.
 		//
-		// Return when process completed.
+		// Return when process completed, ErrSyncDelay returned if
+		// payload in sync mode delayed longer than adapter supported.
 		PublishDirect(p *base.Payload) (err error)
 	}
 
@@ -55,7 +81,7 @@ type (
 func (o *producer) Bucket() ProducerBucket                    { return o.bucket }
 func (o *producer) Processor() process.Processor              { return o.processor }
 func (o *producer) Publish(ps ...*base.Payload) (err error)   { return o.doChannel(ps...) }
-func (o *producer) PublishDirect(p *base.Payload) (err error) { return o.doSend(p) }
+func (o *producer) PublishDirect(p *base.Payload) (err error) { return o.doSendDirect(p) }
 
 // /////////////////////////////////////////////////////////////
 // Event methods.
@@ -84,7 +110,14 @@ func (o *producer) OnAfterClean(ctx context.Context) (ignored bool) {
 			w.Add(1)
 			go func(p *base.Payload) {
 				defer w.Done()
-				p.SetError(e)
+
+				// Keep stored payload
+				// for retry manager.
+				if p.GetStored() {
+					p.SetRequeued(true)
+				} else {
+					p.SetError(e)
+				}
 				o.doRelease(p)
 			}(x)
 		}
@@ -211,7 +244,58 @@ func (o *producer) OnPanic(ctx context.Context, v interface{}) {
 // Action methods.
 // /////////////////////////////////////////////////////////////
 
+func (o *producer) doAccept(ps ...*base.Payload) (spilled bool, err error) {
+	var (
+		counts   = make(map[int]int)
+		deadline = time.Now().Add(time.Duration(conf.Config.Producer.BucketBlockTimeout) * time.Millisecond)
+	)
+
+	// Count payloads
+	// of registries, payloads with key are counted too, so they are
+	// limited by bucket quota as others.
+	for _, p := range ps {
+		counts[p.RegistryId]++
+	}
+
+	for {
+		// Return
+		// if bucket accept payloads of all registries.
+		if err = o.doAcceptable(counts); err == nil {
+			return
+		}
+
+		switch conf.Config.Producer.BucketOverflow {
+		case conf.OverflowBlock:
+			// Recheck
+			// until timeout.
+			if time.Now().Before(deadline) {
+				time.Sleep(conf.EventSleepDuration)
+				continue
+			}
+		case conf.OverflowSpill:
+			// Store into database
+			// as waiting, published by retry manager.
+			log.Warnf("producer manager: spill %d payloads, reason=%v", len(ps), err)
+			if err = o.doSpill(ps...); err == nil {
+				spilled = true
+			}
+		}
+		return
+	}
+}
+
+func (o *producer) doAcceptable(counts map[int]int) error {
+	for id, n := range counts {
+		if err := o.bucket.Acceptable(id, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (o *producer) doChannel(ps ...*base.Payload) (err error) {
+	var spilled bool
+
 	// Return error
 	// if processor is not healthy.
 	if !o.processor.Healthy() {
@@ -219,16 +303,42 @@ func (o *producer) doChannel(ps ...*base.Payload) (err error) {
 		return
 	}
 
-	// Return error
-	// if bucket is full.
-	if o.bucket.IsFull() {
-		err = fmt.Errorf("bucket is full")
+	// Return
+	// if bucket overflowed and payloads rejected or spilled.
+	if spilled, err = o.doAccept(ps...); err != nil || spilled {
 		return
 	}
 
+	// Publish payloads
+	// with key in caller coroutine one by one, so payloads of the same
+	// key are published in order. Error returned like sync mode and
+	// remaining payloads are released without publish.
+	list := make([]*base.Payload, 0)
+	for i, x := range ps {
+		if x.Keyword == "" {
+			list = append(list, x)
+			continue
+		}
+		if err = o.doSendOrdered(x); err != nil {
+			for _, r := range append(list, ps[i+1:]...) {
+				go o.doRelease(r.SetTransient(true))
+			}
+			err = &OrderedError{Err: err, Index: i}
+			return
+		}
+	}
+
+	// Return error
+	// if store payloads failed, publish is not acknowledged.
+	if conf.Config.Producer.BucketPersistent && len(list) > 0 {
+		if err = o.doStore(list...); err != nil {
+			return
+		}
+	}
+
 	// Send payloads
 	// to channel.
-	for _, x := range ps {
+	for _, x := range list {
 		func(ch chan *base.Payload, p *base.Payload) {
 			// Release
 			// if panic occurred.
@@ -247,7 +357,7 @@ func (o *producer) doChannel(ps ...*base.Payload) (err error) {
 
 func (o *producer) doPop(ctx context.Context) {
 	var (
-		payload    *base.Payload
+		list       []*base.Payload
 		publishing int32
 	)
 
@@ -263,9 +373,11 @@ func (o *producer) doPop(ctx context.Context) {
 		atomic.AddInt32(&o.publishing, -1)
 
 		// Release
-		// if payload received.
-		if payload != nil {
-			go o.doRelease(payload)
+		// if payloads received.
+		if len(list) > 0 {
+			for _, p := range list {
+				go o.doRelease(p)
+			}
 
 			// Recall pop.
 			o.doPop(ctx)
@@ -281,15 +393,17 @@ func (o *producer) doPop(ctx context.Context) {
 
 	// Return
 	// if bucket is empty.
-	if payload = o.bucket.Pop(); payload == nil {
+	if list, _ = o.bucket.Popn(o.batchSize()); len(list) == 0 {
 		return
 	}
 
 	// Send process.
 	if log.Config.DebugOn() {
-		log.Debugfc(payload.GetContext(), "producer manager: pop payload from bucket")
+		for _, p := range list {
+			log.Debugfc(p.GetContext(), "producer manager: pop payload from bucket")
+		}
 	}
-	_ = o.doSend(payload)
+	o.doSendList(list)
 }
 
 func (o *producer) doPush(ctx context.Context, payload *base.Payload) {
@@ -341,12 +455,156 @@ func (o *producer) doSend(p *base.Payload) (err error) {
 		}
 	}()
 
+	// Store into database
+	// if delay is longer than adapter supported, it is published by
+	// scheduler manager when due.
+	if p.DeliverAt > 0 && p.GetDelay() > o.adapter.MaxDelay() {
+		log.Infofc(p.GetContext(), "producer manager: schedule payload, deliver-at=%d", p.DeliverAt)
+		err = Boot.Scheduler().Add(p)
+		return
+	}
+
 	// Publish process.
 	log.Infofc(p.GetContext(), "producer manager: call %s adapter and publish", conf.Config.Adapter)
 	messageId, err = o.adapter.Publish(p)
 	return
 }
 
+func (o *producer) doSendDirect(p *base.Payload) (err error) {
+	// Return error
+	// if delay is longer than adapter supported in sync mode.
+	if p.GetSync() && p.DeliverAt > 0 && p.GetDelay() > o.adapter.MaxDelay() {
+		err = ErrSyncDelay
+		p.SetError(err)
+		return
+	}
+	return o.doSend(p)
+}
+
+func (o *producer) doSendBatch(ba adapters.BatchProducerAdapter, ps []*base.Payload) {
+	var (
+		err        error
+		messageIds []string
+		t          = time.Now()
+	)
+
+	// Called
+	// when end.
+	defer func() {
+		d := time.Now().Sub(t).Seconds()
+
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+			log.Panicfc(ps[0].GetContext(), "%v", err)
+		}
+
+		// Update
+		// publish result of each payload.
+		for i, p := range ps {
+			p.SetError(err).SetDuration(d)
+			if err == nil && i < len(messageIds) {
+				p.SetMessageId(messageIds[i])
+			}
+
+			// Logger result.
+			if err != nil {
+				log.Errorfc(p.GetContext(), "%s adapter: batch=%d, duration=%03f, error=%v", conf.Config.Adapter, len(ps), d, err)
+			} else {
+				log.Infofc(p.GetContext(), "%s adapter: batch=%d, duration=%03f, message-id=%s", conf.Config.Adapter, len(ps), d, p.GetMessageId())
+			}
+		}
+	}()
+
+	// Publish process.
+	log.Infofc(ps[0].GetContext(), "producer manager: call %s adapter and publish %d payloads in batch", conf.Config.Adapter, len(ps))
+	messageIds, err = ba.PublishBatch(ps)
+}
+
+// Send payloads
+// popped from bucket. Payloads of the same topic are published in
+// batch if adapter supported, delayed payloads are sent one by one.
+func (o *producer) doSendList(list []*base.Payload) {
+	var (
+		ba     adapters.BatchProducerAdapter
+		groups = make(map[string][]*base.Payload)
+		ok     bool
+		topics = make([]string, 0)
+	)
+
+	// Send one by one
+	// if adapter not support batch.
+	if ba, ok = o.adapter.(adapters.BatchProducerAdapter); !ok || len(list) == 1 {
+		for _, p := range list {
+			_ = o.doSend(p)
+		}
+		return
+	}
+
+	// Group by topic
+	// in pop order.
+	for _, p := range list {
+		if p.DeliverAt > 0 {
+			_ = o.doSend(p)
+			continue
+		}
+		if _, ok = groups[p.TopicName]; !ok {
+			topics = append(topics, p.TopicName)
+		}
+		groups[p.TopicName] = append(groups[p.TopicName], p)
+	}
+
+	for _, topic := range topics {
+		if ps := groups[topic]; len(ps) == 1 {
+			_ = o.doSend(ps[0])
+		} else {
+			o.doSendBatch(ba, ps)
+		}
+	}
+}
+
+func (o *producer) doSendOrdered(p *base.Payload) (err error) {
+	atomic.AddInt32(&o.publishing, 1)
+	defer atomic.AddInt32(&o.publishing, -1)
+
+	// Publish
+	// as sync mode, failed payload is not retried by retry manager,
+	// error is returned to caller.
+	err = o.doSend(p.SetSync(true))
+	go o.doRelease(p)
+	return
+}
+
+func (o *producer) doSpill(ps ...*base.Payload) (err error) {
+	// Return error
+	// if insert as waiting status failed.
+	if _, err = services.NewPayloadService().AddWaitingList(o.beans(ps...)...); err != nil {
+		log.Errorf("producer manager: spill payload failed, error=%v", err)
+		return
+	}
+
+	// Release
+	// without save.
+	for _, p := range ps {
+		go o.doRelease(p.SetRequeued(true))
+	}
+	return
+}
+
+func (o *producer) doStore(ps ...*base.Payload) (err error) {
+	// Insert
+	// as processing status.
+	if _, err = services.NewPayloadService().AddProcessing(Boot.Cluster().Node(), o.beans(ps...)...); err != nil {
+		log.Errorf("producer manager: store payload failed, error=%v", err)
+		return
+	}
+
+	// Mark stored.
+	for _, p := range ps {
+		p.SetStored(true)
+	}
+	return
+}
+
 func (o *producer) rePop(ctx context.Context) {
 	var (
 		backlog int
@@ -366,9 +624,9 @@ func (o *producer) rePop(ctx context.Context) {
 	}
 
 	// Reset
-	// maximum idle coroutines.
-	if idle > backlog {
-		idle = backlog
+	// maximum idle coroutines, each coroutine pop payloads in batch.
+	if n := (backlog + o.batchSize() - 1) / o.batchSize(); idle > n {
+		idle = n
 	}
 
 	// Call pop
@@ -383,9 +641,40 @@ func (o *producer) rePop(ctx context.Context) {
 // Access methods.
 // /////////////////////////////////////////////////////////////
 
+func (o *producer) batchSize() int {
+	if _, ok := o.adapter.(adapters.BatchProducerAdapter); ok {
+		return conf.Config.Producer.BatchSize
+	}
+	return 1
+}
+
+func (o *producer) beans(ps ...*base.Payload) []*models.Payload {
+	list := make([]*models.Payload, 0)
+	for _, p := range ps {
+		list = append(list, &models.Payload{
+			MessageTaskId:    p.MessageTaskId,
+			MessageMessageId: p.MessageMessageId,
+			Hash:             p.Hash,
+			Offset:           p.Offset,
+			Keyword:          p.Keyword,
+			Priority:         int(p.Priority),
+			IdempotencyKey:   p.IdempotencyKey,
+			DeliverAt:        p.DeliverAt,
+			ExpiresAt:        p.ExpiresAt,
+			RegistryId:       p.RegistryId,
+			MessageBody:      p.MessageBody,
+		})
+	}
+	return list
+}
+
 func (o *producer) init() *producer {
 	// Prepare producer bucket.
-	o.bucket = (&bucket{size: conf.Config.Producer.BucketSize}).init()
+	o.bucket = (&bucket{
+		share:   conf.Config.Producer.BucketRegistryShare,
+		size:    conf.Config.Producer.BucketSize,
+		weights: conf.Config.Producer.BucketWeights,
+	}).init()
 
 	// Register producer processor event callbacks.
 	o.processor = process.New("producer manager").After(
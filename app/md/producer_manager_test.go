@@ -0,0 +1,72 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package md
+
+import (
+	"errors"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/util/v8/process"
+	"testing"
+	"time"
+)
+
+type testAdapter struct {
+	published int
+}
+
+func (o *testAdapter) MaxDelay() time.Duration      { return time.Hour }
+func (o *testAdapter) Processor() process.Processor { return nil }
+func (o *testAdapter) Publish(_ *base.Payload) (string, error) {
+	o.published++
+	return "7F00000100207A4E8E9A1A4C4D3A0001", nil
+}
+
+func TestProducerBeans(t *testing.T) {
+	p := base.Pool.AcquirePayload()
+	p.Hash = "C0837A1B5E264F19826F31457D51546D"
+	p.IdempotencyKey = "order-1"
+	p.Keyword = "user-1"
+	p.Offset = 1
+	p.Priority = base.PriorityLow
+	p.RegistryId = 3
+
+	list := (&producer{}).beans(p)
+	if len(list) != 1 {
+		t.Fatalf("one bean expected: %d", len(list))
+	}
+	if b := list[0]; b.Hash != p.Hash || b.Offset != p.Offset || b.RegistryId != p.RegistryId || b.Keyword != p.Keyword {
+		t.Fatalf("bean fields: hash=%s, offset=%d, registry=%d, key=%s", b.Hash, b.Offset, b.RegistryId, b.Keyword)
+	}
+	if b := list[0]; b.Priority != int(base.PriorityLow) || b.IdempotencyKey != p.IdempotencyKey {
+		t.Fatalf("bean fields: priority=%d, idempotency-key=%s", b.Priority, b.IdempotencyKey)
+	}
+}
+
+func TestProducerSyncDelay(t *testing.T) {
+	var (
+		a = &testAdapter{}
+		o = &producer{adapter: a}
+		p = base.Pool.AcquirePayload().SetSync(true)
+	)
+
+	// Reject
+	// if delayed longer than adapter supported.
+	p.DeliverAt = time.Now().Add(time.Hour * 2).Unix()
+	if err := o.PublishDirect(p); !errors.Is(err, ErrSyncDelay) {
+		t.Fatalf("sync delay error expected: %v", err)
+	}
+	if a.published != 0 {
+		t.Fatalf("payload should not be published")
+	}
+
+	// Publish
+	// if delay supported by adapter.
+	p.SetError(nil).DeliverAt = time.Now().Add(time.Minute * 10).Unix()
+	if err := o.PublishDirect(p); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+	if a.published != 1 || p.GetMessageId() == "" {
+		t.Fatalf("payload should be published with message id: %s", p.GetMessageId())
+	}
+}
@@ -269,15 +269,28 @@ func (o *retry) SendMessage(ctx context.Context, bean *models.Message, index int
 	}
 
 	// Prepare payload.
-	message = base.Pool.AcquireMessage().SetContext(ctx)
+	message = base.Pool.AcquireMessage().SetContext(ctx).SetStored(true)
 	message.Dequeue = bean.MessageDequeue
 	message.MessageBody = bean.MessageBody
 	message.MessageId = bean.MessageId
+	message.Keyword = bean.Keyword
 	message.MessageTime = bean.MessageTime
+	message.ExpiresAt = bean.ExpiresAt
 	message.PayloadMessageId = bean.PayloadMessageId
 	message.TaskId = bean.TaskId
 
-	_ = Boot.Consumer().Container().Worker().Do(task, message)
+	// Reset status as waiting
+	// if message deferred, it is not stored by worker.
+	if _, delay := Boot.Consumer().Container().Worker().Do(task, message); delay > 0 {
+		if _, err = service.SetStatusAsWaiting(bean.Id); err != nil {
+			log.Errorfc(ctx,
+				"retry-manager: change message status as waiting failed, bean-id=%d, index=%d, error=%v",
+				bean.Id,
+				index,
+				err,
+			)
+		}
+	}
 }
 
 func (o *retry) SendMessages() (count int) {
@@ -342,7 +355,7 @@ func (o *retry) SendPayload(ctx context.Context, bean *models.Payload, index int
 
 	// Return error
 	// if change status as processing failed.
-	if affects, err = service.SetStatusAsProcessing(bean.Id); err != nil {
+	if affects, err = service.SetStatusAsProcessing(bean.Id, Boot.Cluster().Node()); err != nil {
 		log.Errorfc(ctx,
 			"retry-manager: change payload status as processing failed, bean-id=%d, index=%d, error=%v",
 			bean.Id,
@@ -390,11 +403,16 @@ func (o *retry) SendPayload(ctx context.Context, bean *models.Payload, index int
 	}
 
 	// Prepare payload.
-	payload = base.Pool.AcquirePayload().SetContext(ctx)
+	payload = base.Pool.AcquirePayload().SetContext(ctx).SetStored(true)
 	payload.FilterTag = registry.FilterTag
 	payload.Hash = bean.Hash
+	payload.IdempotencyKey = bean.IdempotencyKey
+	payload.Keyword = bean.Keyword
+	payload.DeliverAt = bean.DeliverAt
+	payload.ExpiresAt = bean.ExpiresAt
 	payload.MessageBody = bean.MessageBody
 	payload.Offset = bean.Offset
+	payload.Priority = base.Priority(bean.Priority)
 	payload.RegistryId = bean.RegistryId
 	payload.TopicName = registry.TopicName
 	payload.TopicTag = registry.TopicTag
@@ -409,6 +427,14 @@ func (o *retry) SendPayloads() (count int) {
 		wg   *sync.WaitGroup
 	)
 
+	// Recover
+	// processing payloads of nodes left cluster.
+	if n, re := services.NewPayloadService().RecoverOrphans(conf.Config.Cluster.ExpireSeconds); re != nil {
+		log.Errorf("retry manager: recover orphan payload failed, error=%v", re)
+	} else if n > 0 {
+		log.Warnf("retry manager: recover orphan payload, affects=%d", n)
+	}
+
 	// Return
 	// if list waiting payloads failed.
 	if list, err = services.NewPayloadService().ListWaiting(conf.Config.Retry.PayloadCount); err != nil {
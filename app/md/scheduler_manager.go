@@ -0,0 +1,366 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-24
+
+package md
+
+import (
+	"context"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/fuyibing/gmd/app/md/conf"
+	"github.com/fuyibing/gmd/app/models"
+	"github.com/fuyibing/gmd/app/services"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/util/v8/process"
+	"sync"
+	"time"
+)
+
+type (
+	SchedulerManager interface {
+		// Add
+		// store payload into database, it is published by scheduler
+		// manager of any node when due.
+		//
+		//   x := md.Boot.Scheduler()
+		//   x.Add(payload)
+		Add(p *base.Payload) (err error)
+
+		// Poll
+		// claim due schedules in database then call publish.
+		//
+		// - Claim due schedules:
+		//   UPDATE `schedule` SET `status` = 4, `lease_node` = ? WHERE `status` = 3 AND `deliver_at` <= UNIX_TIMESTAMP() LIMIT 100
+		//
+		// - Call:
+		//   x := md.Boot.Scheduler()
+		//   x.Poll()
+		Poll()
+
+		// Processor
+		// return scheduler processor interface.
+		//
+		//   x := md.Boot.Scheduler().Processor()
+		//   x.Start(ctx)
+		Processor() process.Processor
+	}
+
+	scheduler struct {
+		ch        chan bool
+		doing     bool
+		mu        *sync.RWMutex
+		processor process.Processor
+	}
+)
+
+// /////////////////////////////////////////////////////////////
+// Interface methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) Add(p *base.Payload) (err error) { return o.doAdd(p) }
+func (o *scheduler) Poll()                           { o.chanPoll() }
+func (o *scheduler) Processor() process.Processor    { return o.processor }
+
+// /////////////////////////////////////////////////////////////
+// Event methods.
+// /////////////////////////////////////////////////////////////
+
+// OnAfter
+// called when processor stopped.
+func (o *scheduler) OnAfter(_ context.Context) (ignored bool) {
+	log.Debugf("scheduler manager: processor stopped")
+	return
+}
+
+// OnBefore
+// called when processor start.
+func (o *scheduler) OnBefore(_ context.Context) (ignored bool) {
+	log.Debugf("scheduler manager: start processor")
+	return
+}
+
+// OnCallChannel
+// listen channel signal.
+func (o *scheduler) OnCallChannel(ctx context.Context) (ignored bool) {
+	log.Debugf("scheduler manager: listen channel signal")
+
+	// Create
+	// channel and ticker.
+	o.ch = make(chan bool)
+	tk := time.NewTicker(time.Duration(conf.Config.Scheduler.PollSeconds) * time.Second)
+
+	// Unset
+	// channel and ticker.
+	defer func() {
+		close(o.ch)
+		o.ch = nil
+
+		tk.Stop()
+		tk = nil
+	}()
+
+	// Range
+	// channel message.
+	for {
+		select {
+		case <-o.ch:
+			go o.CallPoll()
+		case <-tk.C:
+			go o.CallPoll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnPanic
+// called with panic at runtime.
+func (o *scheduler) OnPanic(ctx context.Context, v interface{}) {
+	log.Panicfc(ctx, "scheduler manager: %v", v)
+}
+
+// /////////////////////////////////////////////////////////////
+// Actions methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) CallPoll() {
+	// Return
+	// if process is running.
+	if o.lockExists() {
+		return
+	}
+
+	// Lock
+	// when begin.
+	o.lockSet()
+
+	// Unlock
+	// when end.
+	redo := false
+	defer func() {
+		o.lockUnset()
+
+		// Recall
+		// if claimed count reached limit, more due schedules are
+		// waiting.
+		if redo {
+			o.CallPoll()
+		}
+	}()
+
+	// Wait
+	// schedule publish process.
+	redo = o.SendSchedules() >= conf.Config.Scheduler.Count
+}
+
+func (o *scheduler) SendSchedule(ctx context.Context, bean *models.Schedule, index int) {
+	var (
+		err      error
+		node     = Boot.Cluster().Node()
+		payload  *base.Payload
+		registry *base.Registry
+		sess     = db.Connector.GetMasterWithContext(ctx)
+		service  = services.NewScheduleService(sess)
+	)
+
+	// Called
+	// when end.
+	defer func() {
+		_ = sess.Close()
+
+		// Release payload.
+		if payload != nil {
+			payload.Release()
+		}
+	}()
+
+	// Return
+	// if registry not found, release lease and it is claimed again
+	// when next poll.
+	if registry = base.Memory.GetRegistry(bean.RegistryId); registry == nil {
+		log.Errorfc(ctx,
+			"scheduler manager: registry belongs to not found, bean-id=%d, index=%d, registry=%d",
+			bean.Id,
+			index,
+			bean.RegistryId,
+		)
+		_, _ = service.SetStatusAsWaiting(bean.Id, node)
+		return
+	}
+
+	// Prepare payload.
+	payload = o.payload(ctx, bean, registry)
+
+	// Release lease
+	// if publish failed, it is published in next round. Payload is not
+	// stored, schedule is the only retry source.
+	if err = Boot.Producer().PublishDirect(payload); err != nil {
+		payload.SetTransient(true)
+		_, _ = service.SetStatusAsWaiting(bean.Id, node)
+		return
+	}
+
+	// Return
+	// if lease taken over by other node, message is published and
+	// other node may publish it again.
+	if affects, _ := service.SetStatusAsSucceed(bean.Id, node, payload.GetMessageId()); affects == 0 {
+		log.Warnfc(ctx,
+			"scheduler manager: schedule lease lost, bean-id=%d, index=%d, message-id=%s",
+			bean.Id,
+			index,
+			payload.GetMessageId(),
+		)
+	}
+}
+
+func (o *scheduler) SendSchedules() (count int) {
+	var (
+		affects int64
+		ctx     context.Context
+		err     error
+		list    []*models.Schedule
+		node    = Boot.Cluster().Node()
+		service = services.NewScheduleService()
+		wg      *sync.WaitGroup
+	)
+
+	// Recover
+	// schedules claimed by other node whose lease expired.
+	if affects, err = service.Recover(); err != nil {
+		log.Errorf("scheduler manager: recover expired schedule failed, error=%v", err)
+	} else if affects > 0 {
+		log.Infof("scheduler manager: recover expired schedules, count=%d", affects)
+	}
+
+	// Return
+	// if claim due schedules failed or nothing claimed.
+	if affects, err = service.Claim(node, conf.Config.Scheduler.LeaseSeconds, conf.Config.Scheduler.Count); err != nil {
+		log.Errorf("scheduler manager: claim due schedule failed, error=%v", err)
+		return
+	}
+	if affects == 0 {
+		return
+	}
+
+	// Return
+	// if list claimed schedules failed, they are recovered when
+	// lease expired.
+	if list, err = service.ListLeased(node); err != nil {
+		log.Errorf("scheduler manager: list claimed schedule failed, error=%v", err)
+		return
+	}
+
+	// Return
+	// if schedule not found.
+	if count = len(list); count == 0 {
+		return
+	}
+
+	// Publish with parallel mode.
+	ctx = log.NewContext()
+	log.Infofc(ctx, "scheduler manager: claim due schedules, count=%d, node=%s", count, node)
+
+	wg = &sync.WaitGroup{}
+	for i0, b0 := range list {
+		wg.Add(1)
+		c0 := log.NewChild(ctx)
+		go func(c1 context.Context, b1 *models.Schedule, i1 int) {
+			defer wg.Done()
+			o.SendSchedule(c1, b1, i1)
+		}(c0, b0, i0)
+	}
+	wg.Wait()
+
+	return
+}
+
+func (o *scheduler) doAdd(p *base.Payload) (err error) {
+	_, err = services.NewScheduleService().AddWaiting(o.bean(p))
+	return
+}
+
+// /////////////////////////////////////////////////////////////
+// Channel send
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) chanPoll() {
+	if o.processor.Healthy() && o.ch != nil {
+		o.ch <- true
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Locker operations
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) lockExists() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.doing
+}
+
+func (o *scheduler) lockSet() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = true
+}
+
+func (o *scheduler) lockUnset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.doing = false
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) bean(p *base.Payload) *models.Schedule {
+	return &models.Schedule{
+		DeliverAt:      p.DeliverAt,
+		ExpiresAt:      p.ExpiresAt,
+		Hash:           p.Hash,
+		Offset:         p.Offset,
+		RegistryId:     p.RegistryId,
+		Keyword:        p.Keyword,
+		Priority:       int(p.Priority),
+		MessageBody:    p.MessageBody,
+		IdempotencyKey: p.IdempotencyKey,
+	}
+}
+
+func (o *scheduler) payload(ctx context.Context, bean *models.Schedule, registry *base.Registry) *base.Payload {
+	p := base.Pool.AcquirePayload().SetContext(ctx)
+	p.DeliverAt = bean.DeliverAt
+	p.ExpiresAt = bean.ExpiresAt
+	p.FilterTag = registry.FilterTag
+	p.Hash = bean.Hash
+	p.IdempotencyKey = bean.IdempotencyKey
+	p.Keyword = bean.Keyword
+	p.MessageBody = bean.MessageBody
+	p.Offset = bean.Offset
+	p.Priority = base.Priority(bean.Priority)
+	p.RegistryId = bean.RegistryId
+	p.TopicName = registry.TopicName
+	p.TopicTag = registry.TopicTag
+	return p
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *scheduler) init() *scheduler {
+	o.mu = &sync.RWMutex{}
+
+	// Register scheduler processor event callbacks.
+	o.processor = process.New("scheduler manager").After(
+		o.OnAfter,
+	).Before(
+		o.OnBefore,
+	).Callback(
+		o.OnCallChannel,
+	).Panic(o.OnPanic)
+
+	return o
+}
@@ -0,0 +1,48 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package md
+
+import (
+	"context"
+	"github.com/fuyibing/gmd/app/md/base"
+	"testing"
+)
+
+func TestSchedulerBeanPayload(t *testing.T) {
+	var (
+		registry = &base.Registry{Id: 3, TopicName: "Topic", TopicTag: "Tag", FilterTag: "Filter"}
+		s        = (&scheduler{}).init()
+		src      = base.Pool.AcquirePayload()
+	)
+
+	src.DeliverAt = 1700000000
+	src.ExpiresAt = 1700003600
+	src.Hash = "C0837A1B5E264F19826F31457D51546D"
+	src.IdempotencyKey = "order-1"
+	src.Keyword = "user-1"
+	src.MessageBody = `{"id":1}`
+	src.Offset = 2
+	src.Priority = base.PriorityHigh
+	src.RegistryId = registry.Id
+
+	// Stored schedule
+	// is published with the same fields.
+	p := s.payload(context.Background(), s.bean(src), registry)
+
+	if p.DeliverAt != src.DeliverAt || p.ExpiresAt != src.ExpiresAt {
+		t.Fatalf("time fields: deliver-at=%d, expires-at=%d", p.DeliverAt, p.ExpiresAt)
+	}
+	if p.Hash != src.Hash || p.Offset != src.Offset || p.RegistryId != src.RegistryId {
+		t.Fatalf("hash fields: hash=%s, offset=%d, registry=%d", p.Hash, p.Offset, p.RegistryId)
+	}
+	if p.IdempotencyKey != src.IdempotencyKey || p.Keyword != src.Keyword || p.MessageBody != src.MessageBody {
+		t.Fatalf("message fields: idempotency-key=%s, key=%s, body=%s", p.IdempotencyKey, p.Keyword, p.MessageBody)
+	}
+	if p.Priority != base.PriorityHigh {
+		t.Fatalf("priority expected high: %s", p.Priority)
+	}
+	if p.TopicName != registry.TopicName || p.TopicTag != registry.TopicTag || p.FilterTag != registry.FilterTag {
+		t.Fatalf("registry fields: topic=%s, tag=%s, filter=%s", p.TopicName, p.TopicTag, p.FilterTag)
+	}
+}
@@ -0,0 +1,64 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package middlewares
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/fuyibing/gmd/app/md/base"
+	"github.com/kataras/iris/v12"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decompress
+//
+// decode request body compressed by publisher, accept gzip
+// and zstd with Content-Encoding header.
+func Decompress(i iris.Context) {
+	var (
+		err    error
+		r      = i.Request()
+		reader io.ReadCloser
+	)
+
+	switch strings.ToLower(i.GetHeader("Content-Encoding")) {
+	case "":
+		i.Next()
+		return
+	case "gzip":
+		reader, err = gzip.NewReader(r.Body)
+	case "zstd":
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(r.Body, zstd.WithDecoderMaxMemory(base.CodecMaxSize)); err == nil {
+			reader = d.IOReadCloser()
+		}
+	default:
+		err = fmt.Errorf("unsupported content encoding")
+	}
+
+	if err != nil {
+		i.StopExecution()
+		ErrSend(i, http.StatusBadRequest, err)
+		return
+	}
+
+	// Limit
+	// decompressed size, json payload over limit is truncated and
+	// rejected by logic.
+	r.Body = &decompressReader{Reader: io.LimitReader(reader, base.CodecMaxSize), closer: reader}
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+
+	i.Next()
+}
+
+type decompressReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (o *decompressReader) Close() error { return o.closer.Close() }
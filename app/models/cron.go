@@ -0,0 +1,44 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package models
+
+type (
+	// Cron
+	//
+	// scheduled publication triggered by cron expression.
+	Cron struct {
+		Id     int    `xorm:"id pk autoincr"`
+		Status int    `xorm:"status"`
+		Title  string `xorm:"title"`
+
+		// Expression
+		// standard 5 fields cron expression.
+		//
+		// Example: */5 9-18 * * 1-5
+		Expression string `xorm:"expression"`
+
+		// Timezone
+		// IANA timezone name of expression.
+		//
+		// Default: UTC
+		Timezone string `xorm:"timezone"`
+
+		// NextAt
+		// unix seconds of next fire time.
+		NextAt int64 `xorm:"next_at"`
+
+		LeaseNode  string `xorm:"lease_node"`
+		LeaseUntil int64  `xorm:"lease_until"`
+
+		RegistryId  int    `xorm:"registry_id"`
+		MessageBody string `xorm:"message_body"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+		GmtUpdated Timeline `xorm:"gmt_updated"`
+	}
+)
+
+func (o *Cron) IsEnabled() bool {
+	return o.Status == StatusEnabled
+}
@@ -0,0 +1,23 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package models
+
+type (
+	// CronHistory
+	//
+	// run history of cron, one record per fire time.
+	CronHistory struct {
+		Id        int64  `xorm:"id pk autoincr"`
+		CronId    int    `xorm:"cron_id"`
+		FireAt    int64  `xorm:"fire_at"`
+		Status    int    `xorm:"status"`
+		Node      string `xorm:"node"`
+		Hash      string `xorm:"hash"`
+		MessageId string `xorm:"message_id"`
+		Error     string `xorm:"error"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+		GmtUpdated Timeline `xorm:"gmt_updated"`
+	}
+)
@@ -0,0 +1,20 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package models
+
+type (
+	// Idempotency
+	//
+	// published idempotency key of registry.
+	Idempotency struct {
+		Id             int64  `xorm:"id pk autoincr"`
+		RegistryId     int    `xorm:"registry_id"`
+		IdempotencyKey string `xorm:"idempotency_key"`
+		Hash           string `xorm:"hash"`
+		Offset         int    `xorm:"offset"`
+		MessageId      string `xorm:"message_id"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+	}
+)
@@ -20,7 +20,11 @@ const (
 	StatusFailed     = 2
 	StatusWaiting    = 3
 	StatusProcessing = 4
+	StatusExpired    = 5
 	StatusIgnored    = 9
+
+	SchemaModeStrict = 1
+	SchemaModeSoft   = 2
 )
 
 type Timeline string
@@ -18,10 +18,20 @@ type (
 
 		MessageDequeue int    `xorm:"message_dequeue"`
 		MessageTime    int64  `xorm:"message_time"`
+		ExpiresAt      int64  `xorm:"expires_at"`
 		MessageId      string `xorm:"message_id"`
+		Keyword        string `xorm:"keyword"`
 		MessageBody    string `xorm:"message_body"`
 		ResponseBody   string `xorm:"response_body"`
 
+		// AckToken
+		// token of asynchronous acknowledgement.
+		//
+		// Message keeps processing status until acknowledged by
+		// token or ack deadline exceeded.
+		AckToken    string `xorm:"ack_token"`
+		AckDeadline int64  `xorm:"ack_deadline"`
+
 		GmtCreated Timeline `xorm:"gmt_created"`
 		GmtUpdated Timeline `xorm:"gmt_updated"`
 	}
@@ -0,0 +1,17 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package models
+
+type (
+	// Node
+	//
+	// alive gmd node in cluster.
+	Node struct {
+		Id   int    `xorm:"id pk autoincr"`
+		Node string `xorm:"node"`
+
+		GmtCreated   Timeline `xorm:"gmt_created"`
+		GmtHeartbeat Timeline `xorm:"gmt_heartbeat"`
+	}
+)
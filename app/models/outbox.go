@@ -0,0 +1,27 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-27
+
+package models
+
+type (
+	// Outbox
+	//
+	// message written by business service in its own transaction, table
+	// is in external database and published by outbox manager.
+	Outbox struct {
+		Id           int64  `xorm:"id pk autoincr"`
+		Status       int    `xorm:"status"`
+		AggregateKey string `xorm:"aggregate_key"`
+		TopicName    string `xorm:"topic_name"`
+		TopicTag     string `xorm:"topic_tag"`
+		MessageBody  string `xorm:"message_body"`
+		MessageId    string `xorm:"message_id"`
+		Retry        int    `xorm:"retry"`
+		Error        string `xorm:"error"`
+		LeaseNode    string `xorm:"lease_node"`
+		LeaseUntil   int64  `xorm:"lease_until"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+		GmtUpdated Timeline `xorm:"gmt_updated"`
+	}
+)
@@ -18,11 +18,27 @@ type (
 
 		Hash         string `xorm:"hash"`
 		Offset       int    `xorm:"offset"`
+		Keyword      string `xorm:"keyword"`
+		Priority     int    `xorm:"priority"`
+		DeliverAt    int64  `xorm:"deliver_at"`
+		ExpiresAt    int64  `xorm:"expires_at"`
 		RegistryId   int    `xorm:"registry_id"`
 		MessageId    string `xorm:"message_id"`
 		MessageBody  string `xorm:"message_body"`
 		ResponseBody string `xorm:"response_body"`
 
+		// IdempotencyKey
+		// claimed by publisher, message id of key is updated when
+		// published.
+		IdempotencyKey string `xorm:"idempotency_key"`
+
+		// Node
+		// identify of node which publishing payload.
+		//
+		// Processing payload of node left cluster is published
+		// again by retry manager.
+		Node string `xorm:"node"`
+
 		GmtCreated Timeline `xorm:"gmt_created"`
 		GmtUpdated Timeline `xorm:"gmt_updated"`
 	}
@@ -12,6 +12,7 @@ type (
 		TopicName string `xorm:"topic_name"`
 		TopicTag  string `xorm:"topic_tag"`
 		FilterTag string `xorm:"filter_tag"`
+		Priority  int    `xorm:"priority"`
 
 		GmtCreated Timeline `xorm:"gmt_created"`
 		GmtUpdated Timeline `xorm:"gmt_updated"`
@@ -0,0 +1,20 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package models
+
+type (
+	// RegistrySchema
+	//
+	// json schema of registry message, the latest version is used.
+	RegistrySchema struct {
+		Id         int    `xorm:"id pk autoincr"`
+		RegistryId int    `xorm:"registry_id"`
+		Version    int    `xorm:"version"`
+		Mode       int    `xorm:"mode"`
+		Content    string `xorm:"content"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+		GmtUpdated Timeline `xorm:"gmt_updated"`
+	}
+)
@@ -0,0 +1,32 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-23
+
+package models
+
+type (
+	// Schedule
+	//
+	// scheduled message delayed longer than adapter supported.
+	Schedule struct {
+		Id        int64  `xorm:"id pk autoincr"`
+		Status    int    `xorm:"status"`
+		DeliverAt int64  `xorm:"deliver_at"`
+		ExpiresAt int64  `xorm:"expires_at"`
+		MessageId string `xorm:"message_id"`
+
+		LeaseNode  string `xorm:"lease_node"`
+		LeaseUntil int64  `xorm:"lease_until"`
+
+		Hash        string `xorm:"hash"`
+		Offset      int    `xorm:"offset"`
+		RegistryId  int    `xorm:"registry_id"`
+		Keyword     string `xorm:"keyword"`
+		Priority    int    `xorm:"priority"`
+		MessageBody string `xorm:"message_body"`
+
+		IdempotencyKey string `xorm:"idempotency_key"`
+
+		GmtCreated Timeline `xorm:"gmt_created"`
+		GmtUpdated Timeline `xorm:"gmt_updated"`
+	}
+)
@@ -25,6 +25,22 @@ type (
 		// Default: 10
 		Concurrency int32 `xorm:"concurrency"`
 
+		// ConcurrencyAdaptive
+		// switch of adaptive concurrency.
+		//
+		// When enabled, effective concurrency is tuned between
+		// ConcurrencyMin and Concurrency by latency and error rate
+		// of delivering.
+		//
+		// Default: 0 (disabled).
+		ConcurrencyAdaptive int `xorm:"concurrency_adaptive"`
+
+		// ConcurrencyMin
+		// minimum consuming process per consumer if adaptive.
+		//
+		// Default: 1
+		ConcurrencyMin int32 `xorm:"concurrency_min"`
+
 		// MaxRetry
 		// maximum consume times if delivered failed.
 		//
@@ -55,6 +71,77 @@ type (
 		// NotAccept: Aliyunmns.
 		Broadcasting int `xorm:"broadcasting"`
 
+		// Ordered
+		// switch of ordered delivery.
+		//
+		// When enabled, messages with the same key are delivered one
+		// at a time in publish order, messages with different keys are
+		// delivered in parallel.
+		//
+		// Accept: Rocketmq, Aliyunmns (order kept on the same
+		// consumer node, message key carried in envelope of body).
+		Ordered int `xorm:"ordered"`
+
+		// Dedupe
+		// switch of duplicate suppression.
+		//
+		// When enabled, message delivered already or delivering on
+		// other node is skipped before delivery, it is used to
+		// protect non-idempotent handler.
+		//
+		// Default: 0 (disabled).
+		Dedupe int `xorm:"dedupe"`
+
+		// MaxAgeSeconds
+		// maximum age of message.
+		//
+		// Message older than specified seconds since published is
+		// not delivered and stored as expired, it is used to drop
+		// meaningless messages in backlog.
+		//
+		// Default: 0 (never expired).
+		MaxAgeSeconds int `xorm:"max_age_seconds"`
+
+		// AckTimeout
+		// deadline seconds of asynchronous acknowledgement.
+		//
+		// When greater than 0, handler can respond 202 and call
+		// /message/ack or /message/nack with token later, the
+		// message is retried if not acknowledged in time.
+		//
+		// Default: 0 (synchronous).
+		AckTimeout int `xorm:"ack_timeout"`
+
+		// RateLimit
+		// maximum delivered messages per second on each node.
+		//
+		// Default: 0 (not limited).
+		RateLimit int `xorm:"rate_limit"`
+
+		// RateBurst
+		// maximum messages delivered at once if rate limited.
+		//
+		// Default: equal to rate limit.
+		RateBurst int `xorm:"rate_burst"`
+
+		// GlobalConcurrency
+		// maximum consuming process in cluster.
+		//
+		// Shared equally by alive nodes and rebalanced when node
+		// joined or left.
+		//
+		// Default: 0 (not limited).
+		GlobalConcurrency int `xorm:"global_concurrency"`
+
+		// GlobalRateLimit
+		// maximum delivered messages per second in cluster.
+		//
+		// Shared equally by alive nodes and rebalanced when node
+		// joined or left.
+		//
+		// Default: 0 (not limited).
+		GlobalRateLimit int `xorm:"global_rate_limit"`
+
 		RegistryId int `xorm:"registry_id"`
 
 		Handler             string `xorm:"handler"`
@@ -64,6 +151,23 @@ type (
 		HandlerResponseType int    `xorm:"handler_response_type"`
 		HandlerIgnoreCodes  string `xorm:"handler_ignore_codes"`
 
+		// HandlerEndpoints
+		// extra endpoints of handler in json string.
+		//
+		// Deliver to one of the endpoints (include handler) per
+		// message. Weight is used by weighted balance only.
+		//
+		// Example: [{"addr":"http://10.0.0.2/orders","weight":2}]
+		HandlerEndpoints string `xorm:"handler_endpoints"`
+
+		// HandlerBalance
+		// strategy to select endpoint.
+		//
+		// 0: Round robin.
+		// 1: Weighted.
+		// 2: Least in-flight.
+		HandlerBalance int `xorm:"handler_balance"`
+
 		Failed             string `xorm:"failed"`
 		FailedTimeout      int    `xorm:"failed_timeout"`
 		FailedMethod       string `xorm:"failed_method"`
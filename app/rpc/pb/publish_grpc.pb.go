@@ -0,0 +1,220 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: publish.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PublisherClient is the client API for Publisher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PublisherClient interface {
+	// Publish one message.
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Publish messages of the same topic in one request.
+	PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error)
+	// Publish messages of any topic in client stream, results returned
+	// in send order when client closed stream.
+	PublishStream(ctx context.Context, opts ...grpc.CallOption) (Publisher_PublishStreamClient, error)
+}
+
+type publisherClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPublisherClient(cc grpc.ClientConnInterface) PublisherClient {
+	return &publisherClient{cc}
+}
+
+func (c *publisherClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, "/gmd.Publisher/Publish", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *publisherClient) PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error) {
+	out := new(PublishBatchResponse)
+	err := c.cc.Invoke(ctx, "/gmd.Publisher/PublishBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *publisherClient) PublishStream(ctx context.Context, opts ...grpc.CallOption) (Publisher_PublishStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Publisher_ServiceDesc.Streams[0], "/gmd.Publisher/PublishStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &publisherPublishStreamClient{stream}
+	return x, nil
+}
+
+type Publisher_PublishStreamClient interface {
+	Send(*PublishRequest) error
+	CloseAndRecv() (*PublishStreamResponse, error)
+	grpc.ClientStream
+}
+
+type publisherPublishStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *publisherPublishStreamClient) Send(m *PublishRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *publisherPublishStreamClient) CloseAndRecv() (*PublishStreamResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PublishStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PublisherServer is the server API for Publisher service.
+// All implementations must embed UnimplementedPublisherServer
+// for forward compatibility
+type PublisherServer interface {
+	// Publish one message.
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Publish messages of the same topic in one request.
+	PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error)
+	// Publish messages of any topic in client stream, results returned
+	// in send order when client closed stream.
+	PublishStream(Publisher_PublishStreamServer) error
+	mustEmbedUnimplementedPublisherServer()
+}
+
+// UnimplementedPublisherServer must be embedded to have forward compatible implementations.
+type UnimplementedPublisherServer struct {
+}
+
+func (UnimplementedPublisherServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedPublisherServer) PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishBatch not implemented")
+}
+func (UnimplementedPublisherServer) PublishStream(Publisher_PublishStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method PublishStream not implemented")
+}
+func (UnimplementedPublisherServer) mustEmbedUnimplementedPublisherServer() {}
+
+// UnsafePublisherServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PublisherServer will
+// result in compilation errors.
+type UnsafePublisherServer interface {
+	mustEmbedUnimplementedPublisherServer()
+}
+
+func RegisterPublisherServer(s grpc.ServiceRegistrar, srv PublisherServer) {
+	s.RegisterService(&Publisher_ServiceDesc, srv)
+}
+
+func _Publisher_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PublisherServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmd.Publisher/Publish",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PublisherServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Publisher_PublishBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PublisherServer).PublishBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmd.Publisher/PublishBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PublisherServer).PublishBatch(ctx, req.(*PublishBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Publisher_PublishStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PublisherServer).PublishStream(&publisherPublishStreamServer{stream})
+}
+
+type Publisher_PublishStreamServer interface {
+	SendAndClose(*PublishStreamResponse) error
+	Recv() (*PublishRequest, error)
+	grpc.ServerStream
+}
+
+type publisherPublishStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *publisherPublishStreamServer) SendAndClose(m *PublishStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *publisherPublishStreamServer) Recv() (*PublishRequest, error) {
+	m := new(PublishRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Publisher_ServiceDesc is the grpc.ServiceDesc for Publisher service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Publisher_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmd.Publisher",
+	HandlerType: (*PublisherServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Publish",
+			Handler:    _Publisher_Publish_Handler,
+		},
+		{
+			MethodName: "PublishBatch",
+			Handler:    _Publisher_PublishBatch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PublishStream",
+			Handler:       _Publisher_PublishStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "publish.proto",
+}
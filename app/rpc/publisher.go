@@ -0,0 +1,195 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package rpc
+
+import (
+	"context"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/logics/topic"
+	"github.com/fuyibing/gmd/app/rpc/pb"
+	"github.com/fuyibing/log/v8"
+	"github.com/fuyibing/log/v8/conf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+)
+
+type (
+	// Grpc publisher
+	// service, requests are processed by the same logic of http api
+	// of topic publish and topic batch.
+	publisher struct {
+		pb.UnimplementedPublisherServer
+	}
+)
+
+// Publish
+// one message, message id returned in sync mode.
+func (o *publisher) Publish(ctx context.Context, in *pb.PublishRequest) (*pb.PublishResponse, error) {
+	return o.send(o.context(ctx), in)
+}
+
+// PublishBatch
+// messages of the same topic, all messages share one hash with
+// index as offset.
+func (o *publisher) PublishBatch(c context.Context, in *pb.PublishBatchRequest) (*pb.PublishBatchResponse, error) {
+	var (
+		ctx = o.context(c)
+		req = &topic.BatchRequest{
+			TopicName:       in.TopicName,
+			TopicTag:        in.TopicTag,
+			Messages:        make([]interface{}, len(in.Messages)),
+			IdempotencyKeys: in.IdempotencyKeys,
+			DeliverAt:       in.DeliverAt,
+			DelaySeconds:    int(in.DelaySeconds),
+			DeliverAtList:   in.DeliverAtList,
+			ExpiresAt:       in.ExpiresAt,
+			Key:             in.Key,
+			Priority:        in.Priority,
+			Sync:            in.Sync,
+		}
+	)
+
+	for i, s := range in.Messages {
+		req.Messages[i] = s
+	}
+	for _, s := range in.DelaySecondsList {
+		req.DelayList = append(req.DelayList, int(s))
+	}
+
+	// Call shared logic
+	// of topic batch.
+	x := topic.NewBatch().With(req)
+	if code, err := x.Do(ctx); err != nil {
+		return nil, o.error(code, err)
+	}
+
+	r := x.Response()
+	res := &pb.PublishBatchResponse{
+		Count:      int32(r.Count),
+		Duplicates: make([]*pb.PublishBatchDuplicate, 0),
+		Hash:       r.Hash,
+		RegistryId: int32(r.RegistryId),
+		Results:    make([]*pb.PublishBatchResult, 0),
+	}
+	for _, d := range r.Duplicates {
+		res.Duplicates = append(res.Duplicates, &pb.PublishBatchDuplicate{Index: int32(d.Index), Hash: d.Hash, MessageId: d.MessageId})
+	}
+	for _, v := range r.Results {
+		res.Results = append(res.Results, &pb.PublishBatchResult{Index: int32(v.Index), MessageId: v.MessageId, Error: v.Error})
+	}
+	return res, nil
+}
+
+// PublishStream
+// messages received from client stream one by one, results returned
+// when client closed stream.
+//
+// Stream aborted with error if any message failed, messages received
+// before are published already, count of them given in error message.
+func (o *publisher) PublishStream(stream pb.Publisher_PublishStreamServer) error {
+	var (
+		ctx = o.context(stream.Context())
+		res = &pb.PublishStreamResponse{Results: make([]*pb.PublishResponse, 0)}
+	)
+
+	for {
+		in, err := stream.Recv()
+
+		// Return results
+		// if client closed stream.
+		if err == io.EOF {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Return error
+		// with count of published messages.
+		r, err := o.send(o.child(ctx), in)
+		if err != nil {
+			st := status.Convert(err)
+			return status.Errorf(st.Code(), "%s: published=%d", st.Message(), res.Count)
+		}
+
+		res.Count++
+		res.Results = append(res.Results, r)
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods
+// /////////////////////////////////////////////////////////////
+
+// Return child
+// log context of message in stream, request context kept as parent.
+func (o *publisher) child(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conf.OpenTracingKey, log.NewChild(ctx).Value(conf.OpenTracingKey))
+}
+
+// Return log context
+// derived from request context, so deadline and cancellation of request
+// are kept. Trace id and span id of incoming metadata used if given.
+func (o *publisher) context(ctx context.Context) context.Context {
+	req := &http.Request{Header: http.Header{}, Method: "GRPC"}
+	req.RequestURI, _ = grpc.Method(ctx)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, vs := range md {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	return context.WithValue(ctx, conf.OpenTracingKey, log.NewRequest(req).Value(conf.OpenTracingKey))
+}
+
+// Convert
+// error code of logic to grpc status.
+func (o *publisher) error(code int, err error) error {
+	switch code {
+	case app.CodeInvalidPayloadFormat, app.CodeInvalidPayloadFields:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case app.CodeServiceReadNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case app.CodeTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case app.CodeAdapterError:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// Publish
+// one message of request with shared logic of topic publish.
+func (o *publisher) send(ctx context.Context, in *pb.PublishRequest) (*pb.PublishResponse, error) {
+	x := topic.NewPublish().With(&topic.PublishRequest{
+		TopicName:      in.TopicName,
+		TopicTag:       in.TopicTag,
+		Message:        in.Message,
+		IdempotencyKey: in.IdempotencyKey,
+		DeliverAt:      in.DeliverAt,
+		DelaySeconds:   int(in.DelaySeconds),
+		ExpiresAt:      in.ExpiresAt,
+		Key:            in.Key,
+		Priority:       in.Priority,
+		Sync:           in.Sync,
+	})
+
+	if code, err := x.Do(ctx); err != nil {
+		return nil, o.error(code, err)
+	}
+
+	r := x.Response()
+	return &pb.PublishResponse{
+		Duplicated: r.Duplicated,
+		Hash:       r.Hash,
+		MessageId:  r.MessageId,
+		RegistryId: int32(r.RegistryId),
+	}, nil
+}
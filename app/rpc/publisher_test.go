@@ -0,0 +1,74 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/fuyibing/gmd/app"
+	"github.com/fuyibing/gmd/app/rpc/pb"
+	"github.com/fuyibing/log/v8/conf"
+	"github.com/fuyibing/log/v8/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestPublisherError(t *testing.T) {
+	o := &publisher{}
+	for code, expected := range map[int]codes.Code{
+		app.CodeInvalidPayloadFormat: codes.InvalidArgument,
+		app.CodeInvalidPayloadFields: codes.InvalidArgument,
+		app.CodeServiceReadNotFound:  codes.NotFound,
+		app.CodeTooManyRequests:      codes.ResourceExhausted,
+		app.CodeAdapterError:         codes.Unavailable,
+		app.CodeServiceWriteError:    codes.Internal,
+	} {
+		if c := status.Code(o.error(code, fmt.Errorf("error"))); c != expected {
+			t.Fatalf("code=%d: expected=%v, got=%v", code, expected, c)
+		}
+	}
+}
+
+func TestPublisherRegistryNotFound(t *testing.T) {
+	o := &publisher{}
+
+	if _, err := o.Publish(context.Background(), &pb.PublishRequest{TopicName: "Unknown", TopicTag: "Tag", Message: "{}", IdempotencyKey: "key"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("publish: not found expected, got=%v", err)
+	}
+	if _, err := o.PublishBatch(context.Background(), &pb.PublishBatchRequest{TopicName: "Unknown", TopicTag: "Tag", Messages: []string{"{}"}}); status.Code(err) != codes.NotFound {
+		t.Fatalf("batch: not found expected, got=%v", err)
+	}
+}
+
+func TestPublisherBatchEmptyMessage(t *testing.T) {
+	o := &publisher{}
+
+	if _, err := o.PublishBatch(context.Background(), &pb.PublishBatchRequest{TopicName: "Topic", TopicTag: "Tag", Messages: []string{"{}", ""}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid argument expected, got=%v", err)
+	}
+}
+
+func TestPublisherContext(t *testing.T) {
+	o := &publisher{}
+	c, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-b3-traceid", "trace-1")))
+
+	// Trace id
+	// of incoming metadata used.
+	ctx := o.context(c)
+	if x, ok := ctx.Value(conf.OpenTracingKey).(*trace.Tracing); !ok || x.TraceId != "trace-1" {
+		t.Fatalf("trace id of metadata expected: %v", ctx.Value(conf.OpenTracingKey))
+	}
+
+	// Cancellation
+	// of request kept by log context and child.
+	child := o.child(ctx)
+	if x, ok := child.Value(conf.OpenTracingKey).(*trace.Tracing); !ok || x.TraceId != "trace-1" {
+		t.Fatalf("trace id of parent expected: %v", child.Value(conf.OpenTracingKey))
+	}
+	if cancel(); ctx.Err() == nil || child.Err() == nil {
+		t.Fatalf("request cancellation expected")
+	}
+}
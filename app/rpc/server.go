@@ -0,0 +1,66 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+// Package rpc
+// publish messages over grpc with http/2 multiplexing, service is
+// defined in pb/publish.proto.
+package rpc
+
+import (
+	"github.com/fuyibing/gmd/app/rpc/pb"
+	"github.com/fuyibing/log/v8"
+	"google.golang.org/grpc"
+	"net"
+
+	// Register gzip compressor,
+	// publisher can send compressed requests.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+type (
+	// Server
+	// grpc server of publisher service.
+	Server struct {
+		server *grpc.Server
+	}
+)
+
+// NewServer
+// return grpc server with publisher service registered.
+func NewServer() *Server {
+	return (&Server{}).init()
+}
+
+// Start
+// listen on specified address and serve, block coroutine until
+// stopped.
+//
+//	s := rpc.NewServer()
+//	s.Start("0.0.0.0:8102")
+func (o *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("grpc server begin: addr=%s", addr)
+	defer log.Infof("grpc server finish")
+
+	return o.server.Serve(listener)
+}
+
+// Stop
+// grpc server, block coroutine until pending requests completed.
+func (o *Server) Stop() {
+	o.server.GracefulStop()
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *Server) init() *Server {
+	o.server = grpc.NewServer()
+	pb.RegisterPublisherServer(o.server, &publisher{})
+	return o
+}
@@ -0,0 +1,132 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	CronService struct {
+		db.Service
+	}
+)
+
+func NewCronService(ss ...*xorm.Session) *CronService {
+	o := &CronService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+func (o *CronService) Add(req *models.Cron) (*models.Cron, error) {
+	var (
+		now  = models.NewTimeline()
+		bean = &models.Cron{
+			Status:      models.StatusEnabled,
+			Title:       req.Title,
+			Expression:  req.Expression,
+			Timezone:    req.Timezone,
+			NextAt:      req.NextAt,
+			RegistryId:  req.RegistryId,
+			MessageBody: req.MessageBody,
+			GmtCreated:  now,
+			GmtUpdated:  now,
+		}
+		err error
+	)
+	if _, err = o.Master().Insert(bean); err != nil {
+		return nil, err
+	}
+	return bean, nil
+}
+
+// Claim
+// due crons for node with lease seconds.
+//
+// Return affected rows count, claimed crons are read by ListLeased
+// of the same node. Cron leased by other node is claimed again if
+// lease expired.
+func (o *CronService) Claim(node string, seconds, limit int) (int64, error) {
+	res, err := o.Master().Exec(
+		"UPDATE `cron` SET `lease_node` = ?, `lease_until` = UNIX_TIMESTAMP() + ? "+
+			"WHERE `status` = ? AND `next_at` <= UNIX_TIMESTAMP() AND `lease_until` < UNIX_TIMESTAMP() "+
+			"ORDER BY `next_at` ASC LIMIT ?",
+		node, seconds,
+		models.StatusEnabled,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (o *CronService) GetById(id int) (*models.Cron, error) {
+	var (
+		bean   = &models.Cron{}
+		err    error
+		exists bool
+	)
+	if exists, err = o.Slave().
+		Where("id = ?", id).
+		Get(bean); err != nil || !exists {
+		return nil, err
+	}
+	return bean, nil
+}
+
+func (o *CronService) ListLeased(node string) (list []*models.Cron, err error) {
+	list = make([]*models.Cron, 0)
+	err = o.Master().
+		Where("status = ? AND lease_node = ? AND next_at <= UNIX_TIMESTAMP() AND lease_until >= UNIX_TIMESTAMP()", models.StatusEnabled, node).
+		OrderBy("next_at ASC").
+		Find(&list)
+	return
+}
+
+// SetNextAt
+// advance next fire time and release lease of node.
+//
+// Affects is zero if lease taken over by other node or cron fired
+// already.
+func (o *CronService) SetNextAt(id int, node string, fireAt, nextAt int64) (int64, error) {
+	return o.Master().Cols(
+		"next_at",
+		"lease_node",
+		"lease_until",
+	).Where(
+		"id = ? AND lease_node = ? AND next_at = ?",
+		id,
+		node,
+		fireAt,
+	).Update(&models.Cron{
+		NextAt: nextAt,
+	})
+}
+
+func (o *CronService) SetStatusAsDisabled(id int) (int64, error) {
+	return o.Master().Cols("status").Where("id = ? AND status = ?", id, models.StatusEnabled).Update(&models.Cron{
+		Status: models.StatusDisabled,
+	})
+}
+
+// SetStatusAsEnabled
+// resume cron with next fire time, ticks missed when paused are
+// not fired.
+func (o *CronService) SetStatusAsEnabled(id int, nextAt int64) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"next_at",
+	).Where(
+		"id = ? AND status = ?",
+		id,
+		models.StatusDisabled,
+	).Update(&models.Cron{
+		Status: models.StatusEnabled,
+		NextAt: nextAt,
+	})
+}
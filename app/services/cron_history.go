@@ -0,0 +1,76 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-25
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	CronHistoryService struct {
+		db.Service
+	}
+)
+
+func NewCronHistoryService(ss ...*xorm.Session) *CronHistoryService {
+	o := &CronHistoryService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+// AddProcessing
+// add run history for fire time of cron.
+//
+// Return zero id if fire time run already, each fire time of cron
+// is published at most once.
+func (o *CronHistoryService) AddProcessing(cronId int, fireAt int64, node, hash string) (int64, error) {
+	res, err := o.Master().Exec(
+		"INSERT IGNORE INTO `cron_history` (`cron_id`, `fire_at`, `status`, `node`, `hash`, `gmt_created`, `gmt_updated`) "+
+			"VALUES (?, ?, ?, ?, ?, NOW(), NOW())",
+		cronId, fireAt, models.StatusProcessing, node, hash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+	return res.LastInsertId()
+}
+
+func (o *CronHistoryService) ListByCron(cronId, limit int) (list []*models.CronHistory, err error) {
+	list = make([]*models.CronHistory, 0)
+	err = o.Slave().
+		Where("cron_id = ?", cronId).
+		OrderBy("fire_at DESC").
+		Limit(limit).
+		Find(&list)
+	return
+}
+
+func (o *CronHistoryService) SetStatusAsFailed(id int64, reason string) (int64, error) {
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	return o.Master().Cols(
+		"status",
+		"error",
+	).Where("id = ?", id).Update(&models.CronHistory{
+		Status: models.StatusFailed,
+		Error:  reason,
+	})
+}
+
+func (o *CronHistoryService) SetStatusAsSucceed(id int64, messageId string) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"message_id",
+	).Where("id = ?", id).Update(&models.CronHistory{
+		Status:    models.StatusSucceed,
+		MessageId: messageId,
+	})
+}
@@ -0,0 +1,68 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-22
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	IdempotencyService struct {
+		db.Service
+	}
+)
+
+func NewIdempotencyService(ss ...*xorm.Session) *IdempotencyService {
+	o := &IdempotencyService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+// Claim
+// idempotency key of registry for hash and offset.
+//
+// Return claimed false with original record if key published in
+// window seconds, record older than window is taken over.
+func (o *IdempotencyService) Claim(registryId int, key, hash string, offset, seconds int) (bean *models.Idempotency, claimed bool, err error) {
+	if _, err = o.Master().Exec(
+		"INSERT INTO `idempotency` (`registry_id`, `idempotency_key`, `hash`, `offset`, `gmt_created`) VALUES (?, ?, ?, ?, NOW()) "+
+			"ON DUPLICATE KEY UPDATE "+
+			"`hash` = IF(`gmt_created` < DATE_SUB(NOW(), INTERVAL ? SECOND), VALUES(`hash`), `hash`), "+
+			"`offset` = IF(`gmt_created` < DATE_SUB(NOW(), INTERVAL ? SECOND), VALUES(`offset`), `offset`), "+
+			"`message_id` = IF(`gmt_created` < DATE_SUB(NOW(), INTERVAL ? SECOND), NULL, `message_id`), "+
+			"`gmt_created` = IF(`gmt_created` < DATE_SUB(NOW(), INTERVAL ? SECOND), NOW(), `gmt_created`)",
+		registryId, key, hash, offset,
+		seconds, seconds, seconds, seconds,
+	); err != nil {
+		return
+	}
+
+	var exists bool
+	bean = &models.Idempotency{}
+	if exists, err = o.Master().
+		Where("registry_id = ? AND idempotency_key = ?", registryId, key).
+		Get(bean); err != nil || !exists {
+		return nil, false, err
+	}
+
+	claimed = bean.Hash == hash && bean.Offset == offset
+	return
+}
+
+func (o *IdempotencyService) Release(registryId int, key, hash string, offset int) (int64, error) {
+	return o.Master().
+		Where("registry_id = ? AND idempotency_key = ? AND hash = ? AND offset = ?", registryId, key, hash, offset).
+		Delete(&models.Idempotency{})
+}
+
+func (o *IdempotencyService) SetMessageId(hash string, offset int, messageId string) (int64, error) {
+	return o.Master().Cols(
+		"message_id",
+	).Where("hash = ? AND offset = ?", hash, offset).Update(&models.Idempotency{
+		MessageId: messageId,
+	})
+}
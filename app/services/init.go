@@ -7,3 +7,15 @@
 // Write into database (DELETE / INSERT / UPDATE) and
 // read (SELECT) from.
 package services
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+// IsDuplicate
+// return true if error returned for duplicate entry of unique key.
+func IsDuplicate(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == 1062
+}
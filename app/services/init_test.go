@@ -0,0 +1,22 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package services
+
+import (
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+func TestIsDuplicate(t *testing.T) {
+	if !IsDuplicate(fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})) {
+		t.Fatalf("duplicate entry error expected")
+	}
+	if IsDuplicate(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}) {
+		t.Fatalf("lock wait timeout is not duplicate entry")
+	}
+	if IsDuplicate(fmt.Errorf("connection refused")) || IsDuplicate(nil) {
+		t.Fatalf("other error is not duplicate entry")
+	}
+}
@@ -6,6 +6,7 @@ package services
 import (
 	"github.com/fuyibing/db/v8"
 	"github.com/fuyibing/gmd/app/models"
+	"time"
 	"xorm.io/xorm"
 )
 
@@ -22,6 +23,16 @@ func NewMessageService(ss ...*xorm.Session) *MessageService {
 	return o
 }
 
+func (o *MessageService) AddAccepted(r *models.Message) (*models.Message, error) {
+	r.Status = models.StatusProcessing
+	return o.add(r)
+}
+
+func (o *MessageService) AddExpired(r *models.Message) (*models.Message, error) {
+	r.Status = models.StatusExpired
+	return o.add(r)
+}
+
 func (o *MessageService) AddFailed(r *models.Message) (*models.Message, error) {
 	r.Status = models.StatusFailed
 	return o.add(r)
@@ -32,11 +43,24 @@ func (o *MessageService) AddIgnored(r *models.Message) (*models.Message, error)
 	return o.add(r)
 }
 
+func (o *MessageService) AddProcessing(r *models.Message) (*models.Message, error) {
+	r.Status = models.StatusProcessing
+	return o.add(r)
+}
+
 func (o *MessageService) AddSucceed(r *models.Message) (*models.Message, error) {
 	r.Status = models.StatusSucceed
 	return o.add(r)
 }
 
+func (o *MessageService) DeleteProcessing(id int64) (int64, error) {
+	return o.Master().Where(
+		"id = ? AND status = ?",
+		id,
+		models.StatusProcessing,
+	).Delete(&models.Message{})
+}
+
 func (o *MessageService) GetById(id int64) (*models.Message, error) {
 	var (
 		bean   = &models.Message{}
@@ -51,6 +75,20 @@ func (o *MessageService) GetById(id int64) (*models.Message, error) {
 	return bean, nil
 }
 
+func (o *MessageService) GetByAckToken(token string) (*models.Message, error) {
+	var (
+		bean   = &models.Message{}
+		err    error
+		exists bool
+	)
+	if exists, err = o.Master().
+		Where("ack_token = ?", token).
+		Get(bean); err != nil || !exists {
+		return nil, err
+	}
+	return bean, nil
+}
+
 func (o *MessageService) GetByMessageId(taskId int, messageId string) (*models.Message, error) {
 	var (
 		bean   = &models.Message{}
@@ -65,12 +103,63 @@ func (o *MessageService) GetByMessageId(taskId int, messageId string) (*models.M
 	return bean, nil
 }
 
+func (o *MessageService) ListAckExpired(limit int) (list []*models.Message, err error) {
+	list = make([]*models.Message, 0)
+	err = o.Slave().Where(
+		"status = ? AND ack_deadline > 0 AND ack_deadline < ?",
+		models.StatusProcessing,
+		time.Now().Unix(),
+	).Limit(limit).Find(&list)
+	return
+}
+
 func (o *MessageService) ListWaiting(limit int) (list []*models.Message, err error) {
 	list = make([]*models.Message, 0)
 	err = o.Slave().Where("status = ?", models.StatusWaiting).Limit(limit).Find(&list)
 	return
 }
 
+func (o *MessageService) SetAckResult(id int64, token string, status int, responseBody string) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"response_body",
+		"ack_deadline",
+	).Where(
+		"id = ? AND status = ? AND ack_token = ?",
+		id,
+		models.StatusProcessing,
+		token,
+	).Update(&models.Message{
+		Status:       status,
+		ResponseBody: responseBody,
+		AckDeadline:  0,
+	})
+}
+
+func (o *MessageService) SetStatusAsAccepted(id int64, duration float64, responseBody, token string, deadline int64) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"duration",
+		"response_body",
+		"ack_token",
+		"ack_deadline",
+	).Incr("retry", 1).Where("id = ?", id).Update(&models.Message{
+		Status:       models.StatusProcessing,
+		Duration:     duration,
+		ResponseBody: responseBody,
+		AckToken:     token,
+		AckDeadline:  deadline,
+	})
+}
+
+func (o *MessageService) SetStatusAsExpired(id int64) (int64, error) {
+	return o.Master().Cols(
+		"status",
+	).Where("id = ?", id).Update(&models.Message{
+		Status: models.StatusExpired,
+	})
+}
+
 func (o *MessageService) SetStatusAsFailed(id int64, duration float64, responseBody string) (int64, error) {
 	return o.Master().Cols(
 		"status",
@@ -91,6 +180,41 @@ func (o *MessageService) SetStatusAsIgnored(id int64) (int64, error) {
 	})
 }
 
+// SetStatusAsClaimed
+// change failed or stale processing message as processing.
+//
+// Processing message is stale if not updated in specified seconds,
+// accepted message is not included.
+func (o *MessageService) SetStatusAsClaimed(id int64, seconds int) (int64, error) {
+	return o.Master().Cols(
+		"status",
+	).SetExpr(
+		"gmt_updated", "NOW()",
+	).Where(
+		"id = ? AND (status = ? OR (status = ? AND (ack_token IS NULL OR ack_token = '') AND gmt_updated < DATE_SUB(NOW(), INTERVAL ? SECOND)))",
+		id,
+		models.StatusFailed,
+		models.StatusProcessing,
+		seconds,
+	).Update(&models.Message{
+		Status: models.StatusProcessing,
+	})
+}
+
+// SetStatusAsUnclaimed
+// change claimed processing message as specified status.
+func (o *MessageService) SetStatusAsUnclaimed(id int64, status int) (int64, error) {
+	return o.Master().Cols(
+		"status",
+	).Where(
+		"id = ? AND status = ?",
+		id,
+		models.StatusProcessing,
+	).Update(&models.Message{
+		Status: status,
+	})
+}
+
 func (o *MessageService) SetStatusAsProcessing(id int64) (int64, error) {
 	return o.Master().Cols(
 		"status",
@@ -103,6 +227,18 @@ func (o *MessageService) SetStatusAsProcessing(id int64) (int64, error) {
 	})
 }
 
+func (o *MessageService) SetStatusAsWaiting(id int64) (int64, error) {
+	return o.Master().Cols(
+		"status",
+	).Where(
+		"id = ? AND status = ?",
+		id,
+		models.StatusProcessing,
+	).Update(&models.Message{
+		Status: models.StatusWaiting,
+	})
+}
+
 func (o *MessageService) SetStatusAsSucceed(id int64, duration float64, responseBody string) (int64, error) {
 	return o.Master().Cols(
 		"status",
@@ -130,9 +266,13 @@ func (o *MessageService) add(req *models.Message) (*models.Message, error) {
 			TaskId:           req.TaskId,
 			MessageDequeue:   req.MessageDequeue,
 			MessageTime:      req.MessageTime,
+			ExpiresAt:        req.ExpiresAt,
 			MessageId:        req.MessageId,
+			Keyword:          req.Keyword,
 			MessageBody:      req.MessageBody,
 			ResponseBody:     req.ResponseBody,
+			AckToken:         req.AckToken,
+			AckDeadline:      req.AckDeadline,
 			GmtCreated:       now,
 			GmtUpdated:       now,
 		}
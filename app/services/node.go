@@ -0,0 +1,51 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-21
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	NodeService struct {
+		db.Service
+	}
+)
+
+func NewNodeService(ss ...*xorm.Session) *NodeService {
+	o := &NodeService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+func (o *NodeService) ListAlive(seconds int) (list []*models.Node, err error) {
+	list = make([]*models.Node, 0)
+	err = o.Master().
+		Where("gmt_heartbeat >= DATE_SUB(NOW(), INTERVAL ? SECOND)", seconds).
+		Asc("node").
+		Find(&list)
+	return
+}
+
+func (o *NodeService) DeleteByNode(node string) (int64, error) {
+	return o.Master().Where("node = ?", node).Delete(&models.Node{})
+}
+
+func (o *NodeService) DeleteExpired(seconds int) (int64, error) {
+	return o.Master().
+		Where("gmt_heartbeat < DATE_SUB(NOW(), INTERVAL ? SECOND)", seconds).
+		Delete(&models.Node{})
+}
+
+func (o *NodeService) Heartbeat(node string) (err error) {
+	_, err = o.Master().Exec(
+		"INSERT INTO `node` (`node`, `gmt_created`, `gmt_heartbeat`) VALUES (?, NOW(), NOW()) "+
+			"ON DUPLICATE KEY UPDATE `gmt_heartbeat` = NOW()",
+		node,
+	)
+	return
+}
@@ -0,0 +1,115 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-27
+
+package services
+
+import (
+	"fmt"
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	// OutboxService
+	// operate outbox table in external database, connection and table
+	// name are configured.
+	OutboxService struct {
+		db.Service
+		table string
+	}
+)
+
+func NewOutboxService(connection, table string, ss ...*xorm.Session) *OutboxService {
+	o := &OutboxService{table: fmt.Sprintf("`%s`", table)}
+	o.Use(ss...)
+	o.UseConnection(connection)
+	return o
+}
+
+// Claim
+// outbox record for node with lease seconds.
+//
+// Return zero if published already or leased by other node.
+func (o *OutboxService) Claim(id int64, node string, seconds int) (int64, error) {
+	res, err := o.Master().Exec(
+		"UPDATE "+o.table+" SET `lease_node` = ?, `lease_until` = UNIX_TIMESTAMP() + ? "+
+			"WHERE `id` = ? AND `status` = ? AND `lease_until` < UNIX_TIMESTAMP()",
+		node, seconds,
+		id,
+		models.StatusWaiting,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetHead
+// return first waiting record of aggregate key.
+func (o *OutboxService) GetHead(key string) (*models.Outbox, error) {
+	var (
+		bean   = &models.Outbox{}
+		err    error
+		exists bool
+	)
+	if exists, err = o.Master().
+		SQL(
+			"SELECT * FROM "+o.table+" WHERE `aggregate_key` = ? AND `status` = ? ORDER BY `id` ASC LIMIT 1",
+			key,
+			models.StatusWaiting,
+		).
+		Get(bean); err != nil || !exists {
+		return nil, err
+	}
+	return bean, nil
+}
+
+// ListHeads
+// return waiting records not leased. Records without aggregate key
+// are all returned, otherwise only first record of each key.
+func (o *OutboxService) ListHeads(limit int) (list []*models.Outbox, err error) {
+	list = make([]*models.Outbox, 0)
+	err = o.Master().SQL(
+		"SELECT * FROM "+o.table+" WHERE `status` = ? AND `lease_until` < UNIX_TIMESTAMP() "+
+			"AND (`aggregate_key` = '' OR `id` IN ("+
+			"SELECT MIN(`id`) FROM "+o.table+" WHERE `status` = ? AND `aggregate_key` <> '' GROUP BY `aggregate_key`"+
+			")) ORDER BY `id` ASC LIMIT ?",
+		models.StatusWaiting,
+		models.StatusWaiting,
+		limit,
+	).Find(&list)
+	return
+}
+
+func (o *OutboxService) SetStatusAsFailed(id int64, node, reason string) (int64, error) {
+	return o.setStatus(id, node, models.StatusFailed, "", reason)
+}
+
+func (o *OutboxService) SetStatusAsSucceed(id int64, node, messageId string) (int64, error) {
+	return o.setStatus(id, node, models.StatusSucceed, messageId, "")
+}
+
+// SetStatusAsWaiting
+// release lease and increase retry count, record is claimed again
+// in next poll.
+func (o *OutboxService) SetStatusAsWaiting(id int64, node, reason string) (int64, error) {
+	return o.setStatus(id, node, models.StatusWaiting, "", reason)
+}
+
+func (o *OutboxService) setStatus(id int64, node string, status int, messageId, reason string) (int64, error) {
+	if len(reason) > 255 {
+		reason = reason[0:255]
+	}
+
+	res, err := o.Master().Exec(
+		"UPDATE "+o.table+" SET `status` = ?, `message_id` = ?, `error` = ?, `retry` = `retry` + 1, `lease_until` = 0 "+
+			"WHERE `id` = ? AND `lease_node` = ?",
+		status, messageId, reason,
+		id, node,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
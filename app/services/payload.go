@@ -32,6 +32,12 @@ func (o *PayloadService) AddIgnored(req *models.Payload) (*models.Payload, error
 	return o.add(req)
 }
 
+// AddProcessing
+// add payloads accepted by producer bucket of node.
+func (o *PayloadService) AddProcessing(node string, reqs ...*models.Payload) (int64, error) {
+	return o.addList(models.StatusProcessing, node, reqs...)
+}
+
 func (o *PayloadService) AddSucceed(req *models.Payload) (*models.Payload, error) {
 	req.Status = models.StatusSucceed
 	return o.add(req)
@@ -42,6 +48,12 @@ func (o *PayloadService) AddWaiting(req *models.Payload) (*models.Payload, error
 	return o.add(req)
 }
 
+// AddWaitingList
+// add payloads spilled from producer bucket.
+func (o *PayloadService) AddWaitingList(reqs ...*models.Payload) (int64, error) {
+	return o.addList(models.StatusWaiting, "", reqs...)
+}
+
 func (o *PayloadService) GetByHash(hash string, offset int) (*models.Payload, error) {
 	var (
 		bean   = &models.Payload{}
@@ -74,6 +86,24 @@ func (o *PayloadService) ListWaiting(limit int) (list []*models.Payload, err err
 	return
 }
 
+// RecoverOrphans
+// change processing payloads of node left cluster as waiting, node
+// is left if no heartbeat in specified seconds.
+func (o *PayloadService) RecoverOrphans(seconds int) (int64, error) {
+	res, err := o.Master().Exec(
+		"UPDATE `payload` SET `status` = ? "+
+			"WHERE `status` = ? AND `node` IS NOT NULL AND `node` <> '' "+
+			"AND `node` NOT IN (SELECT `node` FROM `node` WHERE `gmt_heartbeat` >= DATE_SUB(NOW(), INTERVAL ? SECOND))",
+		models.StatusWaiting,
+		models.StatusProcessing,
+		seconds,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (o *PayloadService) SetStatusAsFailed(id int64, duration float64, responseBody string) (int64, error) {
 	return o.Master().Cols(
 		"status",
@@ -94,15 +124,17 @@ func (o *PayloadService) SetStatusAsIgnored(id int64) (int64, error) {
 	})
 }
 
-func (o *PayloadService) SetStatusAsProcessing(id int64) (int64, error) {
+func (o *PayloadService) SetStatusAsProcessing(id int64, node string) (int64, error) {
 	return o.Master().Cols(
 		"status",
+		"node",
 	).Where(
 		"id = ? AND status = ?",
 		id,
 		models.StatusWaiting,
 	).Update(&models.Payload{
 		Status: models.StatusProcessing,
+		Node:   node,
 	})
 }
 
@@ -155,6 +187,11 @@ func (o *PayloadService) add(req *models.Payload) (*models.Payload, error) {
 			MessageMessageId: req.MessageMessageId,
 			Hash:             req.Hash,
 			Offset:           req.Offset,
+			Keyword:          req.Keyword,
+			Priority:         req.Priority,
+			IdempotencyKey:   req.IdempotencyKey,
+			DeliverAt:        req.DeliverAt,
+			ExpiresAt:        req.ExpiresAt,
 			RegistryId:       req.RegistryId,
 			MessageId:        req.MessageId,
 			MessageBody:      req.MessageBody,
@@ -166,3 +203,30 @@ func (o *PayloadService) add(req *models.Payload) (*models.Payload, error) {
 	_, err := o.Master().Insert(bean)
 	return bean, err
 }
+
+func (o *PayloadService) addList(status int, node string, reqs ...*models.Payload) (int64, error) {
+	var (
+		now   = models.NewTimeline()
+		beans = make([]*models.Payload, 0)
+	)
+	for _, req := range reqs {
+		beans = append(beans, &models.Payload{
+			Status:           status,
+			MessageTaskId:    req.MessageTaskId,
+			MessageMessageId: req.MessageMessageId,
+			Hash:             req.Hash,
+			Offset:           req.Offset,
+			Keyword:          req.Keyword,
+			Priority:         req.Priority,
+			IdempotencyKey:   req.IdempotencyKey,
+			DeliverAt:        req.DeliverAt,
+			ExpiresAt:        req.ExpiresAt,
+			RegistryId:       req.RegistryId,
+			MessageBody:      req.MessageBody,
+			Node:             node,
+			GmtCreated:       now,
+			GmtUpdated:       now,
+		})
+	}
+	return o.Master().Insert(&beans)
+}
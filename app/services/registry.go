@@ -82,3 +82,14 @@ func (o *RegistryService) SetFilterTag(id int) (int64, error) {
 		FilterTag: fmt.Sprintf("T%d", id),
 	})
 }
+
+func (o *RegistryService) SetPriority(id, priority int) (int64, error) {
+	return o.Master().Cols(
+		"priority",
+	).Where(
+		"id = ?",
+		id,
+	).Update(&models.Registry{
+		Priority: priority,
+	})
+}
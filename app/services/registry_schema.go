@@ -0,0 +1,85 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-28
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	RegistrySchemaService struct {
+		db.Service
+	}
+)
+
+func NewRegistrySchemaService(ss ...*xorm.Session) *RegistrySchemaService {
+	o := &RegistrySchemaService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+func (o *RegistrySchemaService) Add(req *models.RegistrySchema) (*models.RegistrySchema, error) {
+	var (
+		now  = models.NewTimeline()
+		bean = &models.RegistrySchema{
+			RegistryId: req.RegistryId,
+			Version:    req.Version,
+			Mode:       req.Mode,
+			Content:    req.Content,
+			GmtCreated: now,
+			GmtUpdated: now,
+		}
+		err error
+	)
+	if _, err = o.Master().Insert(bean); err != nil {
+		return nil, err
+	}
+	return bean, nil
+}
+
+func (o *RegistrySchemaService) GetByVersion(registryId, version int) (*models.RegistrySchema, error) {
+	var (
+		bean   = &models.RegistrySchema{}
+		err    error
+		exists bool
+	)
+	if exists, err = o.Slave().
+		Where("registry_id = ? AND version = ?", registryId, version).
+		Get(bean); err != nil || !exists {
+		return nil, err
+	}
+	return bean, nil
+}
+
+// GetLatest
+// return the latest version of registry.
+func (o *RegistrySchemaService) GetLatest(registryId int) (*models.RegistrySchema, error) {
+	var (
+		bean   = &models.RegistrySchema{}
+		err    error
+		exists bool
+	)
+	if exists, err = o.Master().
+		Where("registry_id = ?", registryId).
+		OrderBy("version DESC").
+		Get(bean); err != nil || !exists {
+		return nil, err
+	}
+	return bean, nil
+}
+
+// ListLatest
+// return the latest version of each registry.
+func (o *RegistrySchemaService) ListLatest() (list []*models.RegistrySchema, err error) {
+	list = make([]*models.RegistrySchema, 0)
+	err = o.Slave().SQL(
+		"SELECT `s`.* FROM `registry_schema` `s` " +
+			"JOIN (SELECT `registry_id`, MAX(`version`) `version` FROM `registry_schema` GROUP BY `registry_id`) `t` " +
+			"ON `s`.`registry_id` = `t`.`registry_id` AND `s`.`version` = `t`.`version`",
+	).Find(&list)
+	return
+}
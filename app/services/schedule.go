@@ -0,0 +1,119 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-02-23
+
+package services
+
+import (
+	"github.com/fuyibing/db/v8"
+	"github.com/fuyibing/gmd/app/models"
+	"xorm.io/xorm"
+)
+
+type (
+	ScheduleService struct {
+		db.Service
+	}
+)
+
+func NewScheduleService(ss ...*xorm.Session) *ScheduleService {
+	o := &ScheduleService{}
+	o.Use(ss...)
+	o.UseConnection(models.ConnectionName)
+	return o
+}
+
+func (o *ScheduleService) AddWaiting(req *models.Schedule) (*models.Schedule, error) {
+	var (
+		now  = models.NewTimeline()
+		bean = &models.Schedule{
+			Status:         models.StatusWaiting,
+			DeliverAt:      req.DeliverAt,
+			ExpiresAt:      req.ExpiresAt,
+			Hash:           req.Hash,
+			Offset:         req.Offset,
+			RegistryId:     req.RegistryId,
+			Keyword:        req.Keyword,
+			Priority:       req.Priority,
+			MessageBody:    req.MessageBody,
+			IdempotencyKey: req.IdempotencyKey,
+			GmtCreated:     now,
+			GmtUpdated:     now,
+		}
+	)
+	_, err := o.Master().Insert(bean)
+	return bean, err
+}
+
+// Claim
+// due schedules for node with lease seconds.
+//
+// Return affected rows count, claimed schedules are read by
+// ListLeased of the same node.
+func (o *ScheduleService) Claim(node string, seconds, limit int) (int64, error) {
+	res, err := o.Master().Exec(
+		"UPDATE `schedule` SET `status` = ?, `lease_node` = ?, `lease_until` = UNIX_TIMESTAMP() + ? "+
+			"WHERE `status` = ? AND `deliver_at` <= UNIX_TIMESTAMP() "+
+			"ORDER BY `deliver_at` ASC LIMIT ?",
+		models.StatusProcessing, node, seconds,
+		models.StatusWaiting,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (o *ScheduleService) ListLeased(node string) (list []*models.Schedule, err error) {
+	list = make([]*models.Schedule, 0)
+	err = o.Master().
+		Where("status = ? AND lease_node = ?", models.StatusProcessing, node).
+		OrderBy("deliver_at ASC").
+		Find(&list)
+	return
+}
+
+// Recover
+// schedules whose lease expired, node claimed them is gone or hang.
+func (o *ScheduleService) Recover() (int64, error) {
+	res, err := o.Master().Exec(
+		"UPDATE `schedule` SET `status` = ?, `lease_node` = NULL, `lease_until` = 0 "+
+			"WHERE `status` = ? AND `lease_until` < UNIX_TIMESTAMP()",
+		models.StatusWaiting,
+		models.StatusProcessing,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (o *ScheduleService) SetStatusAsSucceed(id int64, node, messageId string) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"message_id",
+	).Where(
+		"id = ? AND status = ? AND lease_node = ?",
+		id,
+		models.StatusProcessing,
+		node,
+	).Update(&models.Schedule{
+		Status:    models.StatusSucceed,
+		MessageId: messageId,
+	})
+}
+
+func (o *ScheduleService) SetStatusAsWaiting(id int64, node string) (int64, error) {
+	return o.Master().Cols(
+		"status",
+		"lease_node",
+		"lease_until",
+	).Where(
+		"id = ? AND status = ? AND lease_node = ?",
+		id,
+		models.StatusProcessing,
+		node,
+	).Update(&models.Schedule{
+		Status: models.StatusWaiting,
+	})
+}
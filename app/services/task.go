@@ -83,9 +83,19 @@ func (o *TaskService) SetBasicFields(req *models.Task) (int64, error) {
 		"remark",
 		"parallels",
 		"concurrency",
+		"concurrency_adaptive",
+		"concurrency_min",
 		"max_retry",
 		"delay_seconds",
 		"broadcasting",
+		"ordered",
+		"dedupe",
+		"max_age_seconds",
+		"ack_timeout",
+		"rate_limit",
+		"rate_burst",
+		"global_concurrency",
+		"global_rate_limit",
 	).Where("id = ?", req.Id).Update(&models.Task{
 		Title:        req.Title,
 		Remark:       req.Remark,
@@ -94,6 +104,17 @@ func (o *TaskService) SetBasicFields(req *models.Task) (int64, error) {
 		MaxRetry:     req.MaxRetry,
 		DelaySeconds: req.DelaySeconds,
 		Broadcasting: req.Broadcasting,
+		Ordered:      req.Ordered,
+		Dedupe:       req.Dedupe,
+		AckTimeout:   req.AckTimeout,
+		RateLimit:    req.RateLimit,
+		RateBurst:    req.RateBurst,
+
+		ConcurrencyAdaptive: req.ConcurrencyAdaptive,
+		ConcurrencyMin:      req.ConcurrencyMin,
+		GlobalConcurrency:   req.GlobalConcurrency,
+		GlobalRateLimit:     req.GlobalRateLimit,
+		MaxAgeSeconds:       req.MaxAgeSeconds,
 	})
 }
 
@@ -135,6 +156,8 @@ func (o *TaskService) SetSubscriberForHandler(req *models.Task) (int64, error) {
 		"handler_condition",
 		"handler_response_type",
 		"handler_ignore_codes",
+		"handler_endpoints",
+		"handler_balance",
 	).Where("id = ?", req.Id).Update(&models.Task{
 		Handler:             req.Handler,
 		HandlerCondition:    req.HandlerCondition,
@@ -142,6 +165,8 @@ func (o *TaskService) SetSubscriberForHandler(req *models.Task) (int64, error) {
 		HandlerMethod:       req.HandlerMethod,
 		HandlerResponseType: req.HandlerResponseType,
 		HandlerIgnoreCodes:  req.HandlerIgnoreCodes,
+		HandlerEndpoints:    req.HandlerEndpoints,
+		HandlerBalance:      req.HandlerBalance,
 	})
 }
 
@@ -0,0 +1,151 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Enqueue
+// add message into local buffer, return immediately.
+//
+// Buffered messages of the same topic, tag, key, priority and expire
+// time are published with batch api when batch size reached or flush
+// interval elapsed, deliver time of each message is kept. Sync mode is ignored, failed batch is passed
+// to error handler.
+func (o *Client) Enqueue(req *PublishRequest) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Return error
+	// if client closed.
+	if o.closed {
+		return ErrClosed
+	}
+
+	// Start flush coroutine
+	// when first message buffered.
+	if o.stop == nil {
+		o.stop = make(chan bool)
+		o.wg.Add(1)
+		go o.flusher()
+	}
+
+	key := fmt.Sprintf("%s|%s|%s|%s|%d", req.TopicName, req.TopicTag, req.Key, req.Priority, req.ExpiresAt)
+	b, ok := o.buffers[key]
+	if !ok {
+		b = &BatchRequest{
+			TopicName: req.TopicName,
+			TopicTag:  req.TopicTag,
+			ExpiresAt: req.ExpiresAt,
+			Key:       req.Key,
+			Priority:  req.Priority,
+		}
+		o.buffers[key] = b
+	}
+
+	b.Messages = append(b.Messages, req.Message)
+	b.IdempotencyKeys = append(b.IdempotencyKeys, req.IdempotencyKey)
+	b.DeliverAtList = append(b.DeliverAtList, req.DeliverAt)
+	b.DelayList = append(b.DelayList, req.DelaySeconds)
+
+	// Publish in coroutine
+	// if batch size reached.
+	if len(b.Messages) >= o.bufferSize {
+		delete(o.buffers, key)
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			_ = o.flush(context.Background(), b)
+		}()
+	}
+	return nil
+}
+
+// Flush
+// publish all buffered messages and wait completed, first error
+// returned if any batch failed.
+func (o *Client) Flush(ctx context.Context) (err error) {
+	o.mu.Lock()
+	list := make([]*BatchRequest, 0, len(o.buffers))
+	for k, b := range o.buffers {
+		list = append(list, b)
+		delete(o.buffers, k)
+	}
+	o.mu.Unlock()
+
+	for _, b := range list {
+		if fe := o.flush(ctx, b); fe != nil && err == nil {
+			err = fe
+		}
+	}
+	return
+}
+
+// Close
+// stop flush coroutine and publish buffered messages, block until
+// completed or context cancelled.
+func (o *Client) Close(ctx context.Context) error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	if o.stop != nil {
+		close(o.stop)
+	}
+	o.mu.Unlock()
+
+	err := o.Flush(ctx)
+
+	// Wait
+	// publishing coroutines.
+	done := make(chan bool)
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// /////////////////////////////////////////////////////////////
+// Access methods
+// /////////////////////////////////////////////////////////////
+
+func (o *Client) flush(ctx context.Context, b *BatchRequest) error {
+	if _, err := o.Batch(ctx, b); err != nil {
+		if o.errorHandler != nil {
+			o.errorHandler(err, b)
+		}
+		return err
+	}
+	return nil
+}
+
+func (o *Client) flusher() {
+	tk := time.NewTicker(o.bufferInterval)
+
+	defer func() {
+		tk.Stop()
+		o.wg.Done()
+	}()
+
+	for {
+		select {
+		case <-tk.C:
+			_ = o.Flush(context.Background())
+		case <-o.stop:
+			return
+		}
+	}
+}
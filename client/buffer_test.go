@@ -0,0 +1,139 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBufferFlush(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"count": 1})
+	})
+
+	c := New(s.URL, WithBuffer(10, time.Hour))
+	defer func() { _ = c.Close(context.Background()) }()
+
+	// Messages of the same topic
+	// are published in one batch with deliver time of each message.
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "A", Message: "1", DeliverAt: 1700000000})
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "A", Message: "2", DelaySeconds: 60, IdempotencyKey: "key-2"})
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "B", Message: "3"})
+
+	if n := len(s.Requests()); n != 0 {
+		t.Fatalf("buffered before flush expected: requests=%d", n)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	reqs := s.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("one batch of each tag expected: requests=%d", len(reqs))
+	}
+
+	batches := make([]*BatchRequest, 0)
+	for _, r := range reqs {
+		b := &BatchRequest{}
+		_ = json.Unmarshal(r.Body, b)
+		if r.Path != "/topic/batch" {
+			t.Fatalf("batch api expected: %s", r.Path)
+		}
+		batches = append(batches, b)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].TopicTag < batches[j].TopicTag })
+
+	a := batches[0]
+	if len(a.Messages) != 2 || a.Messages[0] != "1" || a.Messages[1] != "2" {
+		t.Fatalf("messages in enqueue order expected: %v", a.Messages)
+	}
+	if a.DeliverAtList[0] != 1700000000 || a.DelayList[1] != 60 || a.IdempotencyKeys[1] != "key-2" {
+		t.Fatalf("fields of each message expected: deliver-at=%v, delay=%v, keys=%v", a.DeliverAtList, a.DelayList, a.IdempotencyKeys)
+	}
+	if len(batches[1].Messages) != 1 {
+		t.Fatalf("one message of tag B expected: %v", batches[1].Messages)
+	}
+
+	// Nothing sent
+	// if buffer is empty.
+	if err := c.Flush(context.Background()); err != nil || len(s.Requests()) != 2 {
+		t.Fatalf("empty flush expected: %v", err)
+	}
+}
+
+func TestBufferSize(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"count": 2})
+	})
+
+	c := New(s.URL, WithBuffer(2, time.Hour))
+	for i := 0; i < 5; i++ {
+		_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: "{}"})
+	}
+
+	// Close
+	// publish remaining message and wait batches sent.
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Fatalf("3 batches expected: %d", n)
+	}
+	if err := c.Enqueue(&PublishRequest{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("closed error expected: %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("close again error: %v", err)
+	}
+}
+
+func TestBufferInterval(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"count": 1})
+	})
+
+	c := New(s.URL, WithBuffer(10, time.Millisecond*20))
+	defer func() { _ = c.Close(context.Background()) }()
+
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: "{}"})
+	for t0 := time.Now(); len(s.Requests()) == 0; {
+		if time.Since(t0) > time.Second {
+			t.Fatalf("flushed by interval expected")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestBufferErrorHandler(t *testing.T) {
+	var (
+		failed int32
+		s      = newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+			writeError(w, http.StatusOK, ErrnoInvalidPayloadFields, "invalid fields")
+		})
+	)
+
+	c := New(s.URL, WithBuffer(10, time.Hour), WithErrorHandler(func(err error, req *BatchRequest) {
+		if len(req.Messages) == 2 {
+			atomic.AddInt32(&failed, 1)
+		}
+	}))
+
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: "1"})
+	_ = c.Enqueue(&PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: "2"})
+
+	var e *Error
+	if err := c.Close(context.Background()); !errors.As(err, &e) {
+		t.Fatalf("flush error returned by close expected: %v", err)
+	}
+	if atomic.LoadInt32(&failed) != 1 {
+		t.Fatalf("error handler called with failed batch expected")
+	}
+}
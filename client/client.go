@@ -0,0 +1,279 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+// Package client
+// Go client of gmd http api, publish messages and manage tasks,
+// registries.
+//
+//	c := client.New("http://127.0.0.1:8101", client.WithRetry(3, time.Millisecond*200))
+//	defer c.Close(context.Background())
+//
+//	res, err := c.Publish(ctx, &client.PublishRequest{
+//	    TopicName: "orders",
+//	    TopicTag:  "created",
+//	    Message:   map[string]interface{}{"id": 1},
+//	})
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errno
+// returned by server, the same as code of app package.
+const (
+	_ int = iota
+
+	ErrnoInternalError
+	ErrnoInvalidPayloadFormat
+	ErrnoInvalidPayloadFields
+	ErrnoServiceReadError
+	ErrnoServiceReadNotFound
+	ErrnoServiceWriteError
+	ErrnoAdapterError
+	ErrnoTooManyRequests
+)
+
+const (
+	// MaxBatchSize
+	// max messages in one batch request.
+	MaxBatchSize = 100
+)
+
+var (
+	ErrClosed = fmt.Errorf("client closed")
+)
+
+type (
+	// Client
+	// of gmd http api, safe for concurrent use.
+	Client struct {
+		addr string
+		hc   *http.Client
+
+		gzip      bool
+		gzipSize  int
+		retry     int
+		retryWait time.Duration
+
+		bufferInterval time.Duration
+		bufferSize     int
+		buffers        map[string]*BatchRequest
+		closed         bool
+		errorHandler   func(err error, req *BatchRequest)
+		mu             *sync.Mutex
+		stop           chan bool
+		wg             *sync.WaitGroup
+	}
+
+	// Error
+	// returned if server responded error.
+	Error struct {
+		// Errno
+		// in response body, it's http status code if not responded
+		// by logic, for example 404, 500.
+		Errno int
+
+		// Message
+		// in response body.
+		Message string
+
+		// RetryAfter
+		// seconds in response header.
+		RetryAfter int
+
+		// StatusCode
+		// of http response.
+		StatusCode int
+	}
+
+	envelope struct {
+		Data  json.RawMessage `json:"data"`
+		Errno int             `json:"errno"`
+		Error string          `json:"error"`
+	}
+)
+
+// New
+// return client of specified server address.
+func New(addr string, options ...Option) *Client {
+	c := (&Client{addr: strings.TrimRight(addr, "/")}).init()
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Error
+// return error message.
+func (o *Error) Error() string {
+	return fmt.Sprintf("gmd: errno=%d, status=%d, error=%s", o.Errno, o.StatusCode, o.Message)
+}
+
+// Retryable
+// return true if request can be retried.
+func (o *Error) Retryable() bool {
+	if o.StatusCode == http.StatusTooManyRequests || o.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	switch o.Errno {
+	case ErrnoServiceWriteError, ErrnoAdapterError, ErrnoTooManyRequests, http.StatusTooManyRequests:
+		return true
+	}
+	return o.Errno >= http.StatusInternalServerError
+}
+
+// /////////////////////////////////////////////////////////////
+// Request methods.
+// /////////////////////////////////////////////////////////////
+
+// Call
+// send request to specified path and assign data of response into
+// res, request retried if failed.
+func (o *Client) call(ctx context.Context, path string, req, res interface{}) error {
+	var (
+		body     []byte
+		encoding string
+		err      error
+	)
+
+	if body, err = json.Marshal(req); err != nil {
+		return err
+	}
+
+	// Compress
+	// if size reached.
+	if o.gzip && len(body) >= o.gzipSize {
+		if body, err = o.compress(body); err != nil {
+			return err
+		}
+		encoding = "gzip"
+	}
+
+	for n := 0; ; n++ {
+		if err = o.send(ctx, path, body, encoding, res); err == nil || n >= o.retry || !o.retryable(err) {
+			return err
+		}
+
+		// Wait
+		// before retry, doubled after each retry or seconds of
+		// Retry-After header.
+		wait := o.retryWait << uint(n)
+		if e, ok := err.(*Error); ok && e.RetryAfter > 0 {
+			wait = time.Duration(e.RetryAfter) * time.Second
+		}
+
+		tm := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return ctx.Err()
+		case <-tm.C:
+		}
+	}
+}
+
+func (o *Client) compress(body []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *Client) retryable(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Retryable()
+	}
+
+	// Not retry
+	// if context cancelled.
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	return true
+}
+
+func (o *Client) send(ctx context.Context, path string, body []byte, encoding string, res interface{}) error {
+	var (
+		buf  []byte
+		env  = &envelope{}
+		err  error
+		req  *http.Request
+		resp *http.Response
+	)
+
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, o.addr+path, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	if resp, err = o.hc.Do(req); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if buf, err = io.ReadAll(resp.Body); err != nil {
+		return err
+	}
+
+	// Return error
+	// if status code not 200 or errno not zero.
+	e := &Error{StatusCode: resp.StatusCode}
+	e.RetryAfter, _ = strconv.Atoi(resp.Header.Get("Retry-After"))
+
+	if json.Unmarshal(buf, env) != nil {
+		if resp.StatusCode != http.StatusOK {
+			e.Errno, e.Message = resp.StatusCode, http.StatusText(resp.StatusCode)
+		} else {
+			e.Message = "invalid response body"
+		}
+		return e
+	}
+	if env.Errno != 0 || resp.StatusCode != http.StatusOK {
+		e.Errno, e.Message = env.Errno, env.Error
+		return e
+	}
+
+	if res == nil || len(env.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Data, res)
+}
+
+// /////////////////////////////////////////////////////////////
+// Constructor methods.
+// /////////////////////////////////////////////////////////////
+
+func (o *Client) init() *Client {
+	o.hc = &http.Client{Timeout: time.Second * 30}
+	o.retryWait = time.Millisecond * 100
+
+	o.bufferInterval = time.Second
+	o.bufferSize = MaxBatchSize
+	o.buffers = make(map[string]*BatchRequest)
+	o.mu = &sync.Mutex{}
+	o.wg = &sync.WaitGroup{}
+	return o
+}
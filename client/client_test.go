@@ -0,0 +1,300 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// Request
+	// received by test server.
+	testRequest struct {
+		Body     []byte
+		Encoding string
+		Path     string
+	}
+
+	// Test server
+	// records requests and responds with handler.
+	testServer struct {
+		*httptest.Server
+		mu       sync.Mutex
+		requests []*testRequest
+	}
+)
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, n int, req *testRequest)) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			body []byte
+			err  error
+			rd   io.Reader = r.Body
+		)
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			if rd, err = gzip.NewReader(r.Body); err != nil {
+				t.Errorf("gzip reader error: %v", err)
+				return
+			}
+		}
+		if body, err = io.ReadAll(rd); err != nil {
+			t.Errorf("read body error: %v", err)
+			return
+		}
+
+		req := &testRequest{Body: body, Encoding: r.Header.Get("Content-Encoding"), Path: r.URL.Path}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		n := len(s.requests)
+		s.mu.Unlock()
+
+		handler(w, n, req)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (o *testServer) Requests() []*testRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*testRequest(nil), o.requests...)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	buf, _ := json.Marshal(map[string]interface{}{"data": data, "dataType": "OBJECT", "errno": 0, "error": ""})
+	_, _ = w.Write(buf)
+}
+
+func writeError(w http.ResponseWriter, status, errno int, message string) {
+	buf, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{}, "dataType": "ERROR", "errno": errno, "error": message})
+	w.WriteHeader(status)
+	_, _ = w.Write(buf)
+}
+
+func TestClientPublish(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"hash": "HASH", "message_id": "MID", "registry_id": 3})
+	})
+
+	res, err := New(s.URL).Publish(context.Background(), &PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: map[string]int{"id": 1}})
+	if err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+	if res.Hash != "HASH" || res.MessageId != "MID" || res.RegistryId != 3 {
+		t.Fatalf("response fields: %+v", res)
+	}
+
+	reqs := s.Requests()
+	if len(reqs) != 1 || reqs[0].Path != "/topic/publish" {
+		t.Fatalf("one request of /topic/publish expected: %d", len(reqs))
+	}
+
+	req := &PublishRequest{}
+	_ = json.Unmarshal(reqs[0].Body, req)
+	if req.TopicName != "Topic" || req.TopicTag != "Tag" || req.IdempotencyKey != "" {
+		t.Fatalf("request fields: %+v", req)
+	}
+}
+
+func TestClientRetryIdempotencyKey(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, n int, _ *testRequest) {
+		if n < 3 {
+			writeError(w, http.StatusOK, ErrnoAdapterError, "adapter error")
+			return
+		}
+		writeData(w, map[string]interface{}{"hash": "HASH"})
+	})
+
+	c := New(s.URL, WithRetry(3, time.Millisecond))
+	if _, err := c.Publish(context.Background(), &PublishRequest{TopicName: "Topic", TopicTag: "Tag", Message: "{}"}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	// Same key
+	// generated once and sent in each retry.
+	reqs := s.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("3 requests expected: %d", len(reqs))
+	}
+	keys := make(map[string]bool)
+	for _, r := range reqs {
+		req := &PublishRequest{}
+		_ = json.Unmarshal(r.Body, req)
+		keys[req.IdempotencyKey] = true
+	}
+	if len(keys) != 1 || keys[""] {
+		t.Fatalf("the same idempotency key expected in retries: %v", keys)
+	}
+}
+
+func TestClientRetryBatchIdempotencyKeys(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, n int, _ *testRequest) {
+		if n == 1 {
+			writeError(w, http.StatusInternalServerError, ErrnoInternalError, "internal error")
+			return
+		}
+		writeData(w, map[string]interface{}{"count": 2})
+	})
+
+	c := New(s.URL, WithRetry(1, time.Millisecond))
+	res, err := c.Batch(context.Background(), &BatchRequest{TopicName: "Topic", TopicTag: "Tag", Messages: []interface{}{"a", "b"}, IdempotencyKeys: []string{"given"}})
+	if err != nil || res.Count != 2 {
+		t.Fatalf("batch error: %v", err)
+	}
+
+	reqs := s.Requests()
+	if len(reqs) != 2 || reqs[0].Path != "/topic/batch" {
+		t.Fatalf("2 requests of /topic/batch expected: %d", len(reqs))
+	}
+	var first, second BatchRequest
+	_ = json.Unmarshal(reqs[0].Body, &first)
+	_ = json.Unmarshal(reqs[1].Body, &second)
+	if len(first.IdempotencyKeys) != 2 || first.IdempotencyKeys[0] != "given" || first.IdempotencyKeys[1] == "" {
+		t.Fatalf("given key kept and missing key generated: %v", first.IdempotencyKeys)
+	}
+	if strings.Join(first.IdempotencyKeys, ",") != strings.Join(second.IdempotencyKeys, ",") {
+		t.Fatalf("the same keys expected in retry: %v, %v", first.IdempotencyKeys, second.IdempotencyKeys)
+	}
+}
+
+func TestClientNotRetryInvalidFields(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeError(w, http.StatusOK, ErrnoInvalidPayloadFields, "invalid fields")
+	})
+
+	_, err := New(s.URL, WithRetry(3, time.Millisecond)).Publish(context.Background(), &PublishRequest{})
+
+	var e *Error
+	if !errors.As(err, &e) || e.Errno != ErrnoInvalidPayloadFields || e.Message != "invalid fields" || e.Retryable() {
+		t.Fatalf("invalid fields error expected: %v", err)
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Fatalf("not retried expected: requests=%d", n)
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, n int, _ *testRequest) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, ErrnoTooManyRequests, "bucket is full")
+			return
+		}
+		writeData(w, map[string]interface{}{"hash": "HASH"})
+	})
+
+	// Wait seconds of header
+	// instead of retry wait.
+	t0 := time.Now()
+	if _, err := New(s.URL, WithRetry(1, time.Millisecond)).Publish(context.Background(), &PublishRequest{}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+	if d := time.Since(t0); d < time.Second {
+		t.Fatalf("retry after 1 second expected: %v", d)
+	}
+}
+
+func TestClientRetryAfterError(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusTooManyRequests, ErrnoTooManyRequests, "bucket is full")
+	})
+
+	_, err := New(s.URL).Publish(context.Background(), &PublishRequest{})
+
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusTooManyRequests || e.RetryAfter != 5 || !e.Retryable() {
+		t.Fatalf("too many requests error expected: %v", err)
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeError(w, http.StatusServiceUnavailable, ErrnoAdapterError, "adapter error")
+	})
+
+	// Cancelled
+	// while waiting retry.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	t0 := time.Now()
+	_, err := New(s.URL, WithRetry(5, time.Second)).Publish(ctx, &PublishRequest{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("deadline exceeded expected: %v", err)
+	}
+	if d := time.Since(t0); d > time.Second {
+		t.Fatalf("returned when cancelled expected: %v", d)
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Fatalf("not retried after cancelled: requests=%d", n)
+	}
+}
+
+func TestClientContextCancelled(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := New(s.URL, WithRetry(3, time.Millisecond)).Publish(ctx, &PublishRequest{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("context canceled expected: %v", err)
+	}
+	if n := len(s.Requests()); n != 0 {
+		t.Fatalf("request not sent expected: %d", n)
+	}
+}
+
+func TestClientGzip(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"hash": "HASH"})
+	})
+
+	c := New(s.URL, WithGzip(256))
+	if _, err := c.Publish(context.Background(), &PublishRequest{Message: "short"}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+	if _, err := c.Publish(context.Background(), &PublishRequest{Message: strings.Repeat("long", 100)}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	// Compress
+	// if size reached only.
+	reqs := s.Requests()
+	if reqs[0].Encoding != "" {
+		t.Fatalf("short body should not be compressed: %s", reqs[0].Encoding)
+	}
+	req := &PublishRequest{}
+	if reqs[1].Encoding != "gzip" || json.Unmarshal(reqs[1].Body, req) != nil || req.Message != strings.Repeat("long", 100) {
+		t.Fatalf("long body should be compressed with gzip: %s", reqs[1].Encoding)
+	}
+}
+
+func TestClientInvalidResponse(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("404 page not found"))
+	})
+
+	_, err := New(s.URL).Publish(context.Background(), &PublishRequest{})
+
+	var e *Error
+	if !errors.As(err, &e) || e.Errno != http.StatusNotFound || e.Retryable() {
+		t.Fatalf("not found error expected: %v", err)
+	}
+}
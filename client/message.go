@@ -0,0 +1,42 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package client
+
+import (
+	"context"
+)
+
+type (
+	AckRequest struct {
+		Token  string `json:"token"`
+		Result string `json:"result,omitempty"`
+	}
+
+	AckResponse struct {
+		Affects int64 `json:"affects"`
+		Id      int64 `json:"id"`
+	}
+)
+
+// Ack
+// acknowledge message accepted with 202 by handler, token is value of
+// X-Gmd-Ack-Token header, call /message/ack.
+func (o *Client) Ack(ctx context.Context, req *AckRequest) (*AckResponse, error) {
+	res := &AckResponse{}
+	if err := o.call(ctx, "/message/ack", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Nack
+// negative acknowledge message accepted with 202 by handler, call
+// /message/nack.
+func (o *Client) Nack(ctx context.Context, req *AckRequest) (*AckResponse, error) {
+	res := &AckResponse{}
+	if err := o.call(ctx, "/message/nack", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
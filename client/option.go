@@ -0,0 +1,75 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	// Option
+	// applied on client when created.
+	//
+	//	c := client.New("http://127.0.0.1:8101",
+	//	    client.WithRetry(3, time.Millisecond*200),
+	//	    client.WithGzip(1024),
+	//	)
+	Option func(c *Client)
+)
+
+// WithBuffer
+// change batch size and flush interval of buffered messages.
+//
+// Default: 100 messages, 1 second.
+func WithBuffer(size int, interval time.Duration) Option {
+	return func(c *Client) {
+		if size > 0 && size <= MaxBatchSize {
+			c.bufferSize = size
+		}
+		if interval > 0 {
+			c.bufferInterval = interval
+		}
+	}
+}
+
+// WithErrorHandler
+// called when buffered messages flushed failed.
+func WithErrorHandler(handler func(err error, req *BatchRequest)) Option {
+	return func(c *Client) {
+		c.errorHandler = handler
+	}
+}
+
+// WithGzip
+// compress request body with gzip if size greater than or equal to
+// specified bytes.
+func WithGzip(size int) Option {
+	return func(c *Client) {
+		c.gzip = true
+		c.gzipSize = size
+	}
+}
+
+// WithHttpClient
+// send requests by specified http client.
+func WithHttpClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.hc = hc
+		}
+	}
+}
+
+// WithRetry
+// retry failed requests, wait time doubled after each retry.
+//
+// Idempotency key generated for publish requests if not specified,
+// message is published once even if retried.
+func WithRetry(times int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.retry = times
+		c.retryWait = wait
+	}
+}
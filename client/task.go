@@ -0,0 +1,164 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package client
+
+import (
+	"context"
+)
+
+type (
+	TaskAddRequest struct {
+		DelaySeconds int    `json:"delay_seconds,omitempty"`
+		TopicName    string `json:"topic_name"`
+		TopicTag     string `json:"topic_tag"`
+		Handler      string `json:"handler"`
+		Title        string `json:"title"`
+		Remark       string `json:"remark,omitempty"`
+	}
+
+	TaskAddResponse struct {
+		DelaySeconds int    `json:"delay_seconds"`
+		Id           int    `json:"id"`
+		Title        string `json:"title"`
+		TopicName    string `json:"topic_name"`
+		TopicTag     string `json:"topic_tag"`
+	}
+
+	// TaskEditRequest
+	// fields of nil value are not changed, except required fields
+	// delay_seconds, parallels, concurrency and max_retry.
+	TaskEditRequest struct {
+		Id           int     `json:"id"`
+		DelaySeconds *int    `json:"delay_seconds"`
+		Parallels    *int    `json:"parallels"`
+		Concurrency  *int32  `json:"concurrency"`
+		MaxRetry     *int    `json:"max_retry"`
+		Broadcasting *int    `json:"broadcasting,omitempty"`
+		Ordered      *int    `json:"ordered,omitempty"`
+		Dedupe       *int    `json:"dedupe,omitempty"`
+		MaxAge       *int    `json:"max_age_seconds,omitempty"`
+		AckTimeout   *int    `json:"ack_timeout,omitempty"`
+		RateLimit    *int    `json:"rate_limit,omitempty"`
+		RateBurst    *int    `json:"rate_burst,omitempty"`
+		Title        *string `json:"title,omitempty"`
+		Remark       *string `json:"remark,omitempty"`
+
+		ConcurrencyAdaptive *int   `json:"concurrency_adaptive,omitempty"`
+		ConcurrencyMin      *int32 `json:"concurrency_min,omitempty"`
+		GlobalConcurrency   *int   `json:"global_concurrency,omitempty"`
+		GlobalRateLimit     *int   `json:"global_rate_limit,omitempty"`
+	}
+
+	TaskEditResponse struct {
+		Affects int64  `json:"affects"`
+		Id      int    `json:"id"`
+		Title   string `json:"title"`
+	}
+
+	// TaskEditSubscriber
+	// fields of nil value are not changed.
+	TaskEditSubscriber struct {
+		Id           int     `json:"id"`
+		Handler      *string `json:"handler,omitempty"`
+		Condition    *string `json:"condition,omitempty"`
+		IgnoreCodes  *string `json:"ignore_codes,omitempty"`
+		Method       *string `json:"method,omitempty"`
+		ResponseType *int    `json:"response_type,omitempty"`
+		Timeout      *int    `json:"timeout,omitempty"`
+
+		Balance   *int                `json:"balance,omitempty"`
+		Endpoints []*TaskEditEndpoint `json:"endpoints,omitempty"`
+	}
+
+	TaskEditEndpoint struct {
+		Addr   string `json:"addr"`
+		Weight int    `json:"weight"`
+	}
+
+	TaskRemoteResponse struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	}
+
+	taskId struct {
+		Id int `json:"id"`
+	}
+)
+
+// TaskAdd
+// add new task, call /task/add.
+func (o *Client) TaskAdd(ctx context.Context, req *TaskAddRequest) (*TaskAddResponse, error) {
+	res := &TaskAddResponse{}
+	if err := o.call(ctx, "/task/add", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TaskDisable
+// disable task, call /task/disable.
+func (o *Client) TaskDisable(ctx context.Context, id int) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/disable", &taskId{Id: id})
+}
+
+// TaskEdit
+// edit task settings, call /task/edit.
+func (o *Client) TaskEdit(ctx context.Context, req *TaskEditRequest) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/edit", req)
+}
+
+// TaskEditFailed
+// edit failed notification subscriber of task, call
+// /task/edit/failed.
+func (o *Client) TaskEditFailed(ctx context.Context, req *TaskEditSubscriber) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/edit/failed", req)
+}
+
+// TaskEditHandler
+// edit handler subscriber of task, call /task/edit/handler.
+func (o *Client) TaskEditHandler(ctx context.Context, req *TaskEditSubscriber) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/edit/handler", req)
+}
+
+// TaskEditSucceed
+// edit succeed notification subscriber of task, call
+// /task/edit/succeed.
+func (o *Client) TaskEditSucceed(ctx context.Context, req *TaskEditSubscriber) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/edit/succeed", req)
+}
+
+// TaskEnable
+// enable task, call /task/enable.
+func (o *Client) TaskEnable(ctx context.Context, id int) (*TaskEditResponse, error) {
+	return o.taskEdit(ctx, "/task/enable", &taskId{Id: id})
+}
+
+// TaskRemoteBuild
+// build topic and subscription of task on broker, call
+// /task/remote/build.
+func (o *Client) TaskRemoteBuild(ctx context.Context, id int) (*TaskRemoteResponse, error) {
+	res := &TaskRemoteResponse{}
+	if err := o.call(ctx, "/task/remote/build", &taskId{Id: id}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TaskRemoteDestroy
+// destroy subscription of task on broker, call /task/remote/destroy.
+func (o *Client) TaskRemoteDestroy(ctx context.Context, id int) (*TaskRemoteResponse, error) {
+	res := &TaskRemoteResponse{}
+	if err := o.call(ctx, "/task/remote/destroy", &taskId{Id: id}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (o *Client) taskEdit(ctx context.Context, path string, req interface{}) (*TaskEditResponse, error) {
+	res := &TaskEditResponse{}
+	if err := o.call(ctx, path, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
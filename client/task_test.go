@@ -0,0 +1,85 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-03
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestClientPaths(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"id": 7, "affects": 1, "title": "Task"})
+	})
+
+	var (
+		c       = New(s.URL)
+		ctx     = context.Background()
+		handler = "http://example.com/path"
+		n       = 1
+	)
+
+	for path, call := range map[string]func() error{
+		"/task/add":     func() error { _, err := c.TaskAdd(ctx, &TaskAddRequest{Title: "Task"}); return err },
+		"/task/edit":    func() error { _, err := c.TaskEdit(ctx, &TaskEditRequest{Id: 7, Parallels: &n}); return err },
+		"/task/disable": func() error { _, err := c.TaskDisable(ctx, 7); return err },
+		"/task/enable":  func() error { _, err := c.TaskEnable(ctx, 7); return err },
+		"/task/edit/handler": func() error {
+			_, err := c.TaskEditHandler(ctx, &TaskEditSubscriber{Id: 7, Handler: &handler})
+			return err
+		},
+		"/task/edit/failed":    func() error { _, err := c.TaskEditFailed(ctx, &TaskEditSubscriber{Id: 7}); return err },
+		"/task/edit/succeed":   func() error { _, err := c.TaskEditSucceed(ctx, &TaskEditSubscriber{Id: 7}); return err },
+		"/task/remote/build":   func() error { _, err := c.TaskRemoteBuild(ctx, 7); return err },
+		"/task/remote/destroy": func() error { _, err := c.TaskRemoteDestroy(ctx, 7); return err },
+		"/topic/priority":      func() error { _, err := c.Priority(ctx, &PriorityRequest{Priority: "high"}); return err },
+		"/topic/schema":        func() error { _, err := c.Schema(ctx, &SchemaRequest{}); return err },
+		"/topic/schema/add":    func() error { _, err := c.SchemaAdd(ctx, &SchemaAddRequest{}); return err },
+		"/message/ack":         func() error { _, err := c.Ack(ctx, &AckRequest{Token: "token"}); return err },
+		"/message/nack":        func() error { _, err := c.Nack(ctx, &AckRequest{Token: "token"}); return err },
+	} {
+		before := len(s.Requests())
+		if err := call(); err != nil {
+			t.Fatalf("%s: error=%v", path, err)
+		}
+		reqs := s.Requests()
+		if len(reqs) != before+1 || reqs[before].Path != path {
+			t.Fatalf("%s: request path not matched", path)
+		}
+	}
+}
+
+func TestClientTaskFields(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, _ int, _ *testRequest) {
+		writeData(w, map[string]interface{}{"id": 7, "affects": 1, "title": "Task"})
+	})
+
+	res, err := New(s.URL).TaskDisable(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	if res.Id != 7 || res.Affects != 1 || res.Title != "Task" {
+		t.Fatalf("response fields: %+v", res)
+	}
+
+	req := map[string]interface{}{}
+	_ = json.Unmarshal(s.Requests()[0].Body, &req)
+	if id, _ := req["id"].(float64); id != 7 {
+		t.Fatalf("task id in request expected: %v", req)
+	}
+
+	// Nil fields
+	// are not sent so they are not changed.
+	handler := "http://example.com/path"
+	if _, err = New(s.URL).TaskEditHandler(context.Background(), &TaskEditSubscriber{Id: 7, Handler: &handler}); err != nil {
+		t.Fatalf("edit handler error: %v", err)
+	}
+	req = map[string]interface{}{}
+	_ = json.Unmarshal(s.Requests()[1].Body, &req)
+	if _, ok := req["condition"]; ok || req["handler"] != handler {
+		t.Fatalf("nil fields should be omitted: %v", req)
+	}
+}
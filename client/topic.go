@@ -0,0 +1,179 @@
+// author: wsfuyibing <websearch@163.com>
+// date: 2023-03-01
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"strings"
+)
+
+type (
+	PublishRequest struct {
+		TopicName      string      `json:"topic_name"`
+		TopicTag       string      `json:"topic_tag"`
+		Message        interface{} `json:"message"`
+		IdempotencyKey string      `json:"idempotency_key,omitempty"`
+		DeliverAt      int64       `json:"deliver_at,omitempty"`
+		DelaySeconds   int         `json:"delay_seconds,omitempty"`
+		ExpiresAt      int64       `json:"expires_at,omitempty"`
+		Key            string      `json:"key,omitempty"`
+		Priority       string      `json:"priority,omitempty"`
+		Sync           bool        `json:"sync,omitempty"`
+	}
+
+	PublishResponse struct {
+		Duplicated bool   `json:"duplicated"`
+		Hash       string `json:"hash"`
+		MessageId  string `json:"message_id"`
+		RegistryId int    `json:"registry_id"`
+	}
+
+	BatchRequest struct {
+		TopicName       string        `json:"topic_name"`
+		TopicTag        string        `json:"topic_tag"`
+		Messages        []interface{} `json:"messages"`
+		IdempotencyKeys []string      `json:"idempotency_keys,omitempty"`
+		DeliverAt       int64         `json:"deliver_at,omitempty"`
+		DelaySeconds    int           `json:"delay_seconds,omitempty"`
+		DeliverAtList   []int64       `json:"deliver_at_list,omitempty"`
+		DelayList       []int         `json:"delay_seconds_list,omitempty"`
+		ExpiresAt       int64         `json:"expires_at,omitempty"`
+		Key             string        `json:"key,omitempty"`
+		Priority        string        `json:"priority,omitempty"`
+		Sync            bool          `json:"sync,omitempty"`
+	}
+
+	BatchResponse struct {
+		Count      int               `json:"count"`
+		Duplicates []*BatchDuplicate `json:"duplicates"`
+		Hash       string            `json:"hash"`
+		RegistryId int               `json:"registry_id"`
+		Results    []*BatchResult    `json:"results"`
+	}
+
+	BatchDuplicate struct {
+		Index     int    `json:"index"`
+		Hash      string `json:"hash"`
+		MessageId string `json:"message_id"`
+	}
+
+	BatchResult struct {
+		Index     int    `json:"index"`
+		MessageId string `json:"message_id"`
+		Error     string `json:"error"`
+	}
+
+	PriorityRequest struct {
+		TopicName string `json:"topic_name"`
+		TopicTag  string `json:"topic_tag"`
+		Priority  string `json:"priority"`
+	}
+
+	PriorityResponse struct {
+		Affects    int64  `json:"affects"`
+		Priority   string `json:"priority"`
+		RegistryId int    `json:"registry_id"`
+	}
+
+	SchemaRequest struct {
+		TopicName string `json:"topic_name"`
+		TopicTag  string `json:"topic_tag"`
+		Version   int    `json:"version,omitempty"`
+	}
+
+	SchemaAddRequest struct {
+		TopicName string      `json:"topic_name"`
+		TopicTag  string      `json:"topic_tag"`
+		Schema    interface{} `json:"schema"`
+		Mode      string      `json:"mode,omitempty"`
+		Force     bool        `json:"force,omitempty"`
+	}
+
+	SchemaResponse struct {
+		Mode       string          `json:"mode"`
+		RegistryId int             `json:"registry_id"`
+		Schema     json.RawMessage `json:"schema"`
+		Version    int             `json:"version"`
+	}
+)
+
+// Publish
+// one message, call /topic/publish.
+//
+// Idempotency key generated if retry enabled and not specified.
+func (o *Client) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	if o.retry > 0 && req.IdempotencyKey == "" {
+		r := *req
+		r.IdempotencyKey = o.idempotencyKey()
+		req = &r
+	}
+
+	res := &PublishResponse{}
+	if err := o.call(ctx, "/topic/publish", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Batch
+// publish messages of the same topic, call /topic/batch.
+//
+// Idempotency keys generated if retry enabled and not specified.
+func (o *Client) Batch(ctx context.Context, req *BatchRequest) (*BatchResponse, error) {
+	if o.retry > 0 {
+		r := *req
+		r.IdempotencyKeys = make([]string, len(req.Messages))
+		for i := range r.IdempotencyKeys {
+			if i < len(req.IdempotencyKeys) && req.IdempotencyKeys[i] != "" {
+				r.IdempotencyKeys[i] = req.IdempotencyKeys[i]
+			} else {
+				r.IdempotencyKeys[i] = o.idempotencyKey()
+			}
+		}
+		req = &r
+	}
+
+	res := &BatchResponse{}
+	if err := o.call(ctx, "/topic/batch", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Priority
+// change default publish priority of registry, call /topic/priority.
+func (o *Client) Priority(ctx context.Context, req *PriorityRequest) (*PriorityResponse, error) {
+	res := &PriorityResponse{}
+	if err := o.call(ctx, "/topic/priority", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Schema
+// return json schema of registry, call /topic/schema.
+func (o *Client) Schema(ctx context.Context, req *SchemaRequest) (*SchemaResponse, error) {
+	res := &SchemaResponse{}
+	if err := o.call(ctx, "/topic/schema", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SchemaAdd
+// add new version of json schema to registry, call
+// /topic/schema/add.
+func (o *Client) SchemaAdd(ctx context.Context, req *SchemaAddRequest) (*SchemaResponse, error) {
+	res := &SchemaResponse{}
+	if err := o.call(ctx, "/topic/schema/add", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (o *Client) idempotencyKey() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
@@ -1,30 +1,107 @@
 SET NAMES utf8mb4;
 SET FOREIGN_KEY_CHECKS = 0;
 
+-- ----------------------------
+-- Table structure for cron
+-- ----------------------------
+DROP TABLE IF EXISTS `cron`;
+CREATE TABLE `cron` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `status` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '状态位(0:已暂停,1:运行中)',
+  `title` varchar(80) NOT NULL DEFAULT '' COMMENT '定时发布名称',
+  `expression` varchar(64) NOT NULL COMMENT 'Cron表达式',
+  `timezone` varchar(64) NOT NULL DEFAULT 'UTC' COMMENT '时区',
+  `next_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '下次触发时间(Unix秒)',
+  `lease_node` varchar(64) DEFAULT NULL COMMENT '认领节点',
+  `lease_until` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '认领租约到期时间(Unix秒)',
+  `registry_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '注册关系表的id字段值',
+  `message_body` text NOT NULL COMMENT 'MQ消息内容',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_due` (`status`,`next_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='定时发布配置';
+
+-- ----------------------------
+-- Table structure for cron_history
+-- ----------------------------
+DROP TABLE IF EXISTS `cron_history`;
+CREATE TABLE `cron_history` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `cron_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '定时发布ID',
+  `fire_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '触发时间(Unix秒)',
+  `status` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '状态位(1:成功,2:失败,4:发布中)',
+  `node` varchar(64) DEFAULT NULL COMMENT '执行节点',
+  `hash` char(32) DEFAULT NULL COMMENT '发布哈希',
+  `message_id` varchar(32) DEFAULT NULL COMMENT 'MQ服务器返回的消息ID',
+  `error` varchar(255) DEFAULT NULL COMMENT '失败原因',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_fire` (`cron_id`,`fire_at`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='定时发布执行记录';
+
+-- ----------------------------
+-- Table structure for idempotency
+-- ----------------------------
+DROP TABLE IF EXISTS `idempotency`;
+CREATE TABLE `idempotency` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `registry_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '注册关系表的id字段值',
+  `idempotency_key` varchar(128) NOT NULL COMMENT '幂等键',
+  `hash` char(32) NOT NULL COMMENT '发布哈希',
+  `offset` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布批次',
+  `message_id` varchar(32) DEFAULT NULL COMMENT 'MQ服务器返回的消息ID',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '首次发布时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_registry_key` (`registry_id`,`idempotency_key`) USING BTREE,
+  KEY `idx_hash` (`hash`,`offset`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='幂等发布记录';
+
 -- ----------------------------
 -- Table structure for message
 -- ----------------------------
 DROP TABLE IF EXISTS `message`;
 CREATE TABLE `message` (
   `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
-  `status` tinyint(3) unsigned NOT NULL COMMENT '状态位(1:成功,2:失败,3:待重试,4:重试中,9:被忽略)',
+  `status` tinyint(3) unsigned NOT NULL COMMENT '状态位(1:成功,2:失败,3:待重试,4:重试中,5:已过期,9:被忽略)',
   `duration` decimal(16,6) unsigned NOT NULL DEFAULT '0.000000' COMMENT '投递耗时',
   `retry` smallint(5) unsigned NOT NULL DEFAULT '0' COMMENT '投递次数',
   `task_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '订阅任务ID',
   `payload_message_id` varchar(32) DEFAULT NULL COMMENT '主题消息ID',
   `message_dequeue` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '第几次出列时落库',
   `message_time` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '发布时间',
+  `expires_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间(Unix秒, 0:不过期)',
   `message_id` varchar(32) NOT NULL COMMENT '消息ID',
+  `keyword` varchar(128) DEFAULT NULL COMMENT '消息键',
   `message_body` text NOT NULL COMMENT '消息正文',
   `response_body` text COMMENT '消息投递结果',
+  `ack_token` varchar(32) DEFAULT NULL COMMENT '异步确认令牌',
+  `ack_deadline` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '异步确认截止时间(Unix秒)',
   `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_task_message` (`task_id`,`message_id`) USING BTREE,
   KEY `idx_topic_message` (`payload_message_id`),
-  KEY `idx_status` (`status`,`task_id`)
+  KEY `idx_status` (`status`,`task_id`),
+  KEY `idx_ack_token` (`ack_token`),
+  KEY `idx_ack_deadline` (`status`,`ack_deadline`)
 ) ENGINE=InnoDB AUTO_INCREMENT=21 DEFAULT CHARSET=utf8 COMMENT='消费记录';
 
+-- ----------------------------
+-- Table structure for node
+-- ----------------------------
+DROP TABLE IF EXISTS `node`;
+CREATE TABLE `node` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `node` varchar(64) NOT NULL COMMENT '节点标识',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '加入时间',
+  `gmt_heartbeat` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最后心跳时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_node` (`node`) USING BTREE,
+  KEY `idx_heartbeat` (`gmt_heartbeat`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='集群节点';
+
 -- ----------------------------
 -- Table structure for payload
 -- ----------------------------
@@ -38,10 +115,16 @@ CREATE TABLE `payload` (
   `message_message_id` varchar(32) NOT NULL COMMENT '消费记录表的message_id字段值',
   `hash` char(32) NOT NULL COMMENT '发布哈希',
   `offset` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布批次',
+  `keyword` varchar(128) DEFAULT NULL COMMENT '消息键',
+  `priority` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '发布优先级(0:normal,1:high,2:low)',
+  `idempotency_key` varchar(128) DEFAULT NULL COMMENT '幂等键',
+  `deliver_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '定时投递时间(Unix秒, 0:立即)',
+  `expires_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间(Unix秒, 0:不过期)',
   `registry_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '注册关系表的id字段值',
   `message_id` varchar(32) DEFAULT NULL COMMENT 'MQ服务器返回的消息ID',
   `message_body` text NOT NULL COMMENT 'MQ消息内容',
   `response_body` text COMMENT 'MQ发布结果',
+  `node` varchar(64) DEFAULT NULL COMMENT '发布节点',
   `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
   PRIMARY KEY (`id`),
@@ -60,12 +143,56 @@ CREATE TABLE `registry` (
   `topic_name` varchar(32) NOT NULL COMMENT '主题名',
   `topic_tag` varchar(64) NOT NULL COMMENT '主题标签',
   `filter_tag` varchar(16) DEFAULT NULL COMMENT '过滤标签',
+  `priority` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '默认发布优先级(0:normal,1:high,2:low)',
   `gmt_created` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   `gmt_updated` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后修改时间',
   PRIMARY KEY (`id`),
   UNIQUE KEY `uni_topic_pair` (`topic_name`,`topic_tag`) USING BTREE
 ) ENGINE=InnoDB AUTO_INCREMENT=17 DEFAULT CHARSET=utf8 COMMENT='注册关系';
 
+-- ----------------------------
+-- Table structure for registry_schema
+-- ----------------------------
+DROP TABLE IF EXISTS `registry_schema`;
+CREATE TABLE `registry_schema` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `registry_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '注册关系表的id字段值',
+  `version` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '版本号, 最大版本生效',
+  `mode` tinyint(3) unsigned NOT NULL DEFAULT '1' COMMENT '校验模式(1:拒绝,2:仅告警)',
+  `content` text NOT NULL COMMENT 'JSON Schema',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_version` (`registry_id`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='注册关系消息格式';
+
+-- ----------------------------
+-- Table structure for schedule
+-- ----------------------------
+DROP TABLE IF EXISTS `schedule`;
+CREATE TABLE `schedule` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `status` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '状态位(1:已发布,3:待发布,4:发布中)',
+  `deliver_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '定时投递时间(Unix秒)',
+  `expires_at` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间(Unix秒, 0:不过期)',
+  `message_id` varchar(32) DEFAULT NULL COMMENT 'MQ服务器返回的消息ID',
+  `lease_node` varchar(64) DEFAULT NULL COMMENT '认领节点',
+  `lease_until` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '认领租约到期时间(Unix秒)',
+  `hash` char(32) NOT NULL COMMENT '发布哈希',
+  `offset` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布批次',
+  `registry_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '注册关系表的id字段值',
+  `keyword` varchar(128) DEFAULT NULL COMMENT '消息键',
+  `priority` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '发布优先级(0:normal,1:high,2:low)',
+  `idempotency_key` varchar(128) DEFAULT NULL COMMENT '幂等键',
+  `message_body` text NOT NULL COMMENT 'MQ消息内容',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_due` (`status`,`deliver_at`),
+  KEY `idx_lease` (`lease_node`,`status`),
+  KEY `idx_hash` (`hash`,`offset`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='定时发布记录';
+
 -- ----------------------------
 -- Table structure for task
 -- ----------------------------
@@ -77,9 +204,19 @@ CREATE TABLE `task` (
   `remark` text COMMENT '描述',
   `parallels` tinyint(3) unsigned NOT NULL DEFAULT '1' COMMENT '最大并行数(单个节点最多开启消费者数)',
   `concurrency` tinyint(3) unsigned NOT NULL DEFAULT '32' COMMENT '最大并发数(单个消费者最多允许多少条消息同时处于投递中)',
+  `concurrency_adaptive` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '是否自适应并发(0:否,1:是, 根据投递耗时与失败率在最小与最大并发数之间调整)',
+  `concurrency_min` tinyint(3) unsigned NOT NULL DEFAULT '1' COMMENT '最小并发数(自适应并发时有效)',
   `max_retry` tinyint(3) unsigned NOT NULL DEFAULT '3' COMMENT '最大重试数(投递失败的消息, 最多允许重试次数)',
   `delay_seconds` smallint(5) unsigned NOT NULL DEFAULT '0' COMMENT '消息发布后, 延时多久(秒)再允许消费',
   `broadcasting` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '是否广播(支持: Rocketmq)',
+  `ordered` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '是否顺序投递(0:否,1:是, 相同消息键按发布顺序逐条投递)',
+  `dedupe` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '是否投递前去重(0:否,1:是, 跳过已投递成功或其它节点投递中的消息)',
+  `max_age_seconds` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '消息最大存活时长(单位:秒, 发布后超过时长的消息不再投递, 0:不过期)',
+  `ack_timeout` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '异步确认超时(单位:秒, 0:同步投递)',
+  `rate_limit` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '每秒最多投递消息数(单个节点, 0: 不限制)',
+  `rate_burst` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '限流时最多允许突发投递消息数(0: 同每秒投递数)',
+  `global_concurrency` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '集群最大并发数(所有节点均分, 0: 不限制)',
+  `global_rate_limit` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '集群每秒最多投递消息数(所有节点均分, 0: 不限制)',
   `registry_id` int(10) unsigned NOT NULL COMMENT '注册关系ID',
   `handler` varchar(255) NOT NULL COMMENT '订阅回调地址',
   `handler_timeout` tinyint(3) NOT NULL DEFAULT '10' COMMENT '订阅回调超时(单位: 秒)',
@@ -87,6 +224,8 @@ CREATE TABLE `task` (
   `handler_condition` varchar(255) DEFAULT NULL COMMENT '条件过滤',
   `handler_response_type` tinyint(3) NOT NULL DEFAULT '0' COMMENT '投递结果(0:JSON.ERRNO=0,1:HTML.CODE=200)',
   `handler_ignore_codes` varchar(255) DEFAULT NULL COMMENT '忽略状态码',
+  `handler_endpoints` text COMMENT '订阅回调扩展地址(JSON: [{"addr":"","weight":1}])',
+  `handler_balance` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '负载策略(0:轮询,1:加权,2:最少处理中)',
   `failed` varchar(255) DEFAULT NULL COMMENT '失败通知地址',
   `failed_timeout` tinyint(3) NOT NULL DEFAULT '10' COMMENT '失败通知超时(单位:秒)',
   `failed_method` varchar(16) DEFAULT NULL COMMENT '失败通知方式',
@@ -0,0 +1,44 @@
+-- ----------------------------
+-- Outbox table of business service
+--
+-- Create in database of business service, then add connection to
+-- config/db.yaml and enable outbox manager in config/md.yaml:
+--
+--   outbox:
+--     connection: "order"
+--     table: "gmd_outbox"
+--
+-- Insert record in the same transaction of business data, it is
+-- published by GMD after committed:
+--
+--   BEGIN;
+--   UPDATE `order` SET `status` = 2 WHERE `id` = 1001;
+--   INSERT INTO `gmd_outbox` (`aggregate_key`, `topic_name`, `topic_tag`, `message_body`)
+--     VALUES ('order-1001', 'ORDER', 'PAID', '{"id":1001}');
+--   COMMIT;
+--
+-- Records of the same aggregate key are published one by one in id
+-- order, write them in serialized transactions (e.g. lock the
+-- aggregate row first). Records without aggregate key are published
+-- in parallel. Message may be published again if node crashed after
+-- publish and before status updated, subscribers should be idempotent.
+-- ----------------------------
+DROP TABLE IF EXISTS `gmd_outbox`;
+CREATE TABLE `gmd_outbox` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'PK',
+  `status` tinyint(3) unsigned NOT NULL DEFAULT '3' COMMENT '状态(1:已发布,2:发布失败,3:待发布)',
+  `aggregate_key` varchar(128) NOT NULL DEFAULT '' COMMENT '聚合键, 相同键按id顺序发布',
+  `topic_name` varchar(32) NOT NULL COMMENT '主题名',
+  `topic_tag` varchar(64) NOT NULL COMMENT '主题标签',
+  `message_body` text NOT NULL COMMENT 'MQ消息内容',
+  `message_id` varchar(64) DEFAULT NULL COMMENT 'MQ服务器返回的消息ID',
+  `retry` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布次数',
+  `error` varchar(255) DEFAULT NULL COMMENT '最后一次发布错误',
+  `lease_node` varchar(64) DEFAULT NULL COMMENT '认领节点',
+  `lease_until` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '认领到期时间',
+  `gmt_created` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `gmt_updated` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '最后更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_status` (`status`,`lease_until`),
+  KEY `idx_aggregate` (`aggregate_key`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='发件箱';
@@ -19,6 +19,7 @@ import (
 	"github.com/fuyibing/gmd/app/md"
 	"github.com/fuyibing/gmd/app/md/conf"
 	"github.com/fuyibing/gmd/app/middlewares"
+	"github.com/fuyibing/gmd/app/rpc"
 	"github.com/fuyibing/log/v8"
 	logConf "github.com/fuyibing/log/v8/conf"
 	"github.com/kataras/iris/v12"
@@ -41,19 +42,30 @@ type (
 		cancel context.CancelFunc
 		ctx    context.Context
 		fw     *iris.Application
+		rpc    *rpc.Server
 	}
 )
 
 // DoBefore
 //
 // called when application start fired. Start message
-// dispatcher boot manager in coroutine.
+// dispatcher boot manager and grpc server in coroutine.
 func (o *Bootstrap) DoBefore(_ *iris.Application) {
 	go func() {
 		if err := md.Boot.Processor().Start(o.ctx); err != nil {
 			log.Errorf("%v", err)
 		}
 	}()
+
+	// Start grpc server
+	// if port configured.
+	if app.Config.GrpcPort > 0 {
+		go func() {
+			if err := o.rpc.Start(app.Config.GrpcAddr); err != nil {
+				log.Errorf("grpc server error: %v", err)
+			}
+		}()
+	}
 }
 
 // DoInterrupt
@@ -61,6 +73,10 @@ func (o *Bootstrap) DoBefore(_ *iris.Application) {
 // called when SIGTERM/SIGINT signal received. Block coroutine
 // until mq dispatcher boot manager stopped.
 func (o *Bootstrap) DoInterrupt() {
+	// Stop grpc server
+	// before producer stopped, pending requests completed.
+	o.rpc.Stop()
+
 	// Cancel context
 	// if it is running.
 	if o.ctx != nil && o.ctx.Err() == nil {
@@ -84,6 +100,7 @@ func (o *Bootstrap) DoInterrupt() {
 func (o *Bootstrap) Initialize() *Bootstrap {
 	o.InitConsoleCommand()
 	o.InitFramework()
+	o.InitGrpc()
 	return o
 }
 
@@ -144,7 +161,7 @@ func (o *Bootstrap) InitFrameworkControllers() {
 // called in initialize method, It register middlewares on
 // each request.
 func (o *Bootstrap) InitFrameworkMiddlewares() {
-	o.fw.UseGlobal(middlewares.Tracer, middlewares.Panic)
+	o.fw.UseGlobal(middlewares.Tracer, middlewares.Panic, middlewares.Decompress)
 }
 
 // InitFrameworkProfile
@@ -165,6 +182,14 @@ func (o *Bootstrap) InitFrameworkStatusCode() {
 	o.fw.OnAnyErrorCode(middlewares.ErrCode)
 }
 
+// InitGrpc
+//
+// called in initialize method. It create grpc server with
+// publisher service registered.
+func (o *Bootstrap) InitGrpc() {
+	o.rpc = rpc.NewServer()
+}
+
 // Run
 //
 // called by console manager.